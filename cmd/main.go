@@ -21,12 +21,14 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"text/template"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -40,9 +42,12 @@ import (
 	"github.com/payback159/namespace-resizer/internal/controller"
 	"github.com/payback159/namespace-resizer/internal/git"
 	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/ownership"
+	"github.com/payback159/namespace-resizer/internal/routing"
 	"github.com/payback159/namespace-resizer/internal/sizing"
 
 	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	// +kubebuilder:scaffold:imports
@@ -181,6 +186,19 @@ func main() {
 						lock.ControllerNamespace: {},
 					},
 				},
+				// Routes and their credentials live in the controller
+				// namespace only; watching Secrets cluster-wide would need
+				// far broader RBAC.
+				&corev1.ConfigMap{}: {
+					Namespaces: map[string]cache.Config{
+						lock.ControllerNamespace: {},
+					},
+				},
+				&corev1.Secret{}: {
+					Namespaces: map[string]cache.Config{
+						lock.ControllerNamespace: {},
+					},
+				},
 			},
 		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
@@ -209,6 +227,12 @@ func main() {
 	if gitPathTemplate == "" {
 		gitPathTemplate = "managed-resources/{{ .Cluster }}/{{ .Namespace }}"
 	}
+	// Empty targets the repository's default branch.
+	gitBaseBranch := os.Getenv("GIT_BASE_BRANCH")
+	if _, err := template.New("base").Parse(gitBaseBranch); err != nil {
+		setupLog.Error(err, "invalid GIT_BASE_BRANCH")
+		os.Exit(1)
+	}
 
 	// GitHub App Config
 	githubAppID := os.Getenv("GITHUB_APP_ID")
@@ -268,13 +292,89 @@ func main() {
 		setupLog.Error(nil, "GitHub configuration missing", "owner", githubOwner, "repo", githubRepo, "cluster", clusterName)
 		os.Exit(1)
 	}
+	if gh, ok := gitProvider.(*git.GitHubProvider); ok && gitBaseBranch != "" {
+		gitProvider = gh.WithBaseBranch(gitBaseBranch)
+	}
+	// Issue mode files an issue per quota instead of a pull request.
+	if gh, ok := gitProvider.(*git.GitHubProvider); ok && os.Getenv("ISSUE_MODE") == trueStr {
+		setupLog.Info("Using issue mode: proposals are filed as GitHub issues")
+		gitProvider = gh.Issues()
+	}
+
+	// Empty opens one PR per quota; a duration batches a namespace's quotas.
+	var batchDebounce time.Duration
+	if raw := os.Getenv("BATCH_DEBOUNCE"); raw != "" {
+		var err error
+		batchDebounce, err = time.ParseDuration(raw)
+		if err != nil || batchDebounce < 0 {
+			setupLog.Error(err, "invalid BATCH_DEBOUNCE", "value", raw)
+			os.Exit(1)
+		}
+	}
+
+	// Empty opens shrink PRs per quota; a duration collects them into one
+	// digest PR per repository at that interval.
+	var shrinkDigestInterval time.Duration
+	if raw := os.Getenv("SHRINK_DIGEST_INTERVAL"); raw != "" {
+		var err error
+		shrinkDigestInterval, err = time.ParseDuration(raw)
+		if err != nil || shrinkDigestInterval < 0 {
+			setupLog.Error(err, "invalid SHRINK_DIGEST_INTERVAL", "value", raw)
+			os.Exit(1)
+		}
+	}
+
+	// Empty opens shrink PRs ready for review; a duration opens them as
+	// drafts and marks them ready once the recommendation held that long.
+	var shrinkDraftSoak time.Duration
+	if raw := os.Getenv("SHRINK_DRAFT_SOAK"); raw != "" {
+		var err error
+		shrinkDraftSoak, err = time.ParseDuration(raw)
+		if err != nil || shrinkDraftSoak < 0 {
+			setupLog.Error(err, "invalid SHRINK_DRAFT_SOAK", "value", raw)
+			os.Exit(1)
+		}
+	}
+
+	// A quota whose manifest cannot be found is escalated after this long.
+	unmappedEscalateAfter := time.Hour
+	if raw := os.Getenv("UNMAPPED_ESCALATE_AFTER"); raw != "" {
+		var err error
+		unmappedEscalateAfter, err = time.ParseDuration(raw)
+		if err != nil || unmappedEscalateAfter < 0 {
+			setupLog.Error(err, "invalid UNMAPPED_ESCALATE_AFTER", "value", raw)
+			os.Exit(1)
+		}
+	}
 
 	locker := lock.NewLeaseLocker(mgr.GetClient())
 
 	basePolicy := sizing.DefaultPolicy()
 	basePolicy.ShrinkEnabled = enableShrink
+	// Empty keeps the quota's own units; "cpu=500m,memory=1Gi" rounds targets
+	// to those steps.
+	if raw := os.Getenv("QUANTITY_STEPS"); raw != "" {
+		steps, err := sizing.ParseSteps(raw)
+		if err != nil {
+			setupLog.Error(err, "invalid QUANTITY_STEPS", "value", raw)
+			os.Exit(1)
+		}
+		basePolicy.Step = steps
+	}
+
+	// Pods matching the selector are left out of the observation window. An
+	// empty value leaves nothing out but still attributes the peaks.
+	ignoreSelector := controller.DefaultIgnoreInSizingSelector
+	if raw, ok := os.LookupEnv("IGNORE_IN_SIZING_SELECTOR"); ok {
+		ignoreSelector = raw
+	}
+	ignoreInSizing, err := labels.Parse(ignoreSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid IGNORE_IN_SIZING_SELECTOR", "value", ignoreSelector)
+		os.Exit(1)
+	}
 
-	observer := controller.NewObserver(locker, time.Now)
+	observer := controller.NewObserver(locker, time.Now).WithAttribution(mgr.GetClient(), ignoreInSizing)
 
 	// Start Lease Garbage Collector (runs every 12 hours)
 	gc := lock.NewLeaseGarbageCollector(mgr.GetClient(), 12*time.Hour)
@@ -283,21 +383,53 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := (&controller.ResourceQuotaReconciler{
+	var router *routing.Router
+	if routingConfigMap := os.Getenv("ROUTING_CONFIGMAP"); routingConfigMap != "" {
+		setupLog.Info("Routing namespaces by ConfigMap", "configMap", routingConfigMap,
+			"namespace", lock.ControllerNamespace)
+		router = routing.NewRouter(mgr.GetClient(), routingConfigMap, clusterName, gitPathTemplate, gitBaseBranch,
+			gitProvider)
+	}
+
+	var reviewers *ownership.Resolver
+	if reviewersConfigMap := os.Getenv("REVIEWERS_CONFIGMAP"); reviewersConfigMap != "" {
+		setupLog.Info("Requesting reviewers by ConfigMap", "configMap", reviewersConfigMap,
+			"namespace", lock.ControllerNamespace)
+		reviewers = ownership.NewResolver(mgr.GetClient(), reviewersConfigMap)
+	}
+
+	reconciler := &controller.ResourceQuotaReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 		// GetEventRecorderFor returns the legacy record.EventRecorder. We keep it
 		// intentionally; migrating to the new events.EventRecorder API is tracked separately.
-		Recorder:        mgr.GetEventRecorderFor("namespace-resizer"), //nolint:staticcheck // legacy events API
-		GitProvider:     gitProvider,
-		Locker:          locker,
-		Observer:        observer,
-		BasePolicy:      basePolicy,
-		EnableAutoMerge: enableAutoMerge,
-	}).SetupWithManager(mgr); err != nil {
+		Recorder:           mgr.GetEventRecorderFor("namespace-resizer"), //nolint:staticcheck // legacy events API
+		GitProvider:        gitProvider,
+		Locker:             locker,
+		Observer:           observer,
+		BasePolicy:         basePolicy,
+		EnableAutoMerge:    enableAutoMerge,
+		ArgoCDNamespace:    os.Getenv("ARGOCD_NAMESPACE"),
+		Router:             router,
+		Reviewers:          reviewers,
+		BatchDebounce:      batchDebounce,
+		EnableShrinkDigest: shrinkDigestInterval > 0,
+		ShrinkDraftSoak:    shrinkDraftSoak,
+
+		UnmappedEscalateAfter: unmappedEscalateAfter,
+		ReportUnmapped:        os.Getenv("UNMAPPED_TRACKING_ISSUE") == trueStr,
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ResourceQuota")
 		os.Exit(1)
 	}
+	if shrinkDigestInterval > 0 {
+		digest := &controller.ShrinkDigest{Reconciler: reconciler, Interval: shrinkDigestInterval}
+		if err := mgr.Add(digest); err != nil {
+			setupLog.Error(err, "unable to add shrink digest to manager")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -50,6 +50,11 @@ const (
 	AnnotationMaxShrinkStep = "resizer.io/max-shrink-step"
 	// AnnotationShrinkPRTTLDays expires an unreviewed shrink PR (default "7").
 	AnnotationShrinkPRTTLDays = "resizer.io/shrink-pr-ttl-days"
+	// AnnotationGrowPRTTLDays expires an unmerged grow PR (default "14").
+	AnnotationGrowPRTTLDays = "resizer.io/grow-pr-ttl-days"
+	// AnnotationGrowPRObsoleteHours closes a grow PR no longer needed for
+	// this long (default "24").
+	AnnotationGrowPRObsoleteHours = "resizer.io/grow-pr-obsolete-hours"
 	// AnnotationShrinkEnabled opts a namespace out of shrinking. It cannot
 	// opt in when --enable-shrink is off.
 	AnnotationShrinkEnabled = "resizer.io/shrink-enabled"
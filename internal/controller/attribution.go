@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/payback159/namespace-resizer/internal/sizing"
+)
+
+// DefaultIgnoreInSizingSelector selects the workloads whose usage the
+// observation window leaves out, by the labels of their pods.
+const DefaultIgnoreInSizingSelector = "resizer.io/ignore-in-sizing=true"
+
+// WithAttribution makes the observer list the pods of a quota's namespace with
+// every sample. Usage of pods matching ignore is left out of the window, and
+// each day's peak is attributed to the workload holding the most of it.
+func (o *Observer) WithAttribution(pods client.Reader, ignore labels.Selector) *Observer {
+	o.pods = pods
+	o.ignore = ignore
+	return o
+}
+
+// sample builds the observation of a quota. Without attribution, or when the
+// pods cannot be listed, it is status.used alone: leaving nothing out can
+// only keep a target higher.
+func (o *Observer) sample(ctx context.Context, quota *corev1.ResourceQuota) (sizing.Sample, error) {
+	sample := sizing.Sample{Used: quota.Status.Used}
+	if o.pods == nil {
+		return sample, nil
+	}
+	var pods corev1.PodList
+	if err := o.pods.List(ctx, &pods, client.InNamespace(quota.Namespace)); err != nil {
+		return sample, fmt.Errorf("failed to list pods for attribution: %w", err)
+	}
+
+	excluded := corev1.ResourceList{}
+	byWorkload := map[string]corev1.ResourceList{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			// Terminal pods no longer count against a quota.
+			continue
+		}
+		usage := podQuotaUsage(pod)
+		if o.ignore != nil && !o.ignore.Empty() && o.ignore.Matches(labels.Set(pod.Labels)) {
+			addResources(excluded, usage)
+			continue
+		}
+		workload := workloadOf(pod)
+		if byWorkload[workload] == nil {
+			byWorkload[workload] = corev1.ResourceList{}
+		}
+		addResources(byWorkload[workload], usage)
+	}
+
+	sample.Excluded = corev1.ResourceList{}
+	for res := range quota.Status.Used {
+		if qty, ok := excluded[res]; ok {
+			sample.Excluded[res] = qty
+		}
+	}
+	sample.Drivers = map[corev1.ResourceName]string{}
+	for res := range quota.Status.Used {
+		var most resource.Quantity
+		for workload, usage := range byWorkload {
+			qty, ok := usage[res]
+			if !ok || qty.Sign() <= 0 {
+				continue
+			}
+			// Ties go to the lower name, so the attribution is stable.
+			if cmp := qty.Cmp(most); cmp > 0 || (cmp == 0 && workload < sample.Drivers[res]) {
+				most = qty
+				sample.Drivers[res] = workload
+			}
+		}
+	}
+	return sample, nil
+}
+
+// podQuotaUsage returns what a pod counts against the compute keys of a
+// quota: the sum of its containers, or its largest init container when that
+// is more, plus the pod overhead.
+func podQuotaUsage(pod *corev1.Pod) corev1.ResourceList {
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addResources(requests, c.Resources.Requests)
+		addResources(limits, c.Resources.Limits)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		maxResources(requests, c.Resources.Requests)
+		maxResources(limits, c.Resources.Limits)
+	}
+	addResources(requests, pod.Spec.Overhead)
+	addResources(limits, pod.Spec.Overhead)
+
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:               resource.MustParse("1"),
+		corev1.ResourceName("count/pods"): resource.MustParse("1"),
+	}
+	for res, qty := range requests {
+		usage[res] = qty
+		usage[corev1.ResourceName("requests."+string(res))] = qty
+	}
+	for res, qty := range limits {
+		usage[corev1.ResourceName("limits."+string(res))] = qty
+	}
+	return usage
+}
+
+func addResources(into, add corev1.ResourceList) {
+	for res, qty := range add {
+		sum := into[res].DeepCopy()
+		sum.Add(qty)
+		into[res] = sum
+	}
+}
+
+func maxResources(into, other corev1.ResourceList) {
+	for res, qty := range other {
+		if current, ok := into[res]; !ok || qty.Cmp(current) > 0 {
+			into[res] = qty.DeepCopy()
+		}
+	}
+}
+
+// workloadOf names the workload a pod belongs to as "Kind/name". A
+// ReplicaSet stamped by a Deployment is reported as the Deployment.
+func workloadOf(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod/" + pod.Name
+	}
+	if owner.Kind == kindReplicaSet {
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" {
+			return "Deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind + "/" + owner.Name
+}
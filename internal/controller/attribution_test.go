@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+func attributedPod(name, cpu string, podLabels map[string]string, owner *metav1.OwnerReference) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", Labels: podLabels},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "main",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			},
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if owner != nil {
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+func TestObserver_AttributesAndExcludesWorkloads(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = coordinationv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	rs := &metav1.OwnerReference{Kind: kindReplicaSet, Name: "api-5d9f", Controller: ptr.To(true)}
+	job := &metav1.OwnerReference{Kind: "Job", Name: "migrate", Controller: ptr.To(true)}
+	ignored := map[string]string{"resizer.io/ignore-in-sizing": "true"}
+	done := attributedPod("old-migrate", "8", ignored, job)
+	done.Status.Phase = corev1.PodSucceeded
+	objects := []client.Object{
+		attributedPod("api-1", "1", map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "5d9f"}, rs),
+		attributedPod("api-2", "1", map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "5d9f"}, rs),
+		attributedPod("worker", "1500m", nil, nil),
+		attributedPod("migrate-x", "6", ignored, job),
+		done,
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	selector, err := labels.Parse(DefaultIgnoreInSizingSelector)
+	g.Expect(err).NotTo(HaveOccurred())
+	now := time.Date(2026, 8, 8, 0, 0, 0, 0, time.UTC)
+	observer := NewObserver(lock.NewLeaseLocker(c), func() time.Time { return now }).WithAttribution(c, selector)
+
+	window, err := observer.Observe(ctx, observedQuota("9500m"), 14)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	day := window.Days[0]
+	g.Expect(day.Peaks).To(HaveKeyWithValue("requests.cpu", "3500m"))
+	g.Expect(day.Excluded).To(HaveKeyWithValue("requests.cpu", "6"))
+	g.Expect(day.Drivers).To(HaveKeyWithValue("requests.cpu", "Deployment/api"))
+}
+
+func TestPodQuotaUsage_InitContainersAndLimits(t *testing.T) {
+	g := NewWithT(t)
+	pod := attributedPod("p", "500m", nil, nil)
+	pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}
+	pod.Spec.InitContainers = []corev1.Container{{
+		Name: "init",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+		},
+	}}
+
+	usage := podQuotaUsage(pod)
+
+	g.Expect(usage.Cpu().String()).To(Equal("2"), "the init container needs more than the app")
+	g.Expect(usage.Name(corev1.ResourceRequestsCPU, resource.DecimalSI).String()).To(Equal("2"))
+	g.Expect(usage.Name(corev1.ResourceLimitsMemory, resource.BinarySI).String()).To(Equal("1Gi"))
+	g.Expect(usage.Pods().String()).To(Equal("1"))
+}
@@ -202,3 +202,49 @@ func TestAutoMerge_NeverMergesAShrink(t *testing.T) {
 	g.Expect(provider.MergedPRID).To(Equal(0),
 		"a shrink PR must never be auto-merged, however clean it looks")
 }
+
+func TestAutoMerge_SkipsACappedGrowThatLeavesAShortage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		max      string
+		merged   int
+		shortage bool
+	}{
+		// Used 4 plus a shortage of 20 asks for 30 with headroom.
+		{name: "cap below the shortage", max: "20", merged: 0, shortage: true},
+		{name: "cap above the target", max: "40", merged: 42},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			h := newShrinkHarness(t, &git.PRStatus{
+				IsOpen:         true,
+				Mergeable:      true,
+				MergeableState: git.MergeableStateClean,
+				ChecksState:    git.ChecksStateSuccess,
+			}, shrinkHarnessOpts{}, shortageObjects("20")...)
+			h.reconciler.EnableAutoMerge = true
+
+			var ns corev1.Namespace
+			g.Expect(h.reconciler.Get(ctx, types.NamespacedName{Name: "team-a"}, &ns)).To(Succeed())
+			ns.Annotations = map[string]string{"resizer.io/requests.cpu-max": tc.max}
+			g.Expect(h.reconciler.Update(ctx, &ns)).To(Succeed())
+			g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+				s.PRID = 42
+				s.PRDirection = git.DirectionGrow
+			})).To(Succeed())
+
+			g.Expect(h.reconcile(ctx)).To(Succeed())
+
+			g.Expect(h.provider.MergedPRID).To(Equal(tc.merged))
+			capped := ContainElement(ContainSubstring("GrowCapped"))
+			if tc.shortage {
+				g.Expect(h.events()).To(capped)
+				limit := h.provider.LastLimits[corev1.ResourceRequestsCPU]
+				g.Expect(limit.String()).To(Equal("20"))
+			} else {
+				g.Expect(h.events()).NotTo(capped)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/sizing"
+)
+
+// proposeBatch records the quota's proposal on its Lease and, once the oldest
+// pending proposal of the same direction in the namespace is older than
+// BatchDebounce, opens one PR for all of them. Whichever quota reconciles
+// first after the debounce opens it; the others find their Lease already
+// pointing at the PR. Grow and shrink proposals are never mixed, for the same
+// reason Decide never mixes them within one quota.
+func (r *ResourceQuotaReconciler) proposeBatch(
+	ctx context.Context,
+	req ctrl.Request,
+	provider git.Provider,
+	quota corev1.ResourceQuota,
+	ns corev1.Namespace,
+	state lock.State,
+	decision sizing.Decision,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	direction := decision.Direction.String()
+	now := time.Now()
+
+	recorded, err := r.recordPending(ctx, req, state, decision, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	states, err := r.Locker.ListStates(ctx, req.Namespace)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list pending proposals: %w", err)
+	}
+	// The cache may not show the proposal just recorded yet, and a batch
+	// opened from a stale read would carry the quota's previous limits or
+	// leave it out.
+	states[req.Name] = recorded
+	var members []string
+	oldest := now
+	for name, s := range states {
+		if s.PRID != 0 || s.PendingDirection != direction {
+			continue
+		}
+		members = append(members, name)
+		if !s.PendingSince.IsZero() && s.PendingSince.Before(oldest) {
+			oldest = s.PendingSince
+		}
+	}
+	sort.Strings(members)
+	if wait := oldest.Add(r.BatchDebounce).Sub(now); wait > 0 {
+		logger.Info("Waiting for the namespace's other quotas before opening a PR",
+			"direction", direction, "pending", members, "wait", wait)
+		return ctrl.Result{RequeueAfter: wait + time.Second}, nil
+	}
+
+	var changes []git.QuotaChange
+	for _, name := range members {
+		if name == req.Name {
+			r.recordRecommendations(ctx, &quota, decision.Direction, decision.Targets)
+			changes = append(changes, git.QuotaChange{
+				Quota:      name,
+				Limits:     decision.Targets,
+				Reason:     decision.Reason,
+				Exclusions: decision.Exclusions,
+			})
+			continue
+		}
+		var member corev1.ResourceQuota
+		if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: name}, &member); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+		memberLimits, err := decodeLimits(states[name].PendingLimits)
+		if err != nil {
+			logger.Error(err, "ignoring unreadable pending proposal", "quota", name)
+			continue
+		}
+		r.recordRecommendations(ctx, &member, decision.Direction, memberLimits)
+		var exclusions []string
+		if states[name].PendingExclusions != "" {
+			exclusions = strings.Split(states[name].PendingExclusions, "\n")
+		}
+		changes = append(changes, git.QuotaChange{
+			Quota:      name,
+			Limits:     memberLimits,
+			Reason:     states[name].PendingReason,
+			Exclusions: exclusions,
+		})
+	}
+	if len(changes) == 0 {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	provider, err = r.withCreateKey(ctx, provider, req.Namespace, direction, changes, states)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var prID int
+	if len(changes) == 1 {
+		prID, err = provider.CreatePR(ctx, changes[0].Quota, req.Namespace, direction, ns.Annotations, changes[0].Limits)
+	} else {
+		logger.Info("Creating batched PR", "quotas", len(changes))
+		prID, err = provider.CreateBatchPR(ctx, req.Namespace, direction, ns.Annotations, changes)
+	}
+	if err != nil {
+		if errors.Is(err, git.ErrFileNotFound) {
+			// The quota without a manifest holds up the batch; it is the one
+			// escalated.
+			unmapped := req.NamespacedName
+			var mappingErr *git.UnmappedError
+			if errors.As(err, &mappingErr) {
+				unmapped.Name = mappingErr.Quota
+			}
+			return r.handleUnmapped(ctx, provider, unmapped, err)
+		}
+		logger.Error(err, "failed to create PR")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("PR created, acquiring locks", "prID", prID)
+	draft := r.opensDraft(provider, decision.Direction)
+	for _, change := range changes {
+		// A draft's soak compares each quota against what it proposed.
+		var proposed string
+		if draft {
+			proposed = states[change.Quota].PendingLimits
+		}
+		err := r.Locker.MutateState(ctx, req.Namespace, change.Quota, func(s *lock.State) {
+			s.PRID = prID
+			s.PRDirection = direction
+			s.PRRepo = git.RepositoryOf(provider)
+			s.PRBatch = len(changes) > 1
+			s.ClearPending()
+			s.CreateKey = ""
+			s.PRDraft = draft
+			s.PRLimits = proposed
+			if decision.Direction == sizing.DirectionShrink {
+				s.LastShrink = now
+			} else {
+				s.LastGrow = now
+			}
+		})
+		if err != nil {
+			// The quotas left unlocked adopt the PR through FindOpenPR.
+			logger.Error(err, "failed to record the new pull request", "quota", change.Quota)
+			return ctrl.Result{}, err
+		}
+		if err := r.forgetUnmapped(ctx, provider, req.Namespace, change.Quota, states[change.Quota]); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// recordPending stores the decision on the quota's Lease as a proposal waiting
+// to be batched, along with its explanation, unless it is already recorded.
+// PendingSince only moves when the direction changes, so revised targets do
+// not restart the wait. It returns the state as recorded.
+func (r *ResourceQuotaReconciler) recordPending(
+	ctx context.Context,
+	req ctrl.Request,
+	state lock.State,
+	decision sizing.Decision,
+	now time.Time,
+) (lock.State, error) {
+	direction := decision.Direction.String()
+	limits, err := encodeLimits(decision.Targets)
+	if err != nil {
+		return state, err
+	}
+	exclusions := strings.Join(decision.Exclusions, "\n")
+	if state.PendingDirection == direction && state.PendingLimits == limits &&
+		state.PendingReason == decision.Reason && state.PendingExclusions == exclusions {
+		return state, nil
+	}
+	record := func(s *lock.State) {
+		if s.PendingDirection != direction {
+			s.PendingSince = now
+		}
+		s.PendingDirection = direction
+		s.PendingLimits = limits
+		s.PendingReason = decision.Reason
+		s.PendingExclusions = exclusions
+	}
+	if err := r.Locker.MutateState(ctx, req.Namespace, req.Name, record); err != nil {
+		return state, fmt.Errorf("failed to record pending proposal: %w", err)
+	}
+	record(&state)
+	return state, nil
+}
+
+// dropPending withdraws the quota from a batch it no longer needs, forgets
+// the create key of a pull request that no longer has to be opened, and
+// withdraws the escalation of a manifest that no longer has to be found.
+func (r *ResourceQuotaReconciler) dropPending(
+	ctx context.Context,
+	req ctrl.Request,
+	quota *corev1.ResourceQuota,
+	ns *corev1.Namespace,
+	state lock.State,
+) (ctrl.Result, error) {
+	if err := r.withdrawUnmapped(ctx, quota, ns, state); err != nil {
+		return ctrl.Result{}, err
+	}
+	if state.PendingDirection != "" || state.CreateKey != "" {
+		err := r.Locker.MutateState(ctx, req.Namespace, req.Name, func(s *lock.State) {
+			s.ClearPending()
+			s.CreateKey = ""
+		})
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to drop pending proposal: %w", err)
+		}
+	}
+	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// mutatePRHolders applies fn to the state of every quota holding the pull
+// request recorded in state: quotaName alone, every quota of a batch, or
+// every quota of a shrink digest across namespaces, so that closing or
+// merging one releases it and stamps its cooldowns everywhere at once.
+// quotaName goes last: if an earlier write fails, its still-held lock makes
+// the next reconcile try again.
+func (r *ResourceQuotaReconciler) mutatePRHolders(
+	ctx context.Context,
+	namespace, quotaName string,
+	state lock.State,
+	fn func(*lock.State),
+) error {
+	self := types.NamespacedName{Namespace: namespace, Name: quotaName}
+	var holders []types.NamespacedName
+	switch {
+	case state.PRDigest:
+		states, err := r.Locker.ListAllStates(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list the quotas sharing PR %d: %w", state.PRID, err)
+		}
+		for key, other := range states {
+			if key != self && other.PRID == state.PRID && other.PRRepo == state.PRRepo {
+				holders = append(holders, key)
+			}
+		}
+		sort.Slice(holders, func(i, j int) bool { return holders[i].String() < holders[j].String() })
+	case state.PRBatch:
+		states, err := r.Locker.ListStates(ctx, namespace)
+		if err != nil {
+			return fmt.Errorf("failed to list the quotas sharing PR %d: %w", state.PRID, err)
+		}
+		for _, name := range sharingPR(states, state, quotaName) {
+			holders = append(holders, types.NamespacedName{Namespace: namespace, Name: name})
+		}
+	}
+	holders = append(holders, self)
+	for _, key := range holders {
+		err := r.Locker.MutateState(ctx, key.Namespace, key.Name, func(s *lock.State) {
+			// Another reconcile may have released it meanwhile.
+			if s.PRID == state.PRID && s.PRRepo == state.PRRepo {
+				fn(s)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharingPR returns the quotas other than quotaName whose state holds the
+// same pull request as state, sorted.
+func sharingPR(states map[string]lock.State, state lock.State, quotaName string) []string {
+	var names []string
+	for name, other := range states {
+		if name != quotaName && other.PRID == state.PRID && other.PRRepo == state.PRRepo {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeLimits and decodeLimits store a proposal's targets on the Lease.
+func encodeLimits(limits map[corev1.ResourceName]resource.Quantity) (string, error) {
+	raw := make(map[string]string, len(limits))
+	for res, qty := range limits {
+		raw[string(res)] = qty.String()
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode proposal: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func decodeLimits(encoded string) (map[corev1.ResourceName]resource.Quantity, error) {
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(encoded), &raw); err != nil {
+		return nil, fmt.Errorf("invalid pending limits: %w", err)
+	}
+	limits := make(map[corev1.ResourceName]resource.Quantity, len(raw))
+	for res, value := range raw {
+		qty, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pending limit for %s: %w", res, err)
+		}
+		limits[corev1.ResourceName(res)] = qty
+	}
+	return limits, nil
+}
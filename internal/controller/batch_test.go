@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/sizing"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newBatchTestReconciler builds a namespace with two fully used quotas,
+// "compute" and "storage", both of which need to grow.
+func newBatchTestReconciler(t *testing.T) (*ResourceQuotaReconciler, *FakeGitProvider, *lock.LeaseLocker) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = coordinationv1.AddToScheme(scheme)
+
+	ns := &corev1.Namespace{}
+	ns.Name = prTestNS
+	compute := newResizeNeededQuota()
+	compute.Name = "compute"
+	storage := newResizeNeededQuota()
+	storage.Name = "storage"
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ns, compute, storage).Build()
+	locker := lock.NewLeaseLocker(c)
+	provider := &FakeGitProvider{CreatePRID: 77}
+	return &ResourceQuotaReconciler{
+		Client:        c,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(100),
+		GitProvider:   provider,
+		Locker:        locker,
+		Observer:      NewObserver(locker, time.Now),
+		BasePolicy:    sizing.DefaultPolicy(),
+		BatchDebounce: time.Minute,
+	}, provider, locker
+}
+
+func reconcileQuota(r *ResourceQuotaReconciler, name string) (ctrl.Result, error) {
+	return r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: prTestNS, Name: name}})
+}
+
+func TestBatch_OnePRForAllQuotasAfterTheDebounce(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	r, provider, locker := newBatchTestReconciler(t)
+
+	result, err := reconcileQuota(r, "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, 2*time.Second))
+	g.Expect(provider.CreatePRCalls+provider.CreateBatchPRCalls).To(Equal(0), "the debounce has not elapsed")
+
+	state, err := locker.GetState(ctx, prTestNS, "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PendingDirection).To(Equal(git.DirectionGrow))
+	g.Expect(state.PendingReason).To(ContainSubstring("`requests.cpu`"), "the explanation waits with the proposal")
+
+	// Let the first proposal age past the debounce.
+	g.Expect(locker.MutateState(ctx, prTestNS, "compute", func(s *lock.State) {
+		s.PendingSince = time.Now().Add(-2 * time.Minute)
+	})).To(Succeed())
+
+	_, err = reconcileQuota(r, "storage")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(provider.CreatePRCalls).To(Equal(0))
+	g.Expect(provider.CreateBatchPRCalls).To(Equal(1))
+	g.Expect(provider.LastBatch).To(HaveLen(2))
+	g.Expect(provider.LastBatch[0].Quota).To(Equal("compute"))
+	g.Expect(provider.LastBatch[1].Quota).To(Equal("storage"))
+	g.Expect(provider.LastBatch[0].Reason).To(ContainSubstring("`requests.cpu`"))
+	g.Expect(provider.LastBatch[1].Reason).To(ContainSubstring("`requests.cpu`"))
+
+	for _, quota := range []string{"compute", "storage"} {
+		state, err := locker.GetState(ctx, prTestNS, quota)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(state.PRID).To(Equal(77), quota)
+		g.Expect(state.PRBatch).To(BeTrue(), quota)
+		g.Expect(state.PendingDirection).To(BeEmpty(), quota)
+		g.Expect(state.PendingReason).To(BeEmpty(), quota)
+		g.Expect(state.LastGrow.IsZero()).To(BeFalse(), quota)
+	}
+}
+
+func TestBatch_SingleQuotaOpensAPlainPR(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	r, provider, locker := newBatchTestReconciler(t)
+
+	g.Expect(locker.MutateState(ctx, prTestNS, "compute", func(s *lock.State) {
+		s.PendingDirection = git.DirectionGrow
+		s.PendingSince = time.Now().Add(-2 * time.Minute)
+		s.PendingLimits = `{"requests.cpu":"13"}`
+	})).To(Succeed())
+	// storage is held by another PR and takes no part.
+	g.Expect(locker.MutateState(ctx, prTestNS, "storage", func(s *lock.State) {
+		s.PRID = 5
+	})).To(Succeed())
+
+	_, err := reconcileQuota(r, "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(provider.CreatePRCalls).To(Equal(1))
+	g.Expect(provider.CreateBatchPRCalls).To(Equal(0))
+	state, err := locker.GetState(ctx, prTestNS, "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(77))
+	g.Expect(state.PRBatch).To(BeFalse())
+}
+
+// staleLeaseClient answers Lease lists from a snapshot, like an informer
+// cache that has not yet seen the latest writes.
+type staleLeaseClient struct {
+	client.Client
+	leases coordinationv1.LeaseList
+}
+
+func (c *staleLeaseClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if leases, ok := list.(*coordinationv1.LeaseList); ok {
+		c.leases.DeepCopyInto(leases)
+		return nil
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestBatch_ProposesTheCurrentDecisionDespiteAStaleCache(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	r, provider, locker := newBatchTestReconciler(t)
+
+	g.Expect(locker.MutateState(ctx, prTestNS, "compute", func(s *lock.State) {
+		s.PendingDirection = git.DirectionGrow
+		s.PendingSince = time.Now().Add(-2 * time.Minute)
+		s.PendingLimits = `{"requests.cpu":"13"}`
+		s.PendingReason = "an earlier reason"
+	})).To(Succeed())
+	g.Expect(locker.MutateState(ctx, prTestNS, "storage", func(s *lock.State) {
+		s.PRID = 5
+	})).To(Succeed())
+	stale := &staleLeaseClient{Client: r.Client}
+	g.Expect(r.Client.List(ctx, &stale.leases)).To(Succeed())
+	r.Locker = lock.NewLeaseLocker(stale)
+
+	_, err := reconcileQuota(r, "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(provider.CreatePRCalls).To(Equal(1))
+	cpu := provider.LastLimits[corev1.ResourceRequestsCPU]
+	g.Expect(cpu.String()).NotTo(Equal("13"), "the batch carries the decision just made, not the cached one")
+}
+
+func TestBatch_MergeReleasesEveryQuota(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	r, provider, locker := newBatchTestReconciler(t)
+	provider.PRStatus = &git.PRStatus{IsOpen: false, IsMerged: true}
+
+	for _, quota := range []string{"compute", "storage"} {
+		g.Expect(locker.MutateState(ctx, prTestNS, quota, func(s *lock.State) {
+			s.PRID = 77
+			s.PRDirection = git.DirectionGrow
+			s.PRBatch = true
+		})).To(Succeed())
+	}
+
+	_, err := reconcileQuota(r, "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, quota := range []string{"compute", "storage"} {
+		state, err := locker.GetState(ctx, prTestNS, quota)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(state.PRID).To(Equal(0), quota)
+		g.Expect(state.PRBatch).To(BeFalse(), quota)
+		g.Expect(state.LastGrow.IsZero()).To(BeFalse(), quota)
+		g.Expect(state.LastModified.IsZero()).To(BeFalse(), quota)
+	}
+}
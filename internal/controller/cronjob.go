@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/sizing"
+)
+
+const kindCronJob = "CronJob"
+
+// scheduledRuns returns the runs the CronJobs of the quota's namespace have
+// due between now and now+lookahead, leaving out CronJobs whose pods are
+// outside the quota's scope. A CronJob whose schedule cannot be parsed is
+// left out and reported on the quota; the API server rejects most of those
+// already.
+func (r *ResourceQuotaReconciler) scheduledRuns(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+	now time.Time,
+	lookahead time.Duration,
+) ([]sizing.CronRun, error) {
+	logger := log.FromContext(ctx)
+
+	var cronJobs batchv1.CronJobList
+	if err := r.List(ctx, &cronJobs, client.InNamespace(quota.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list CronJobs: %w", err)
+	}
+	var runs []sizing.CronRun
+	for _, cj := range cronJobs.Items {
+		if !inQuotaScope(quota, &cj.Spec.JobTemplate.Spec.Template.Spec) {
+			continue
+		}
+		name := kindCronJob + "/" + cj.Name
+		cjRuns, err := sizing.CronJobRuns(name, cj.Spec, len(cj.Status.Active), now, now.Add(lookahead))
+		if err != nil {
+			logger.Info("CronJob left out of the scheduled-run projection", "cronJob", cj.Name, "reason", err.Error())
+			r.Recorder.Event(quota, corev1.EventTypeWarning, "CronJobScheduleUnreadable",
+				fmt.Sprintf("%s left out of the scheduled-run projection: %v", name, err))
+			continue
+		}
+		runs = append(runs, cjRuns...)
+	}
+	return runs, nil
+}
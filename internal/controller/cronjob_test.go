@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+)
+
+// reportCronJob returns a CronJob due within the next day whose single pod
+// requests cpu.
+func reportCronJob(schedule, cpu string) *batchv1.CronJob {
+	cj := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "team-a"},
+		Spec:       batchv1.CronJobSpec{Schedule: schedule},
+	}
+	cj.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "report",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse(cpu),
+		}},
+	}}
+	return cj
+}
+
+func TestCronJob_UpcomingRunOpensAGrow(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	// 4 used plus a run of 20 -> 24, past the hard limit of 16.
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, reportCronJob("0 2 * * *", "20"))
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	limit := h.provider.LastLimits[corev1.ResourceRequestsCPU]
+	g.Expect(limit.String()).To(Equal("30"))
+}
+
+func TestCronJob_LookaheadOptOut(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, reportCronJob("0 2 * * *", "20"))
+	var ns corev1.Namespace
+	g.Expect(h.reconciler.Get(ctx, types.NamespacedName{Name: "team-a"}, &ns)).To(Succeed())
+	ns.Annotations = map[string]string{"resizer.io/cronjob-lookahead-hours": "0"}
+	g.Expect(h.reconciler.Update(ctx, &ns)).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0))
+}
+
+func TestCronJob_OutOfScopeRunIsIgnored(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	report := reportCronJob("0 2 * * *", "20")
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, report)
+	var quota corev1.ResourceQuota
+	g.Expect(h.reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "compute"}, &quota)).To(Succeed())
+	quota.Spec.Scopes = []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeTerminating}
+	g.Expect(h.reconciler.Update(ctx, &quota)).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0), "the run's pods have no active deadline")
+	g.Expect(h.reconciler.mapWorkloadToQuotas(ctx, report)).To(BeEmpty())
+}
+
+func TestCronJob_UnreadableScheduleIsReported(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, reportCronJob("0 2 * *", "20"))
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0))
+	g.Expect(h.events()).To(ContainElement(ContainSubstring("CronJobScheduleUnreadable")))
+}
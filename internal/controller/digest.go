@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/sizing"
+)
+
+// digestPollInterval is how often the digest checks whether a proposal has
+// waited long enough. The interval itself is measured from the proposals'
+// PendingSince, so a restart does not push the next digest back.
+const digestPollInterval = 5 * time.Minute
+
+// queueForDigest records a shrink on the quota's Lease for the next digest
+// instead of opening a pull request for it.
+func (r *ResourceQuotaReconciler) queueForDigest(
+	ctx context.Context,
+	req ctrl.Request,
+	state lock.State,
+	decision sizing.Decision,
+) (ctrl.Result, error) {
+	if _, err := r.recordPending(ctx, req, state, decision, time.Now()); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.FromContext(ctx).Info("Shrink queued for the next digest")
+	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// ShrinkDigest collects the shrink proposals queued by the reconciler and
+// opens one pull request per repository and base branch for all of them,
+// once the oldest has waited Interval.
+type ShrinkDigest struct {
+	Reconciler *ResourceQuotaReconciler
+	Interval   time.Duration
+}
+
+// Start implements manager.Runnable to run in the controller manager.
+func (d *ShrinkDigest) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("shrink-digest")
+	logger.Info("Starting shrink digest", "interval", d.Interval)
+
+	ticker := time.NewTicker(digestPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.run(ctx, time.Now()); err != nil {
+			logger.Error(err, "Failed to open shrink digests")
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping shrink digest")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// digestGroup is the queued shrinks that go into one digest.
+type digestGroup struct {
+	provider git.Provider
+	target   git.Target
+	oldest   time.Time
+	entries  []git.DigestEntry
+	quotas   map[types.NamespacedName]*corev1.ResourceQuota
+	limits   map[types.NamespacedName]string
+	// reviewers are everyone the namespaces of the digest ask for shrinks.
+	reviewers git.Reviewers
+}
+
+func (d *ShrinkDigest) run(ctx context.Context, now time.Time) error {
+	r := d.Reconciler
+	logger := log.FromContext(ctx).WithName("shrink-digest")
+
+	states, err := r.Locker.ListAllStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pending shrinks: %w", err)
+	}
+	keys := make([]types.NamespacedName, 0, len(states))
+	for key, state := range states {
+		if state.PRID == 0 && state.PendingDirection == git.DirectionShrink {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	groups := map[string]*digestGroup{}
+	var order []string
+	for _, key := range keys {
+		state := states[key]
+		var quota corev1.ResourceQuota
+		if err := r.Get(ctx, key, &quota); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logger.Error(err, "failed to fetch queued quota", "quota", key)
+			}
+			continue
+		}
+		var ns corev1.Namespace
+		if err := r.Get(ctx, client.ObjectKey{Name: key.Namespace}, &ns); err != nil {
+			logger.Error(err, "failed to fetch namespace of queued quota", "quota", key)
+			continue
+		}
+		limits, err := decodeLimits(state.PendingLimits)
+		if err != nil {
+			logger.Error(err, "ignoring unreadable pending proposal", "quota", key)
+			continue
+		}
+		provider, err := r.providerFor(ctx, &quota, &ns)
+		if err != nil {
+			logger.Error(err, "failed to resolve the git provider", "quota", key)
+			continue
+		}
+		target, err := git.TargetOf(provider, ns.Name, ns.Annotations)
+		if err != nil {
+			logger.Error(err, "failed to resolve where the quota's pull requests go", "quota", key)
+			continue
+		}
+
+		groupKey := target.Repository + "@" + target.BaseBranch
+		group, ok := groups[groupKey]
+		if !ok {
+			group = &digestGroup{
+				provider: provider,
+				target:   target,
+				oldest:   now,
+				quotas:   map[types.NamespacedName]*corev1.ResourceQuota{},
+				limits:   map[types.NamespacedName]string{},
+			}
+			groups[groupKey] = group
+			order = append(order, groupKey)
+		}
+		if !state.PendingSince.IsZero() && state.PendingSince.Before(group.oldest) {
+			group.oldest = state.PendingSince
+		}
+		group.entries = append(group.entries, git.DigestEntry{
+			Namespace: key.Namespace,
+			Quota:     key.Name,
+			Path:      target.Path,
+			Current:   quota.Status.Hard,
+			Limits:    limits,
+		})
+		group.quotas[key] = &quota
+		group.limits[key] = state.PendingLimits
+		if r.Reviewers != nil {
+			reviewers, err := r.Reviewers.ReviewersFor(ctx, &ns, git.DirectionShrink)
+			if err != nil {
+				logger.Error(err, "failed to resolve reviewers", "quota", key)
+			}
+			group.reviewers = group.reviewers.Merge(reviewers)
+		}
+	}
+
+	var errs []error
+	for _, groupKey := range order {
+		group := groups[groupKey]
+		if now.Sub(group.oldest) < d.Interval {
+			continue
+		}
+		if err := d.open(ctx, group, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// open creates the digest of group and locks every quota it carries.
+func (d *ShrinkDigest) open(ctx context.Context, group *digestGroup, now time.Time) error {
+	r := d.Reconciler
+	logger := log.FromContext(ctx).WithName("shrink-digest")
+
+	logger.Info("Creating shrink digest PR", "repository", group.target.Repository,
+		"baseBranch", group.target.BaseBranch, "quotas", len(group.entries))
+	provider := group.provider
+	if assigner, ok := provider.(git.ReviewerAssigner); ok && !group.reviewers.IsZero() {
+		provider = assigner.WithReviewers(group.reviewers)
+	}
+	prID, included, err := provider.CreateDigestPR(ctx, group.target.BaseBranch, group.entries)
+	if errors.Is(err, git.ErrFileNotFound) {
+		logger.Info("No manifest of the digest was found, retrying with the next digest",
+			"repository", group.target.Repository, "error", err.Error())
+		return d.postpone(ctx, group.entries, now)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create shrink digest in %q: %w", group.target.Repository, err)
+	}
+
+	logger.Info("Shrink digest PR created, acquiring locks", "prID", prID, "quotas", len(included))
+	for _, entry := range included {
+		key := types.NamespacedName{Namespace: entry.Namespace, Name: entry.Quota}
+		r.recordRecommendations(ctx, group.quotas[key], sizing.DirectionShrink, entry.Limits)
+		err := r.Locker.MutateState(ctx, key.Namespace, key.Name, func(s *lock.State) {
+			s.PRID = prID
+			s.PRDirection = git.DirectionShrink
+			s.PRRepo = git.RepositoryOf(group.provider)
+			s.PRDigest = true
+			s.ClearPending()
+			s.DigestLimits = group.limits[key]
+			s.DigestApplied = time.Time{}
+			s.LastShrink = now
+		})
+		if err != nil {
+			// The quotas left unlocked adopt the digest through FindOpenPR.
+			return fmt.Errorf("failed to record shrink digest %d for %s: %w", prID, key, err)
+		}
+	}
+
+	var left []git.DigestEntry
+	carried := map[types.NamespacedName]bool{}
+	for _, entry := range included {
+		carried[types.NamespacedName{Namespace: entry.Namespace, Name: entry.Quota}] = true
+	}
+	for _, entry := range group.entries {
+		if !carried[types.NamespacedName{Namespace: entry.Namespace, Name: entry.Quota}] {
+			left = append(left, entry)
+		}
+	}
+	return d.postpone(ctx, left, now)
+}
+
+// postpone moves the entries to the next digest. Retrying them on the next
+// poll would open a digest every few minutes for a manifest that is not
+// there.
+func (d *ShrinkDigest) postpone(ctx context.Context, entries []git.DigestEntry, now time.Time) error {
+	for _, entry := range entries {
+		err := d.Reconciler.Locker.MutateState(ctx, entry.Namespace, entry.Quota, func(s *lock.State) {
+			if s.PendingDirection == git.DirectionShrink {
+				s.PendingSince = now
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to postpone %s/%s to the next digest: %w", entry.Namespace, entry.Quota, err)
+		}
+	}
+	return nil
+}
+
+// watchDigestRevert notices a team reverting its part of a merged shrink
+// digest. Until the cluster has carried the digest's limits once, a higher
+// limit only means the merge has not been applied yet; afterwards it means
+// the manifest was changed back, which counts as a rejection of the shrink
+// for this quota alone. A grow of its own, or the shrink cooldown running
+// out, ends the watch: past that point higher limits are no longer an answer
+// to the digest.
+func (r *ResourceQuotaReconciler) watchDigestRevert(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+	policy sizing.Policy,
+	state lock.State,
+) (lock.State, error) {
+	logger := log.FromContext(ctx)
+	now := time.Now()
+
+	var update func(*lock.State)
+	limits, err := decodeLimits(state.DigestLimits)
+	applied := err == nil && withinLimits(quota.Status.Hard, limits)
+	switch {
+	case err != nil:
+		logger.Error(err, "dropping unreadable shrink digest limits")
+		update = func(s *lock.State) { s.ForgetDigest() }
+	case state.DigestApplied.IsZero() && applied:
+		update = func(s *lock.State) { s.DigestApplied = now }
+	case state.DigestApplied.IsZero():
+		if now.Sub(state.LastShrink) < policy.ShrinkCooldown {
+			return state, nil
+		}
+		// Never applied; nothing left to revert.
+		update = func(s *lock.State) { s.ForgetDigest() }
+	case state.LastGrow.After(state.DigestApplied):
+		update = func(s *lock.State) { s.ForgetDigest() }
+	case !applied:
+		msg := fmt.Sprintf("The limits of a merged shrink digest were reverted for this quota; "+
+			"treating the shrink as rejected, no new one is proposed for %s",
+			formatDays(policy.ShrinkCooldown))
+		logger.Info(msg)
+		r.Recorder.Event(quota, corev1.EventTypeNormal, "ShrinkReverted", msg)
+		update = func(s *lock.State) {
+			s.ForgetDigest()
+			s.LastShrink = now
+		}
+	case now.Sub(state.DigestApplied) >= policy.ShrinkCooldown:
+		update = func(s *lock.State) { s.ForgetDigest() }
+	default:
+		return state, nil
+	}
+
+	if err := r.Locker.MutateState(ctx, quota.Namespace, quota.Name, update); err != nil {
+		return state, fmt.Errorf("failed to update shrink digest watch: %w", err)
+	}
+	update(&state)
+	return state, nil
+}
+
+// withinLimits reports whether hard is at or below limits for every resource
+// limits names.
+func withinLimits(hard corev1.ResourceList, limits map[corev1.ResourceName]resource.Quantity) bool {
+	for res, limit := range limits {
+		if current, ok := hard[res]; ok && current.Cmp(limit) > 0 {
+			return false
+		}
+	}
+	return true
+}
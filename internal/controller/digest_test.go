@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/sizing"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var digestNamespaces = []string{"team-a", "team-b"}
+
+// newDigestTestReconciler builds two namespaces with an oversized "compute"
+// quota each (hard 16, used 4), in digest mode.
+func newDigestTestReconciler(t *testing.T) (*ResourceQuotaReconciler, *FakeGitProvider, *record.FakeRecorder) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = coordinationv1.AddToScheme(scheme)
+
+	var objects []client.Object
+	for _, name := range digestNamespaces {
+		objects = append(objects,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}},
+			&corev1.ResourceQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: name},
+				Status: corev1.ResourceQuotaStatus{
+					Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("16")},
+					Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")},
+				},
+			})
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	locker := lock.NewLeaseLocker(c)
+	provider := &FakeGitProvider{CreatePRID: 77}
+	recorder := record.NewFakeRecorder(20)
+	return &ResourceQuotaReconciler{
+		Client:             c,
+		Scheme:             scheme,
+		Recorder:           recorder,
+		GitProvider:        provider,
+		Locker:             locker,
+		Observer:           NewObserver(locker, time.Now),
+		BasePolicy:         sizing.DefaultPolicy(),
+		EnableShrinkDigest: true,
+	}, provider, recorder
+}
+
+func reconcileIn(r *ResourceQuotaReconciler, namespace string) error {
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: namespace, Name: "compute"},
+	})
+	return err
+}
+
+func TestDigest_ShrinkIsQueuedInsteadOfOpened(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true})
+	h.reconciler.EnableShrinkDigest = true
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0))
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(0))
+	g.Expect(state.PendingDirection).To(Equal(git.DirectionShrink))
+	g.Expect(state.PendingLimits).NotTo(BeEmpty())
+}
+
+func TestDigest_OnePRForEveryNamespaceAfterTheInterval(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	r, provider, _ := newDigestTestReconciler(t)
+	now := time.Now()
+
+	for i, ns := range digestNamespaces {
+		since := now.Add(-time.Duration(i+1) * time.Hour)
+		g.Expect(r.Locker.MutateState(ctx, ns, "compute", func(s *lock.State) {
+			s.PendingDirection = git.DirectionShrink
+			s.PendingSince = since
+			s.PendingLimits = `{"requests.cpu":"12"}`
+		})).To(Succeed())
+	}
+
+	digest := &ShrinkDigest{Reconciler: r, Interval: 3 * time.Hour}
+	g.Expect(digest.run(ctx, now)).To(Succeed())
+	g.Expect(provider.CreateDigestPRCalls).To(Equal(0), "the oldest proposal is only two hours old")
+
+	digest.Interval = 2 * time.Hour
+	g.Expect(digest.run(ctx, now)).To(Succeed())
+	g.Expect(provider.CreateDigestPRCalls).To(Equal(1))
+	g.Expect(provider.LastDigest).To(HaveLen(2))
+	g.Expect(provider.LastDigest[0].Namespace).To(Equal("team-a"))
+	g.Expect(provider.LastDigest[1].Namespace).To(Equal("team-b"))
+
+	for _, ns := range digestNamespaces {
+		state, err := r.Locker.GetState(ctx, ns, "compute")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(state.PRID).To(Equal(77), ns)
+		g.Expect(state.PRDirection).To(Equal(git.DirectionShrink), ns)
+		g.Expect(state.PRDigest).To(BeTrue(), ns)
+		g.Expect(state.PendingDirection).To(BeEmpty(), ns)
+		g.Expect(state.DigestLimits).To(Equal(`{"requests.cpu":"12"}`), ns)
+		g.Expect(state.LastShrink.Equal(now.Truncate(time.Second))).To(BeTrue(), ns)
+	}
+}
+
+func TestDigest_MergeReleasesEveryNamespace(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	r, provider, _ := newDigestTestReconciler(t)
+	provider.PRStatus = &git.PRStatus{IsOpen: false, IsMerged: true}
+
+	for _, ns := range digestNamespaces {
+		g.Expect(r.Locker.MutateState(ctx, ns, "compute", func(s *lock.State) {
+			s.PRID = 77
+			s.PRDirection = git.DirectionShrink
+			s.PRDigest = true
+			s.DigestLimits = `{"requests.cpu":"12"}`
+		})).To(Succeed())
+	}
+
+	g.Expect(reconcileIn(r, "team-a")).To(Succeed())
+
+	for _, ns := range digestNamespaces {
+		state, err := r.Locker.GetState(ctx, ns, "compute")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(state.PRID).To(Equal(0), ns)
+		g.Expect(state.PRDigest).To(BeFalse(), ns)
+		g.Expect(state.LastShrink.IsZero()).To(BeFalse(), ns)
+		g.Expect(state.DigestLimits).NotTo(BeEmpty(), "%s: a merged digest is watched for reverts", ns)
+	}
+}
+
+func TestDigest_RevertIsARejectionForThatQuotaOnly(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	r, _, recorder := newDigestTestReconciler(t)
+	merged := time.Now().Add(-2 * time.Hour)
+
+	// Both quotas carried the digest's 12 CPU for a while; team-a's manifest
+	// was then reverted to 16, team-b's still says 12.
+	for _, ns := range digestNamespaces {
+		g.Expect(r.Locker.MutateState(ctx, ns, "compute", func(s *lock.State) {
+			s.LastShrink = merged
+			s.DigestLimits = `{"requests.cpu":"12"}`
+			s.DigestApplied = merged.Add(time.Hour)
+		})).To(Succeed())
+	}
+	var teamB corev1.ResourceQuota
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: "team-b", Name: "compute"}, &teamB)).To(Succeed())
+	teamB.Status.Hard[corev1.ResourceRequestsCPU] = resource.MustParse("12")
+	g.Expect(r.Update(ctx, &teamB)).To(Succeed())
+
+	for _, ns := range digestNamespaces {
+		g.Expect(reconcileIn(r, ns)).To(Succeed())
+	}
+
+	reverted, err := r.Locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reverted.LastShrink.After(merged)).To(BeTrue(), "the revert restarts the cooldown")
+	g.Expect(reverted.DigestLimits).To(BeEmpty())
+
+	kept, err := r.Locker.GetState(ctx, "team-b", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kept.LastShrink.Equal(merged.Truncate(time.Second))).To(BeTrue())
+	g.Expect(kept.DigestLimits).NotTo(BeEmpty())
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	g.Expect(strings.Join(events, "\n")).To(ContainSubstring("ShrinkReverted"))
+	g.Expect(events).To(HaveLen(1))
+}
+
+func TestDigest_UnsyncedMergeIsNotARevert(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	r, _, _ := newDigestTestReconciler(t)
+	merged := time.Now().Add(-time.Hour)
+
+	g.Expect(r.Locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.LastShrink = merged
+		s.DigestLimits = `{"requests.cpu":"12"}`
+	})).To(Succeed())
+
+	g.Expect(reconcileIn(r, "team-a")).To(Succeed())
+
+	state, err := r.Locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.LastShrink.Equal(merged.Truncate(time.Second))).To(BeTrue())
+	g.Expect(state.DigestLimits).NotTo(BeEmpty(), "still waiting for the merge to be applied")
+	g.Expect(state.DigestApplied.IsZero()).To(BeTrue())
+}
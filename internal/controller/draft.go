@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/sizing"
+)
+
+// opensDraft reports whether a proposal in direction is opened as a draft:
+// shrinks are, when a soak is configured and the provider supports drafts.
+func (r *ResourceQuotaReconciler) opensDraft(provider git.Provider, direction sizing.Direction) bool {
+	_, ok := provider.(git.DraftProposer)
+	return ok && r.ShrinkDraftSoak > 0 && direction == sizing.DirectionShrink
+}
+
+// shrinkTTLStart returns when the TTL of an open shrink pull request starts:
+// once it was marked ready for review, since before that nobody was asked to
+// review it. A draft has no TTL, the soak decides its fate. A pull request
+// the lock knows nothing about started when it was opened.
+func shrinkTTLStart(state lock.State, status *git.PRStatus) time.Time {
+	switch {
+	case state.PRDraft:
+		return time.Time{}
+	case !state.PRReady.IsZero():
+		return state.PRReady
+	default:
+		return status.CreatedAt
+	}
+}
+
+// soakDraft decides the fate of a draft shrink pull request. It is marked
+// ready for review once it has been open for ShrinkDraftSoak and closed as
+// soon as the soak is broken: a recommendation above the proposed limits, or
+// a workload failing to be created for lack of quota.
+func (r *ResourceQuotaReconciler) soakDraft(
+	ctx context.Context,
+	req ctrl.Request,
+	provider git.Provider,
+	quota corev1.ResourceQuota,
+	state lock.State,
+	status *git.PRStatus,
+	decision sizing.Decision,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	openedAt := status.CreatedAt
+	if openedAt.IsZero() {
+		openedAt = state.LastShrink
+	}
+
+	reason, broken, err := r.draftSoakBroken(ctx, quota, state, openedAt, decision)
+	if err != nil {
+		// Unverified is not ready: the next reconcile tries again.
+		return ctrl.Result{}, err
+	}
+	now := time.Now()
+	if broken {
+		logger.Info("Closing draft shrink PR", "prID", state.PRID, "reason", reason)
+		if err := provider.ClosePR(ctx, state.PRID, reason); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to close draft shrink PR %d: %w", state.PRID, err)
+		}
+		err := r.mutatePRHolders(ctx, req.Namespace, quota.Name, state, func(s *lock.State) {
+			s.ReleasePR()
+			s.ForgetDigest()
+			s.LastShrink = now
+		})
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to release lock after closing draft shrink PR: %w", err)
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if remaining := openedAt.Add(r.ShrinkDraftSoak).Sub(now); remaining > 0 {
+		logger.Info("Draft shrink PR is soaking", "prID", state.PRID, "remaining", remaining)
+		return ctrl.Result{RequeueAfter: min(remaining+time.Second, 5*time.Minute)}, nil
+	}
+
+	if drafter, ok := provider.(git.DraftProposer); ok {
+		if err := drafter.MarkReady(ctx, state.PRID); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	logger.Info("Draft shrink PR soaked, marked ready for review", "prID", state.PRID)
+	err = r.mutatePRHolders(ctx, req.Namespace, quota.Name, state, func(s *lock.State) {
+		s.PRDraft = false
+		s.PRReady = now
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to record that PR %d is ready for review: %w", state.PRID, err)
+	}
+	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// draftSoakBroken reports whether a draft shrink pull request must not be
+// marked ready, and why. Every resource it proposes has to still be a shrink
+// candidate at the same or a lower value, and no workload may have failed to
+// be created for lack of quota since it was opened.
+func (r *ResourceQuotaReconciler) draftSoakBroken(
+	ctx context.Context,
+	quota corev1.ResourceQuota,
+	state lock.State,
+	openedAt time.Time,
+	decision sizing.Decision,
+) (string, bool, error) {
+	deficits, err := r.collectDeficits(ctx, quota, openedAt)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to scan for shortages during the soak: %w", err)
+	}
+	for res, deficit := range deficits {
+		if deficit > 0 {
+			return fmt.Sprintf("Closing automatically: a workload failed to be created for lack of %s "+
+				"while this shrink proposal was soaking as a draft.", res), true, nil
+		}
+	}
+
+	if state.PRLimits == "" {
+		return "", false, nil
+	}
+	proposed, err := decodeLimits(state.PRLimits)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the draft's proposed limits: %w", err)
+	}
+	for res, limit := range proposed {
+		preview, ok := decision.ShrinkPreview[res]
+		if !ok || preview.Cmp(limit) > 0 {
+			return fmt.Sprintf("Closing automatically: the recommendation for %s rose above the "+
+				"proposed %s while this shrink proposal was soaking as a draft. A fresh proposal "+
+				"will be opened once the cooldown expires.", res, limit.String()), true, nil
+		}
+	}
+	return "", false, nil
+}
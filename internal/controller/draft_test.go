@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// draftingProvider is a FakeGitProvider that can open drafts and records
+// which pull requests were marked ready.
+type draftingProvider struct {
+	*FakeGitProvider
+	draft   bool
+	readied []int
+}
+
+func (f *draftingProvider) AsDraft() git.Provider {
+	f.draft = true
+	return f
+}
+
+func (f *draftingProvider) MarkReady(_ context.Context, prID int) error {
+	f.readied = append(f.readied, prID)
+	return nil
+}
+
+func newDraftHarness(t *testing.T) (*shrinkHarness, *draftingProvider) {
+	t.Helper()
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true})
+	provider := &draftingProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = provider
+	h.reconciler.ShrinkDraftSoak = 24 * time.Hour
+	return h, provider
+}
+
+func TestDraft_SoakThenReady(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h, provider := newDraftHarness(t)
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	g.Expect(provider.draft).To(BeTrue())
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRDraft).To(BeTrue())
+	g.Expect(state.PRLimits).NotTo(BeEmpty())
+
+	// Halfway through the soak nothing happens.
+	h.provider.PRStatus = &git.PRStatus{IsOpen: true, CreatedAt: time.Now().Add(-12 * time.Hour)}
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+	g.Expect(provider.readied).To(BeEmpty())
+	g.Expect(h.provider.ClosePRCalls).To(Equal(0))
+
+	h.provider.PRStatus = &git.PRStatus{IsOpen: true, CreatedAt: time.Now().Add(-25 * time.Hour)}
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+	g.Expect(provider.readied).To(Equal([]int{43}))
+	g.Expect(h.provider.ClosePRCalls).To(Equal(0))
+
+	state, err = h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRDraft).To(BeFalse())
+	g.Expect(state.PRReady.IsZero()).To(BeFalse())
+}
+
+func TestDraft_ClosedWhenTheRecommendationRises(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h, provider := newDraftHarness(t)
+	proposed, err := encodeLimits(map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceRequestsCPU: resource.MustParse("1"),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 42
+		s.PRDirection = git.DirectionShrink
+		s.PRDraft = true
+		s.PRLimits = proposed
+		s.LastShrink = time.Now().Add(-time.Hour)
+	})).To(Succeed())
+	h.provider.PRStatus = &git.PRStatus{IsOpen: true, CreatedAt: time.Now().Add(-time.Hour)}
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.ClosePRCalls).To(Equal(1))
+	g.Expect(h.provider.ClosedComment).To(ContainSubstring("rose above the proposed 1"))
+	g.Expect(provider.readied).To(BeEmpty())
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(0))
+	g.Expect(state.PRDraft).To(BeFalse())
+}
+
+func TestDraft_ClosedOnFailedCreate(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{IsOpen: true, CreatedAt: time.Now().Add(-time.Hour)},
+		shrinkHarnessOpts{window: true}, shortageObjects("1")...)
+	provider := &draftingProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = provider
+	h.reconciler.ShrinkDraftSoak = time.Minute
+	proposed, err := encodeLimits(map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceRequestsCPU: resource.MustParse("16"),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 42
+		s.PRDirection = git.DirectionShrink
+		s.PRDraft = true
+		s.PRLimits = proposed
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.ClosePRCalls).To(Equal(1))
+	g.Expect(h.provider.ClosedComment).To(ContainSubstring("failed to be created"))
+	g.Expect(provider.readied).To(BeEmpty(), "the soak is over, but it was broken")
+}
+
+func TestShrink_TTLCountsFromReadyForReview(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{
+		IsOpen:    true,
+		CreatedAt: time.Now().Add(-30 * 24 * time.Hour),
+	}, shrinkHarnessOpts{window: true})
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 42
+		s.PRDirection = git.DirectionShrink
+		s.PRReady = time.Now().Add(-24 * time.Hour)
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.ClosePRCalls).To(Equal(0))
+}
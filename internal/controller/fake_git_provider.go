@@ -32,6 +32,14 @@ type FakeGitProvider struct {
 	LastLimits map[corev1.ResourceName]resource.Quantity
 	// LastDirection records the direction passed to the most recent CreatePR.
 	LastDirection string
+	// CreateBatchPRCalls counts CreateBatchPR invocations; LastBatch records
+	// the changes of the most recent one.
+	CreateBatchPRCalls int
+	LastBatch          []git.QuotaChange
+	// CreateDigestPRCalls counts CreateDigestPR invocations; LastDigest
+	// records the entries of the most recent one.
+	CreateDigestPRCalls int
+	LastDigest          []git.DigestEntry
 
 	// ClosedPRID and ClosedComment record the most recent ClosePR call.
 	ClosedPRID    int
@@ -71,6 +79,7 @@ func (f *FakeGitProvider) MergePR(ctx context.Context, prID int, method string)
 func (f *FakeGitProvider) FindOpenPR(
 	ctx context.Context,
 	namespace, quotaName string,
+	annotations map[string]string,
 ) (int, string, error) {
 	f.FindOpenPRCalls++
 	direction := f.ExistingPRDirection
@@ -86,3 +95,32 @@ func (f *FakeGitProvider) ClosePR(ctx context.Context, prID int, comment string)
 	f.ClosedComment = comment
 	return nil
 }
+
+func (f *FakeGitProvider) CreateBatchPR(
+	ctx context.Context,
+	namespace, direction string,
+	annotations map[string]string,
+	changes []git.QuotaChange,
+) (int, error) {
+	f.CreateBatchPRCalls++
+	f.LastBatch = changes
+	f.LastDirection = direction
+	if f.CreatePRID != 0 {
+		return f.CreatePRID, nil
+	}
+	return 1, nil
+}
+
+func (f *FakeGitProvider) CreateDigestPR(
+	ctx context.Context,
+	baseBranch string,
+	entries []git.DigestEntry,
+) (int, []git.DigestEntry, error) {
+	f.CreateDigestPRCalls++
+	f.LastDigest = entries
+	f.LastDirection = git.DirectionShrink
+	if f.CreatePRID != 0 {
+		return f.CreatePRID, entries, nil
+	}
+	return 1, entries, nil
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// The shrink harness's quota (hard 16, used 4) without a covered window
+// yields DirectionNone, which is exactly what an obsolete grow PR sees.
+
+func TestGrowPR_IdleTimeIsRecorded(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	h := newShrinkHarness(t, &git.PRStatus{
+		IsOpen:    true,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+	}, shrinkHarnessOpts{})
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 42
+		s.PRDirection = git.DirectionGrow
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.ClosePRCalls).To(Equal(0), "the PR has not been idle long enough")
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(42))
+	g.Expect(state.GrowIdleSince.IsZero()).To(BeFalse())
+}
+
+func TestGrowPR_ClosedWhenObsolete(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	h := newShrinkHarness(t, &git.PRStatus{
+		IsOpen:    true,
+		CreatedAt: time.Now().Add(-3 * 24 * time.Hour),
+	}, shrinkHarnessOpts{})
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 42
+		s.PRDirection = git.DirectionGrow
+		s.GrowIdleSince = time.Now().Add(-25 * time.Hour)
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.ClosePRCalls).To(Equal(1))
+	g.Expect(h.provider.ClosedPRID).To(Equal(42))
+	g.Expect(h.provider.ClosedComment).To(ContainSubstring("fits the existing limits"))
+	g.Expect(h.provider.ClosedComment).To(ContainSubstring("needed for 1 day "))
+
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(0))
+	g.Expect(state.GrowIdleSince.IsZero()).To(BeTrue())
+	g.Expect(state.LastGrow.IsZero()).To(BeTrue(), "an abandoned grow must not start any cooldown")
+}
+
+func TestGrowPR_ExpiresAfterTTL(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	// A pending shortage keeps the decision at grow, so only the TTL applies.
+	h := newShrinkHarness(t, &git.PRStatus{
+		IsOpen:    true,
+		CreatedAt: time.Now().Add(-15 * 24 * time.Hour),
+	}, shrinkHarnessOpts{}, shortageObjects("20")...)
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 42
+		s.PRDirection = git.DirectionGrow
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.ClosePRCalls).To(Equal(1))
+	g.Expect(h.provider.ClosedComment).To(ContainSubstring("14 days"))
+	g.Expect(h.provider.UpdatePRCalls).To(Equal(0))
+
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(0))
+}
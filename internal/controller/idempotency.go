@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// withCreateKey records the idempotency key of the pull request about to be
+// opened for changes on the Lease of every quota in it, before anything is
+// created, and returns provider scoped to that key. A key an interrupted
+// attempt recorded wins over a fresh one, so that its branch is picked up
+// even if the targets moved since.
+func (r *ResourceQuotaReconciler) withCreateKey(
+	ctx context.Context,
+	provider git.Provider,
+	namespace, direction string,
+	changes []git.QuotaChange,
+	states map[string]lock.State,
+) (git.Provider, error) {
+	creator, ok := provider.(git.IdempotentCreator)
+	if !ok {
+		return provider, nil
+	}
+	var key string
+	for _, change := range changes {
+		if key = states[change.Quota].CreateKey; key != "" {
+			break
+		}
+	}
+	if key == "" {
+		key = git.CreateKey(namespace, direction, changes)
+	}
+	for _, change := range changes {
+		if states[change.Quota].CreateKey == key {
+			continue
+		}
+		err := r.Locker.MutateState(ctx, namespace, change.Quota, func(s *lock.State) {
+			s.CreateKey = key
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record the create key of %s: %w", change.Quota, err)
+		}
+	}
+	return creator.WithCreateKey(key), nil
+}
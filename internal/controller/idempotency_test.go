@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// keyedProvider is a FakeGitProvider that takes a create key and records
+// which key the Lease held when CreatePR was called.
+type keyedProvider struct {
+	*FakeGitProvider
+	locker    *lock.LeaseLocker
+	key       string
+	leaseKeys []string
+	fail      bool
+}
+
+func (f *keyedProvider) WithCreateKey(key string) git.Provider {
+	f.key = key
+	return f
+}
+
+func (f *keyedProvider) CreatePR(
+	ctx context.Context,
+	quotaName, namespace, direction string,
+	annotations map[string]string,
+	newLimits map[corev1.ResourceName]resource.Quantity,
+) (int, error) {
+	state, err := f.locker.GetState(ctx, namespace, quotaName)
+	if err != nil {
+		return 0, err
+	}
+	f.leaseKeys = append(f.leaseKeys, state.CreateKey)
+	if f.fail {
+		return 0, errors.New("leader lost")
+	}
+	return f.FakeGitProvider.CreatePR(ctx, quotaName, namespace, direction, annotations, newLimits)
+}
+
+func TestCreateKey_RecordedBeforeCreating(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true})
+	provider := &keyedProvider{FakeGitProvider: h.provider, locker: h.locker, fail: true}
+	h.reconciler.GitProvider = provider
+
+	g.Expect(h.reconcile(ctx)).NotTo(Succeed())
+	g.Expect(provider.key).NotTo(BeEmpty())
+	g.Expect(provider.leaseKeys).To(Equal([]string{provider.key}))
+	first := provider.key
+
+	// The next attempt, by this or another replica, resumes under the same
+	// key and clears it once the pull request is recorded.
+	provider.fail = false
+	provider.key = ""
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+	g.Expect(provider.key).To(Equal(first))
+
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(43))
+	g.Expect(state.CreateKey).To(BeEmpty())
+}
+
+func TestCreateKey_RecordedKeyWins(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true})
+	provider := &keyedProvider{FakeGitProvider: h.provider, locker: h.locker}
+	h.reconciler.GitProvider = provider
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.CreateKey = "00000000deadbeef"
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(provider.key).To(Equal("00000000deadbeef"))
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+}
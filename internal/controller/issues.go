@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// issueResolvedComment is posted on an issue the controller closes because
+// the quota carries its recommendation.
+const issueResolvedComment = "The quota's `spec.hard` now carries the recommended limits. Closing this issue."
+
+// isIssueMode reports whether provider proposes changes as issues. Issues are
+// filed per quota and resolved by the controller, so batching and the shrink
+// digest do not apply.
+func isIssueMode(provider git.Provider) bool {
+	_, ok := provider.(git.IssueTracker)
+	return ok
+}
+
+// recordIssueLimits stores the limits an issue recommends, so that the
+// controller can tell when the quota carries them.
+func (r *ResourceQuotaReconciler) recordIssueLimits(
+	ctx context.Context,
+	req ctrl.Request,
+	provider git.Provider,
+	state lock.State,
+	limits map[corev1.ResourceName]resource.Quantity,
+) error {
+	if !isIssueMode(provider) {
+		return nil
+	}
+	encoded, err := encodeLimits(limits)
+	if err != nil {
+		return err
+	}
+	if encoded == state.PRLimits {
+		return nil
+	}
+	return r.Locker.MutateState(ctx, req.Namespace, req.Name, func(s *lock.State) {
+		s.PRLimits = encoded
+	})
+}
+
+// resolveAppliedIssue closes the open issue once the quota's spec carries
+// what it recommends, and releases the lock as if a pull request had been
+// merged: the cooldowns start from here. It reports whether it did.
+func (r *ResourceQuotaReconciler) resolveAppliedIssue(
+	ctx context.Context,
+	req ctrl.Request,
+	provider git.Provider,
+	quota corev1.ResourceQuota,
+	state lock.State,
+) (bool, error) {
+	tracker, ok := provider.(git.IssueTracker)
+	if !ok || state.PRLimits == "" {
+		return false, nil
+	}
+	limits, err := decodeLimits(state.PRLimits)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the issue's recommended limits: %w", err)
+	}
+	if !carriesLimits(quota.Spec.Hard, limits) {
+		return false, nil
+	}
+
+	log.FromContext(ctx).Info("Quota carries the recommended limits, resolving issue", "issue", state.PRID)
+	if err := tracker.ResolveIssue(ctx, state.PRID, issueResolvedComment); err != nil {
+		return false, fmt.Errorf("failed to resolve issue %d: %w", state.PRID, err)
+	}
+	now := time.Now()
+	err = r.mutatePRHolders(ctx, req.Namespace, quota.Name, state, func(s *lock.State) {
+		wasShrink := s.PRDirection == git.DirectionShrink
+		s.ReleasePR()
+		s.LastModified = now
+		if wasShrink {
+			s.LastShrink = now
+		} else {
+			s.LastGrow = now
+		}
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to release lock after resolving issue %d: %w", state.PRID, err)
+	}
+	return true, nil
+}
+
+// carriesLimits reports whether hard sets every resource of limits to exactly
+// the recommended value.
+func carriesLimits(hard corev1.ResourceList, limits map[corev1.ResourceName]resource.Quantity) bool {
+	for res, limit := range limits {
+		current, ok := hard[res]
+		if !ok || current.Cmp(limit) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// fakeIssueProvider is a FakeGitProvider in issue mode.
+type fakeIssueProvider struct {
+	*FakeGitProvider
+	ResolvedIDs []int
+}
+
+func (f *fakeIssueProvider) ResolveIssue(ctx context.Context, id int, comment string) error {
+	f.ResolvedIDs = append(f.ResolvedIDs, id)
+	return nil
+}
+
+func TestIssueMode_ShrinkIsFiledInsteadOfQueued(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true})
+	h.reconciler.EnableShrinkDigest = true
+	issues := &fakeIssueProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = issues
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1), "issues are never queued for a digest")
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(43))
+	g.Expect(state.PRLimits).NotTo(BeEmpty())
+}
+
+func TestIssueMode_ResolvedOnceTheQuotaCarriesTheLimits(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{IsOpen: true}, shrinkHarnessOpts{})
+	issues := &fakeIssueProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = issues
+
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.PRID = 31
+		s.PRDirection = git.DirectionShrink
+		s.PRLimits = `{"requests.cpu":"8"}`
+	})).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+	g.Expect(issues.ResolvedIDs).To(BeEmpty(), "the quota still says 16")
+
+	var quota corev1.ResourceQuota
+	g.Expect(h.reconciler.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "compute"}, &quota)).To(Succeed())
+	quota.Spec.Hard = corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("8")}
+	g.Expect(h.reconciler.Update(ctx, &quota)).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+	g.Expect(issues.ResolvedIDs).To(Equal([]int{31}))
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(0))
+	g.Expect(state.PRLimits).To(BeEmpty())
+	g.Expect(state.LastShrink.IsZero()).To(BeFalse(), "the shrink cooldown starts when the issue is resolved")
+	g.Expect(state.LastModified.IsZero()).To(BeFalse())
+}
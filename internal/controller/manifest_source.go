@@ -0,0 +1,349 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+)
+
+// Argo CD marks every object it applies with one of these, depending on the
+// configured resource tracking method.
+const (
+	argoInstanceLabel        = "argocd.argoproj.io/instance"
+	argoTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+
+	// DefaultArgoCDNamespace is where Argo CD keeps its Applications unless
+	// configured otherwise.
+	DefaultArgoCDNamespace = "argocd"
+)
+
+// Flux's kustomize-controller labels every object it applies with the
+// Kustomization that produced it.
+const (
+	fluxNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+
+	// fluxDefaultBranch is what a GitRepository without spec.ref checks out.
+	fluxDefaultBranch = "master"
+)
+
+var (
+	argoApplicationGVK = schema.GroupVersionKind{
+		Group:   "argoproj.io",
+		Version: "v1alpha1",
+		Kind:    "Application",
+	}
+	fluxKustomizationGVK = schema.GroupVersionKind{
+		Group:   "kustomize.toolkit.fluxcd.io",
+		Version: "v1",
+		Kind:    "Kustomization",
+	}
+	fluxGitRepositoryGVK = schema.GroupVersionKind{
+		Group:   "source.toolkit.fluxcd.io",
+		Version: "v1",
+		Kind:    "GitRepository",
+	}
+)
+
+// unsupportedSourceError reports a GitOps source that a pull request cannot
+// change: an OCI artifact, a bucket, or a git repository pinned to a tag or
+// commit.
+type unsupportedSourceError struct {
+	kind, namespace, name, reason string
+}
+
+func (e *unsupportedSourceError) Error() string {
+	return fmt.Sprintf("%s %s/%s %s", e.kind, e.namespace, e.name, e.reason)
+}
+
+// providerFor returns the git provider a proposal for quota has to go
+// through. The namespace's route, if any, picks the repository and
+// credentials; otherwise the global provider is used. When the quota was
+// deployed by a GitOps tool that provider is further scoped to the
+// repository, revision and path the tool syncs from; otherwise, or when the
+// provider cannot be scoped, its path template applies.
+//
+// Routing and discovery errors are returned rather than papered over with the
+// global provider: an active pull request number in the Lease belongs to the
+// routed or discovered repository, and asking another repository about it
+// could merge or close an unrelated pull request.
+func (r *ResourceQuotaReconciler) providerFor(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+	ns *corev1.Namespace,
+) (git.Provider, error) {
+	base := r.GitProvider
+	if r.Router != nil {
+		routed, err := r.Router.ProviderFor(ctx, ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to route namespace %s: %w", ns.Name, err)
+		}
+		base = routed
+	}
+
+	scoper, ok := base.(git.SourceScoper)
+	if !ok {
+		return base, nil
+	}
+	src, err := r.discoverSource(ctx, quota)
+	var unsupported *unsupportedSourceError
+	if errors.As(err, &unsupported) {
+		msg := fmt.Sprintf("Quota is deployed from %s; no pull request can change it there, "+
+			"falling back to the configured repository", unsupported.Error())
+		log.FromContext(ctx).Info(msg)
+		r.Recorder.Event(quota, corev1.EventTypeWarning, "ManifestSourceUnsupported", msg)
+		return base, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the manifest source: %w", err)
+	}
+	if src == nil {
+		return base, nil
+	}
+	provider, err := scoper.WithSource(*src)
+	if err != nil {
+		// Deterministic for a given Application, so falling back cannot
+		// flip between two repositories from one reconcile to the next.
+		log.FromContext(ctx).Info("Discovered source is not usable, falling back to the path template",
+			"repoURL", src.RepoURL, "reason", err.Error())
+		return base, nil
+	}
+	log.FromContext(ctx).V(1).Info("Using discovered manifest source",
+		"repoURL", src.RepoURL, "revision", src.Revision, "path", src.Path)
+	return provider, nil
+}
+
+// discoverSource asks the GitOps tools in turn where the quota comes from:
+// Argo CD first, then Flux. It returns nil if none of them manages it.
+func (r *ResourceQuotaReconciler) discoverSource(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+) (*git.Source, error) {
+	src, err := r.argoSource(ctx, quota)
+	if src != nil || err != nil {
+		return src, err
+	}
+	return r.fluxSource(ctx, quota)
+}
+
+// argoSource resolves the Argo CD Application that owns quota and returns the
+// source it deploys from.
+func (r *ResourceQuotaReconciler) argoSource(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+) (*git.Source, error) {
+	appNamespace, appName := argoApplicationFor(quota)
+	if appName == "" {
+		return nil, nil
+	}
+	if appNamespace == "" {
+		appNamespace = r.ArgoCDNamespace
+		if appNamespace == "" {
+			appNamespace = DefaultArgoCDNamespace
+		}
+	}
+
+	app, err := r.getGitOpsObject(ctx, argoApplicationGVK, appNamespace, appName)
+	if app == nil || err != nil {
+		return nil, err
+	}
+	return argoApplicationSource(app)
+}
+
+// getGitOpsObject reads an object of a GitOps tool's API. It returns nil
+// without an error when the object or the API itself does not exist: a
+// copied label, a deleted object or a cluster without that tool all mean the
+// quota is not managed by it as far as we can tell.
+func (r *ResourceQuotaReconciler) getGitOpsObject(
+	ctx context.Context,
+	gvk schema.GroupVersionKind,
+	namespace, name string,
+) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		log.FromContext(ctx).V(1).Info("GitOps object not found",
+			"kind", gvk.Kind, "namespace", namespace, "name", name)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s/%s: %w", gvk.Kind, namespace, name, err)
+	}
+	return obj, nil
+}
+
+// argoApplicationFor reads the owning Application's namespace and name from
+// the tracking annotation or, failing that, the instance label. The namespace
+// is only known when Argo CD runs with applications in any namespace, where
+// the name is written as <namespace>_<name>.
+func argoApplicationFor(quota *corev1.ResourceQuota) (string, string) {
+	var app string
+	if id, ok := quota.Annotations[argoTrackingIDAnnotation]; ok {
+		// <app>:<group>/<kind>:<namespace>/<name>. The annotation is only
+		// trusted when it names this very object; one copied along with a
+		// manifest would otherwise redirect the pull request.
+		parts := strings.SplitN(id, ":", 3)
+		if len(parts) == 3 &&
+			parts[1] == "/ResourceQuota" &&
+			parts[2] == quota.Namespace+"/"+quota.Name {
+			app = parts[0]
+		}
+	}
+	if app == "" {
+		app = quota.Labels[argoInstanceLabel]
+	}
+	if app == "" {
+		return "", ""
+	}
+	if ns, name, ok := strings.Cut(app, "_"); ok {
+		return ns, name
+	}
+	return "", app
+}
+
+// argoApplicationSource returns the git source of an Application. A
+// multi-source Application contributes its first source that renders a
+// directory of manifests; Helm chart sources and pure ref sources (values
+// files) cannot hold the quota manifest. As in Argo CD itself, spec.sources
+// wins over spec.source when both are set.
+func argoApplicationSource(app *unstructured.Unstructured) (*git.Source, error) {
+	var candidates []map[string]any
+	if multi, ok, _ := unstructured.NestedSlice(app.Object, "spec", "sources"); ok && len(multi) > 0 {
+		for _, item := range multi {
+			if src, ok := item.(map[string]any); ok {
+				candidates = append(candidates, src)
+			}
+		}
+	} else if single, ok, _ := unstructured.NestedMap(app.Object, "spec", "source"); ok {
+		candidates = append(candidates, single)
+	}
+
+	for _, src := range candidates {
+		repoURL, _, _ := unstructured.NestedString(src, "repoURL")
+		path, hasPath, _ := unstructured.NestedString(src, "path")
+		chart, _, _ := unstructured.NestedString(src, "chart")
+		if repoURL == "" || !hasPath || chart != "" {
+			continue
+		}
+		revision, _, _ := unstructured.NestedString(src, "targetRevision")
+		branch, err := argoBranch(app, revision)
+		if err != nil {
+			return nil, err
+		}
+		return &git.Source{RepoURL: repoURL, Revision: branch, Path: path}, nil
+	}
+	return nil, nil
+}
+
+var (
+	// argoCommitPattern matches a full SHA-1 or SHA-256 commit hash.
+	argoCommitPattern = regexp.MustCompile(`^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)
+	// argoVersionPattern matches a version tag such as "v1.2" or "1.2.3-rc.1",
+	// and a semver constraint such as "1.2.*", "^1.2" or ">=1.0.0 <2.0.0".
+	argoVersionPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9xX*]+)*(-[0-9A-Za-z.-]+)?$|[*^~<>=|, ]`)
+)
+
+// argoBranch returns the branch an Application's targetRevision tracks. An
+// empty revision or "HEAD" is returned as "HEAD", the repository's default
+// branch. As with fluxBranch, a revision pinned to a tag, a semver range or
+// a commit tracks no branch, so a merged pull request would never be
+// deployed. A tag is only recognised by its refs/tags/ prefix or by looking
+// like a version; any other name is taken for a branch.
+func argoBranch(app *unstructured.Unstructured, revision string) (string, error) {
+	if revision == "" || revision == "HEAD" {
+		return "HEAD", nil
+	}
+	if branch, ok := strings.CutPrefix(revision, "refs/heads/"); ok {
+		return branch, nil
+	}
+	if !strings.HasPrefix(revision, "refs/") &&
+		!argoCommitPattern.MatchString(revision) &&
+		!argoVersionPattern.MatchString(revision) {
+		return revision, nil
+	}
+	return "", &unsupportedSourceError{
+		kind:      argoApplicationGVK.Kind,
+		namespace: app.GetNamespace(),
+		name:      app.GetName(),
+		reason:    fmt.Sprintf("is pinned to %q instead of a branch", revision),
+	}
+}
+
+// fluxSource follows the quota's Flux labels to its Kustomization and from
+// there to the GitRepository the Kustomization builds from.
+func (r *ResourceQuotaReconciler) fluxSource(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+) (*git.Source, error) {
+	ksName, ksNamespace := quota.Labels[fluxNameLabel], quota.Labels[fluxNamespaceLabel]
+	if ksName == "" || ksNamespace == "" {
+		return nil, nil
+	}
+	ks, err := r.getGitOpsObject(ctx, fluxKustomizationGVK, ksNamespace, ksName)
+	if ks == nil || err != nil {
+		return nil, err
+	}
+
+	kind, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "kind")
+	name, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "name")
+	namespace, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "namespace")
+	if namespace == "" {
+		namespace = ksNamespace
+	}
+	if kind != fluxGitRepositoryGVK.Kind {
+		return nil, &unsupportedSourceError{kind: kind, namespace: namespace, name: name,
+			reason: "is not a git repository"}
+	}
+
+	repo, err := r.getGitOpsObject(ctx, fluxGitRepositoryGVK, namespace, name)
+	if repo == nil || err != nil {
+		return nil, err
+	}
+	url, _, _ := unstructured.NestedString(repo.Object, "spec", "url")
+	branch, err := fluxBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+	path, _, _ := unstructured.NestedString(ks.Object, "spec", "path")
+	return &git.Source{RepoURL: url, Revision: branch, Path: path}, nil
+}
+
+// fluxBranch returns the branch a GitRepository tracks. A repository pinned
+// to a tag, a semver range or a commit tracks no branch, so a merged pull
+// request would never be deployed.
+func fluxBranch(repo *unstructured.Unstructured) (string, error) {
+	ref, _, _ := unstructured.NestedStringMap(repo.Object, "spec", "ref")
+	// Same precedence as the source-controller: commit, name, semver, tag,
+	// branch.
+	switch {
+	case ref["commit"] != "":
+	case ref["name"] != "":
+		if branch, ok := strings.CutPrefix(ref["name"], "refs/heads/"); ok {
+			return branch, nil
+		}
+	case ref["semver"] != "", ref["tag"] != "":
+	case ref["branch"] != "":
+		return ref["branch"], nil
+	default:
+		return fluxDefaultBranch, nil
+	}
+	return "", &unsupportedSourceError{
+		kind:      fluxGitRepositoryGVK.Kind,
+		namespace: repo.GetNamespace(),
+		name:      repo.GetName(),
+		reason:    "is pinned to a tag, semver range or commit instead of a branch",
+	}
+}
@@ -0,0 +1,359 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+)
+
+func newArgoApplication(namespace, name string, spec map[string]any) *unstructured.Unstructured {
+	app := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	app.SetGroupVersionKind(argoApplicationGVK)
+	app.SetNamespace(namespace)
+	app.SetName(name)
+	return app
+}
+
+func newArgoQuota(labels, annotations map[string]string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{
+		Name:        "compute",
+		Namespace:   "team-a",
+		Labels:      labels,
+		Annotations: annotations,
+	}}
+}
+
+func newSourceTestReconciler(objs ...client.Object) *ResourceQuotaReconciler {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	return &ResourceQuotaReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+var _ = Describe("Argo CD source discovery", func() {
+	It("reads the source of an Application served by the API server", func() {
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: DefaultArgoCDNamespace},
+		})).To(Succeed())
+		app := newArgoApplication(DefaultArgoCDNamespace, "team-a", map[string]any{
+			"source": map[string]any{
+				"repoURL":        "https://github.com/org/tenants.git",
+				"targetRevision": "main",
+				"path":           "clusters/prod/team-a",
+			},
+		})
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+
+		r := &ResourceQuotaReconciler{Client: k8sClient}
+		src, err := r.argoSource(ctx, newArgoQuota(map[string]string{argoInstanceLabel: "team-a"}, nil))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(src).To(Equal(&git.Source{
+			RepoURL:  "https://github.com/org/tenants.git",
+			Revision: "main",
+			Path:     "clusters/prod/team-a",
+		}))
+	})
+})
+
+func TestArgoSource_SingleSource(t *testing.T) {
+	g := NewWithT(t)
+	app := newArgoApplication("argocd", "team-a", map[string]any{
+		"source": map[string]any{
+			"repoURL":        "https://github.com/org/tenants.git",
+			"targetRevision": "main",
+			"path":           "clusters/prod/team-a",
+		},
+	})
+	r := newSourceTestReconciler(app)
+
+	src, err := r.argoSource(context.Background(),
+		newArgoQuota(map[string]string{argoInstanceLabel: "team-a"}, nil))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src).To(Equal(&git.Source{
+		RepoURL:  "https://github.com/org/tenants.git",
+		Revision: "main",
+		Path:     "clusters/prod/team-a",
+	}))
+}
+
+func TestArgoSource_MultiSourceSkipsChartsAndRefs(t *testing.T) {
+	g := NewWithT(t)
+	app := newArgoApplication("apps", "team-a", map[string]any{
+		"sources": []any{
+			map[string]any{"repoURL": "https://charts.example.com", "chart": "base", "targetRevision": "1.2.3"},
+			map[string]any{"repoURL": "https://github.com/org/values.git", "ref": "values"},
+			map[string]any{"repoURL": "git@github.com:org/tenants.git", "targetRevision": "HEAD", "path": "team-a"},
+		},
+	})
+	r := newSourceTestReconciler(app)
+	quota := newArgoQuota(nil, map[string]string{
+		argoTrackingIDAnnotation: "apps_team-a:/ResourceQuota:team-a/compute",
+	})
+
+	src, err := r.argoSource(context.Background(), quota)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src).NotTo(BeNil())
+	g.Expect(src.RepoURL).To(Equal("git@github.com:org/tenants.git"))
+	g.Expect(src.Path).To(Equal("team-a"))
+}
+
+func TestArgoSource_NotManaged(t *testing.T) {
+	g := NewWithT(t)
+	r := newSourceTestReconciler()
+
+	src, err := r.argoSource(context.Background(), newArgoQuota(nil, nil))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src).To(BeNil())
+
+	// A label pointing at an Application that does not exist is treated the
+	// same way.
+	src, err = r.argoSource(context.Background(),
+		newArgoQuota(map[string]string{argoInstanceLabel: "gone"}, nil))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src).To(BeNil())
+}
+
+func TestArgoBranch(t *testing.T) {
+	cases := []struct {
+		revision string
+		want     string
+		wantErr  bool
+	}{
+		{revision: "", want: "HEAD"},
+		{revision: "HEAD", want: "HEAD"},
+		{revision: "main", want: "main"},
+		{revision: "release/2024-q3", want: "release/2024-q3"},
+		{revision: "refs/heads/release", want: "release"},
+		{revision: "refs/tags/prod", wantErr: true},
+		{revision: "v1.4.0", wantErr: true},
+		{revision: "1.4.0-rc.1", wantErr: true},
+		{revision: "1.4.*", wantErr: true},
+		{revision: ">=1.0.0 <2.0.0", wantErr: true},
+		{revision: "4f2a9c0d1e3b5a7c9e1f3a5b7d9c1e3f5a7b9d1c", wantErr: true},
+	}
+	app := newArgoApplication("argocd", "team-a", nil)
+	for _, tc := range cases {
+		t.Run(tc.revision, func(t *testing.T) {
+			g := NewWithT(t)
+			branch, err := argoBranch(app, tc.revision)
+			if tc.wantErr {
+				var unsupported *unsupportedSourceError
+				g.Expect(errors.As(err, &unsupported)).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(branch).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestProviderFor_PinnedApplicationWarns checks that an Application pinned to
+// a tag falls back to the configured repository instead of proposing against
+// the tag.
+func TestProviderFor_PinnedApplicationWarns(t *testing.T) {
+	g := NewWithT(t)
+	app := newArgoApplication(DefaultArgoCDNamespace, "team-a", map[string]any{
+		"source": map[string]any{
+			"repoURL":        "https://github.com/org/tenants.git",
+			"targetRevision": "v2.3.1",
+			"path":           "clusters/prod/team-a",
+		},
+	})
+	r := newSourceTestReconciler(app)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	scoper := &scopingGitProvider{}
+	r.GitProvider = scoper
+
+	provider, err := r.providerFor(context.Background(),
+		newArgoQuota(map[string]string{argoInstanceLabel: "team-a"}, nil), &corev1.Namespace{})
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeIdenticalTo(scoper))
+	g.Expect(scoper.scopedTo).To(BeNil())
+	g.Expect(recorder.Events).To(Receive(And(
+		ContainSubstring("ManifestSourceUnsupported"),
+		ContainSubstring("Application argocd/team-a"))))
+}
+
+func TestArgoApplicationFor(t *testing.T) {
+	cases := []struct {
+		name            string
+		labels          map[string]string
+		annotations     map[string]string
+		wantNS, wantApp string
+	}{
+		{
+			name:    "instance label",
+			labels:  map[string]string{argoInstanceLabel: "team-a"},
+			wantApp: "team-a",
+		},
+		{
+			name:        "tracking id wins over the label",
+			labels:      map[string]string{argoInstanceLabel: "other"},
+			annotations: map[string]string{argoTrackingIDAnnotation: "team-a:/ResourceQuota:team-a/compute"},
+			wantApp:     "team-a",
+		},
+		{
+			name:        "tracking id with application namespace",
+			annotations: map[string]string{argoTrackingIDAnnotation: "apps_team-a:/ResourceQuota:team-a/compute"},
+			wantNS:      "apps",
+			wantApp:     "team-a",
+		},
+		{
+			name:        "tracking id of another object is ignored",
+			annotations: map[string]string{argoTrackingIDAnnotation: "team-b:/ResourceQuota:team-b/compute"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ns, app := argoApplicationFor(newArgoQuota(tc.labels, tc.annotations))
+			g.Expect(ns).To(Equal(tc.wantNS))
+			g.Expect(app).To(Equal(tc.wantApp))
+		})
+	}
+}
+
+// scopingGitProvider records the source the reconciler scoped it to.
+type scopingGitProvider struct {
+	FakeGitProvider
+	scopedTo *git.Source
+}
+
+func (s *scopingGitProvider) WithSource(src git.Source) (git.Provider, error) {
+	s.scopedTo = &src
+	return &s.FakeGitProvider, nil
+}
+
+func TestProviderFor_FallsBackWithoutApplication(t *testing.T) {
+	g := NewWithT(t)
+	app := newArgoApplication("argocd", "team-a", map[string]any{
+		"source": map[string]any{"repoURL": "https://github.com/org/tenants.git", "path": "team-a"},
+	})
+	r := newSourceTestReconciler(app)
+	scoper := &scopingGitProvider{}
+	r.GitProvider = scoper
+
+	provider, err := r.providerFor(context.Background(), newArgoQuota(nil, nil), &corev1.Namespace{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeIdenticalTo(scoper))
+	g.Expect(scoper.scopedTo).To(BeNil())
+
+	provider, err = r.providerFor(context.Background(),
+		newArgoQuota(map[string]string{argoInstanceLabel: "team-a"}, nil), &corev1.Namespace{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeIdenticalTo(&scoper.FakeGitProvider))
+	g.Expect(scoper.scopedTo.Path).To(Equal("team-a"))
+}
+
+func newFluxObject(gvk schema.GroupVersionKind, namespace, name string, spec map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+var fluxQuotaLabels = map[string]string{
+	fluxNameLabel:      "tenants",
+	fluxNamespaceLabel: "flux-system",
+}
+
+func TestFluxSource_GitRepository(t *testing.T) {
+	g := NewWithT(t)
+	ks := newFluxObject(fluxKustomizationGVK, "flux-system", "tenants", map[string]any{
+		"path":      "./clusters/prod/tenants",
+		"sourceRef": map[string]any{"kind": "GitRepository", "name": "fleet"},
+	})
+	repo := newFluxObject(fluxGitRepositoryGVK, "flux-system", "fleet", map[string]any{
+		"url": "ssh://git@github.com/org/fleet",
+		"ref": map[string]any{"branch": "main"},
+	})
+	r := newSourceTestReconciler(ks, repo)
+
+	src, err := r.discoverSource(context.Background(), newArgoQuota(fluxQuotaLabels, nil))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(src).To(Equal(&git.Source{
+		RepoURL:  "ssh://git@github.com/org/fleet",
+		Revision: "main",
+		Path:     "./clusters/prod/tenants",
+	}))
+}
+
+func TestFluxBranch(t *testing.T) {
+	cases := []struct {
+		name    string
+		ref     map[string]any
+		want    string
+		wantErr bool
+	}{
+		{name: "no ref", ref: nil, want: "master"},
+		{name: "branch", ref: map[string]any{"branch": "main"}, want: "main"},
+		{name: "full ref name", ref: map[string]any{"name": "refs/heads/release"}, want: "release"},
+		{name: "tag", ref: map[string]any{"tag": "v1.0.0"}, wantErr: true},
+		{name: "commit wins over branch", ref: map[string]any{"branch": "main", "commit": "abc"}, wantErr: true},
+		{name: "semver", ref: map[string]any{"semver": ">=1.0.0"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			spec := map[string]any{"url": "https://github.com/org/fleet"}
+			if tc.ref != nil {
+				spec["ref"] = tc.ref
+			}
+			branch, err := fluxBranch(newFluxObject(fluxGitRepositoryGVK, "flux-system", "fleet", spec))
+			if tc.wantErr {
+				var unsupported *unsupportedSourceError
+				g.Expect(errors.As(err, &unsupported)).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(branch).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestProviderFor_OCISourceWarns checks that an OCI source falls back to the
+// configured repository and tells the namespace owner why.
+func TestProviderFor_OCISourceWarns(t *testing.T) {
+	g := NewWithT(t)
+	ks := newFluxObject(fluxKustomizationGVK, "flux-system", "tenants", map[string]any{
+		"path":      "./",
+		"sourceRef": map[string]any{"kind": "OCIRepository", "name": "fleet"},
+	})
+	r := newSourceTestReconciler(ks)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	scoper := &scopingGitProvider{}
+	r.GitProvider = scoper
+
+	provider, err := r.providerFor(context.Background(), newArgoQuota(fluxQuotaLabels, nil), &corev1.Namespace{})
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeIdenticalTo(scoper))
+	g.Expect(scoper.scopedTo).To(BeNil())
+	g.Expect(recorder.Events).To(Receive(And(
+		ContainSubstring("ManifestSourceUnsupported"),
+		ContainSubstring("OCIRepository flux-system/fleet"))))
+}
@@ -34,6 +34,11 @@ var (
 		Help: "1 while the named gate blocks a pending shrink, 0 otherwise.",
 	}, []string{labelNamespace, labelQuota, labelGate})
 
+	quotaUnmapped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "resizer_quota_unmapped",
+		Help: "1 while no manifest for the quota has been found in the repository for longer than the escalation threshold.",
+	}, []string{labelNamespace, labelQuota})
+
 	decisionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "resizer_decision_total",
 		Help: "Decisions taken, by direction.",
@@ -47,11 +52,12 @@ var allGates = []sizing.Gate{
 	sizing.GateWindow,
 	sizing.GateRecentGrow,
 	sizing.GateCooldown,
+	sizing.GateSeasonal,
 }
 
 func init() {
 	metrics.Registry.MustRegister(
-		quotaTarget, quotaWasteRatio, shrinkBlockedBy, decisionTotal)
+		quotaTarget, quotaWasteRatio, shrinkBlockedBy, quotaUnmapped, decisionTotal)
 }
 
 // recordDecision publishes one evaluation. quotaTarget and quotaWasteRatio
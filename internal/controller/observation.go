@@ -6,6 +6,9 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/payback159/namespace-resizer/internal/lock"
 	"github.com/payback159/namespace-resizer/internal/sizing"
@@ -37,6 +40,10 @@ type Observer struct {
 	// serialises the (rare, cheap) case where they touch different keys.
 	mu     sync.Mutex
 	cached map[string]sizing.Window
+
+	// pods and ignore attribute samples; see WithAttribution.
+	pods   client.Reader
+	ignore labels.Selector
 }
 
 // NewObserver builds an Observer. now is injected so tests can drive the clock.
@@ -76,8 +83,12 @@ func (o *Observer) Observe(
 		window = sizing.DecodeWindow(state.Window)
 	}
 
+	sample, err := o.sample(ctx, quota)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "observing the quota without attribution")
+	}
 	now := o.now()
-	changed := window.Observe(now, string(quota.UID), quota.Status.Used, windowDays)
+	changed := window.ObserveSample(now, string(quota.UID), sample, windowDays)
 
 	if changed || o.heartbeatElapsed(window, now) {
 		window.LastWriteAt = now.UTC().Format(time.RFC3339)
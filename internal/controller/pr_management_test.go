@@ -155,7 +155,7 @@ func TestHandleNewProposal_RecordsDecisionDirectionOnCreate(t *testing.T) {
 	}
 
 	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: prTestQuota, Namespace: prTestNS}}
-	_, err := r.handleNewProposal(ctx, req, *quota, ns, policy, lock.State{}, decision)
+	_, err := r.handleNewProposal(ctx, req, fakeGit, *quota, ns, policy, lock.State{}, decision)
 	g.Expect(err).ToNot(HaveOccurred())
 
 	g.Expect(fakeGit.LastDirection).To(Equal(git.DirectionShrink), "CreatePR must receive the decision's direction")
@@ -381,3 +381,66 @@ func TestHandleActivePR_ClosedUnmergedGrow_ClearsLockWithoutStamping(t *testing.
 	g.Expect(state.LastShrink.IsZero()).To(BeTrue(), "closing a grow without merging must not stamp LastShrink")
 	g.Expect(state.LastGrow.IsZero()).To(BeTrue(), "closing a grow without merging must not stamp LastGrow")
 }
+
+// repoGitProvider is a FakeGitProvider bound to a repository, like a
+// GitHubProvider.
+type repoGitProvider struct {
+	FakeGitProvider
+	repository string
+}
+
+func (p *repoGitProvider) Repository() string { return p.repository }
+
+// TestHandleNewProposal_RecordsRepository verifies that the lock remembers
+// which repository the pull request was opened in.
+func TestHandleNewProposal_RecordsRepository(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	r, b := newPRTestReconciler(t)
+	r.GitProvider = &repoGitProvider{repository: "org/tenants"}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: prTestQuota, Namespace: prTestNS}}
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	state, err := b.locker.GetState(ctx, prTestNS, prTestQuota)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(1))
+	g.Expect(state.PRRepo).To(Equal("org/tenants"))
+}
+
+// TestHandleActivePR_RepositoryChanged verifies that a pull request recorded
+// for another repository is never looked up in the one the namespace is now
+// routed to: the same number there is an unrelated pull request.
+func TestHandleActivePR_RepositoryChanged(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	r, b := newPRTestReconciler(t)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	fakeGit := &repoGitProvider{
+		FakeGitProvider: FakeGitProvider{PRStatus: &git.PRStatus{IsOpen: true, Mergeable: true, MergeableState: "clean", ChecksState: "success"}},
+		repository:      "payments/k8s-config",
+	}
+	r.GitProvider = fakeGit
+	r.EnableAutoMerge = true
+	g.Expect(b.locker.MutateState(ctx, prTestNS, prTestQuota, func(s *lock.State) {
+		s.PRID = 7
+		s.PRDirection = git.DirectionGrow
+		s.PRRepo = "org/tenants"
+	})).To(Succeed())
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: prTestQuota, Namespace: prTestNS}}
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(fakeGit.MergedPRID).To(Equal(0), "PR #7 of the new repository must not be merged")
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("PRRepositoryChanged")))
+
+	state, err := b.locker.GetState(ctx, prTestNS, prTestQuota)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state.PRID).To(Equal(0))
+	g.Expect(state.PRRepo).To(BeEmpty())
+}
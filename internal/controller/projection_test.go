@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+)
+
+// scaledDeployment returns a Deployment of replicas pods requesting one CPU
+// each, none of which need exist yet.
+func scaledDeployment(replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "api",
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("1"),
+					}},
+				}},
+			}},
+		},
+	}
+}
+
+func TestProjection_ScaledDeploymentOpensAGrow(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	// 20 replicas of one CPU project past the hard limit of 16 before a
+	// single pod is rejected.
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, scaledDeployment(20))
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	limit := h.provider.LastLimits[corev1.ResourceRequestsCPU]
+	g.Expect(limit.String()).To(Equal("25"))
+}
+
+// explainingProvider is a FakeGitProvider that records the explanation its
+// proposals were given.
+type explainingProvider struct {
+	*FakeGitProvider
+	reason string
+}
+
+func (f *explainingProvider) WithReason(reason string, exclusions []string) git.Provider {
+	f.reason = reason
+	return f
+}
+
+func TestProjection_ReasonReachesThePR(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, scaledDeployment(20))
+	provider := &explainingProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = provider
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	g.Expect(provider.reason).To(ContainSubstring("(driven by projected demand)"))
+}
+
+func TestProjection_HPAMinimumCounts(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "api",
+			},
+			MinReplicas: ptr.To[int32](20),
+			MaxReplicas: 40,
+		},
+	}
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, scaledDeployment(2), hpa)
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	limit := h.provider.LastLimits[corev1.ResourceRequestsCPU]
+	g.Expect(limit.String()).To(Equal("25"))
+}
+
+func TestProjection_WithinTheLimitDoesNothing(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, scaledDeployment(12))
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0))
+}
+
+// TestProjection_OutOfScopeWorkloadIsIgnored checks that a quota scoped to a
+// priority class is not grown for workloads it does not count.
+func TestProjection_OutOfScopeWorkloadIsIgnored(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{}, scaledDeployment(20))
+	var quota corev1.ResourceQuota
+	g.Expect(h.reconciler.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "compute"}, &quota)).To(Succeed())
+	quota.Spec.ScopeSelector = &corev1.ScopeSelector{MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+		ScopeName: corev1.ResourceQuotaScopePriorityClass,
+		Operator:  corev1.ScopeSelectorOpIn,
+		Values:    []string{"batch"},
+	}}}
+	g.Expect(h.reconciler.Update(ctx, &quota)).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0))
+	g.Expect(h.reconciler.mapWorkloadToQuotas(ctx, scaledDeployment(20))).To(BeEmpty(),
+		"a workload outside the quota's scope does not enqueue it")
+}
+
+func TestMapWorkloadToQuotas(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{})
+
+	requests := h.reconciler.mapWorkloadToQuotas(ctx, scaledDeployment(1))
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].NamespacedName).To(Equal(client.ObjectKey{Namespace: "team-a", Name: "compute"}))
+
+	owned := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "api-5d8f", Namespace: "team-a",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "apps/v1", Kind: kindDeployment, Name: "api", UID: "uid-api",
+			Controller: ptr.To(true),
+		}},
+	}}
+	g.Expect(h.reconciler.mapWorkloadToQuotas(ctx, owned)).To(BeEmpty(),
+		"a Deployment's ReplicaSet is covered by the Deployment's own event")
+}
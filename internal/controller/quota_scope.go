@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// inQuotaScope reports whether pods of spec count against quota. A quota
+// without scopes counts every pod; a scoped one only the pods matching all of
+// spec.scopes and spec.scopeSelector, as the API server decides it. A scope
+// this function does not know matches nothing, so demand is never charged
+// to a quota that would not see it.
+func inQuotaScope(quota *corev1.ResourceQuota, spec *corev1.PodSpec) bool {
+	for _, scope := range quota.Spec.Scopes {
+		if !matchesScope(corev1.ScopedResourceSelectorRequirement{
+			ScopeName: scope,
+			Operator:  corev1.ScopeSelectorOpExists,
+		}, spec) {
+			return false
+		}
+	}
+	if quota.Spec.ScopeSelector != nil {
+		for _, req := range quota.Spec.ScopeSelector.MatchExpressions {
+			if !matchesScope(req, spec) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesScope evaluates one scope requirement against spec. Only
+// PriorityClass takes values; the other scopes are written with Exists.
+func matchesScope(req corev1.ScopedResourceSelectorRequirement, spec *corev1.PodSpec) bool {
+	switch req.ScopeName {
+	case corev1.ResourceQuotaScopeTerminating:
+		return isTerminating(spec)
+	case corev1.ResourceQuotaScopeNotTerminating:
+		return !isTerminating(spec)
+	case corev1.ResourceQuotaScopeBestEffort:
+		return isBestEffort(spec)
+	case corev1.ResourceQuotaScopeNotBestEffort:
+		return !isBestEffort(spec)
+	case corev1.ResourceQuotaScopeCrossNamespacePodAffinity:
+		return usesCrossNamespaceAffinity(spec)
+	case corev1.ResourceQuotaScopePriorityClass:
+		name := spec.PriorityClassName
+		switch req.Operator {
+		case corev1.ScopeSelectorOpIn:
+			return slices.Contains(req.Values, name)
+		case corev1.ScopeSelectorOpNotIn:
+			return !slices.Contains(req.Values, name)
+		case corev1.ScopeSelectorOpExists:
+			return name != ""
+		case corev1.ScopeSelectorOpDoesNotExist:
+			return name == ""
+		}
+	}
+	return false
+}
+
+// isTerminating mirrors the quota's Terminating scope: pods with an active
+// deadline.
+func isTerminating(spec *corev1.PodSpec) bool {
+	return spec.ActiveDeadlineSeconds != nil && *spec.ActiveDeadlineSeconds >= 0
+}
+
+// isBestEffort mirrors the BestEffort QoS class: no container requests or
+// limits CPU or memory.
+func isBestEffort(spec *corev1.PodSpec) bool {
+	containers := slices.Concat(spec.InitContainers, spec.Containers)
+	for _, c := range containers {
+		for _, res := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if _, ok := c.Resources.Requests[res]; ok {
+				return false
+			}
+			if _, ok := c.Resources.Limits[res]; ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// usesCrossNamespaceAffinity reports whether a pod (anti-)affinity term of
+// spec looks at other namespaces.
+func usesCrossNamespaceAffinity(spec *corev1.PodSpec) bool {
+	if spec.Affinity == nil {
+		return false
+	}
+	var terms []corev1.PodAffinityTerm
+	if a := spec.Affinity.PodAffinity; a != nil {
+		terms = append(terms, a.RequiredDuringSchedulingIgnoredDuringExecution...)
+		for _, w := range a.PreferredDuringSchedulingIgnoredDuringExecution {
+			terms = append(terms, w.PodAffinityTerm)
+		}
+	}
+	if a := spec.Affinity.PodAntiAffinity; a != nil {
+		terms = append(terms, a.RequiredDuringSchedulingIgnoredDuringExecution...)
+		for _, w := range a.PreferredDuringSchedulingIgnoredDuringExecution {
+			terms = append(terms, w.PodAffinityTerm)
+		}
+	}
+	for _, term := range terms {
+		if len(term.Namespaces) > 0 || term.NamespaceSelector != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestInQuotaScope(t *testing.T) {
+	burstable := corev1.PodSpec{Containers: []corev1.Container{{
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1"),
+		}},
+	}}}
+	bestEffort := corev1.PodSpec{Containers: []corev1.Container{{}}}
+	deadline := *burstable.DeepCopy()
+	deadline.ActiveDeadlineSeconds = ptr.To[int64](600)
+	batch := *burstable.DeepCopy()
+	batch.PriorityClassName = "batch"
+	crossNamespace := *burstable.DeepCopy()
+	crossNamespace.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+			TopologyKey:       "kubernetes.io/hostname",
+			NamespaceSelector: &metav1.LabelSelector{},
+		}},
+	}}
+	priority := func(op corev1.ScopeSelectorOperator, values ...string) *corev1.ScopeSelector {
+		return &corev1.ScopeSelector{MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+			ScopeName: corev1.ResourceQuotaScopePriorityClass, Operator: op, Values: values,
+		}}}
+	}
+
+	cases := []struct {
+		name     string
+		scopes   []corev1.ResourceQuotaScope
+		selector *corev1.ScopeSelector
+		spec     corev1.PodSpec
+		want     bool
+	}{
+		{name: "unscoped", spec: burstable, want: true},
+		{name: "best effort", scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			spec: bestEffort, want: true},
+		{name: "not best effort", scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			spec: burstable, want: false},
+		{name: "terminating", scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeTerminating},
+			spec: deadline, want: true},
+		{name: "all scopes must match", scopes: []corev1.ResourceQuotaScope{
+			corev1.ResourceQuotaScopeNotTerminating, corev1.ResourceQuotaScopeNotBestEffort,
+		}, spec: deadline, want: false},
+		{name: "priority class in", selector: priority(corev1.ScopeSelectorOpIn, "batch"),
+			spec: batch, want: true},
+		{name: "priority class not in", selector: priority(corev1.ScopeSelectorOpIn, "batch"),
+			spec: burstable, want: false},
+		{name: "priority class does not exist", selector: priority(corev1.ScopeSelectorOpDoesNotExist),
+			spec: burstable, want: true},
+		{name: "cross-namespace affinity",
+			scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeCrossNamespacePodAffinity},
+			spec:   crossNamespace, want: true},
+		{name: "unknown scope", scopes: []corev1.ResourceQuotaScope{"VolumeAttributesClass"},
+			spec: burstable, want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			quota := &corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{
+				Scopes: tc.scopes, ScopeSelector: tc.selector,
+			}}
+			g.Expect(inQuotaScope(quota, &tc.spec)).To(Equal(tc.want))
+		})
+	}
+}
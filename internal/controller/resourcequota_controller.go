@@ -23,19 +23,27 @@ import (
 	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	resizerConfig "github.com/payback159/namespace-resizer/internal/config"
 	"github.com/payback159/namespace-resizer/internal/git"
 	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/ownership"
+	"github.com/payback159/namespace-resizer/internal/routing"
 	"github.com/payback159/namespace-resizer/internal/sizing"
 )
 
@@ -49,6 +57,31 @@ type ResourceQuotaReconciler struct {
 	Observer        *Observer
 	BasePolicy      sizing.Policy
 	EnableAutoMerge bool
+	// ArgoCDNamespace is where Argo CD Applications are looked up when a
+	// quota's tracking metadata does not name a namespace. Empty means
+	// DefaultArgoCDNamespace.
+	ArgoCDNamespace string
+	// Router picks the repository and credentials per namespace. Nil sends
+	// every namespace to GitProvider.
+	Router *routing.Router
+	// Reviewers picks the reviewers and assignees of new PRs per namespace.
+	// Nil requests none.
+	Reviewers *ownership.Resolver
+	// BatchDebounce, when set, collects the proposals of all quotas of a
+	// namespace for this long and opens a single PR for them. Zero opens one
+	// PR per quota.
+	BatchDebounce time.Duration
+	// EnableShrinkDigest queues shrink proposals for the cluster-wide digest
+	// (see ShrinkDigest) instead of opening a PR per quota.
+	EnableShrinkDigest bool
+	// ShrinkDraftSoak, when set, opens shrink PRs as drafts and marks them
+	// ready for review once the recommendation held for this long.
+	ShrinkDraftSoak time.Duration
+	// UnmappedEscalateAfter is how long a quota's manifest may not be found
+	// before the quota is escalated; see handleUnmapped.
+	UnmappedEscalateAfter time.Duration
+	// ReportUnmapped also opens an issue about an escalated quota.
+	ReportUnmapped bool
 }
 
 // +kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch
@@ -57,8 +90,13 @@ type ResourceQuotaReconciler struct {
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets;daemonsets,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories,verbs=get
+// +kubebuilder:rbac:groups="",namespace=system,resources=configmaps;secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -77,8 +115,9 @@ func (r *ResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			// waiting for a controller restart. A stale cache miss here
 			// just costs one extra Lease read on the next reconcile.
 			r.Observer.Forget(req.Namespace, req.Name)
+			return ctrl.Result{}, r.forgetDeletedQuota(ctx, req.NamespacedName)
 		}
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return ctrl.Result{}, err
 	}
 
 	// 2. Fetch Namespace to check for annotations
@@ -110,7 +149,11 @@ func (r *ResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 	if !policy.Enabled {
 		logger.V(1).Info("Namespace is opted out", "namespace", req.Namespace)
-		return ctrl.Result{}, nil
+		state, err := r.Locker.GetState(ctx, req.Namespace, quota.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.withdrawUnmapped(ctx, &quota, &ns, state)
 	}
 
 	window, err := r.Observer.Observe(ctx, &quota, policy.WindowDays)
@@ -124,6 +167,12 @@ func (r *ResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		logger.Error(err, "failed to read lease state")
 		return ctrl.Result{}, err
 	}
+	if state.PRID == 0 && state.DigestLimits != "" {
+		if state, err = r.watchDigestRevert(ctx, &quota, policy, state); err != nil {
+			logger.Error(err, "failed to check for a reverted shrink")
+			return ctrl.Result{}, err
+		}
+	}
 
 	deficits, err := r.collectDeficits(ctx, quota, state.LastModified)
 	deficitScanFailed := err != nil
@@ -137,8 +186,24 @@ func (r *ResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		logger.Error(err, "failed to collect event deficits")
 	}
 
-	decision := sizing.Decide(sizing.Input{
-		Now:        time.Now(),
+	// A projection only adds a grow, so a failed one costs nothing but the
+	// head start: the shortage is still met once pods are rejected.
+	projected, err := r.projectedDemand(ctx, &quota)
+	if err != nil {
+		logger.Error(err, "failed to project the workloads' demand")
+	}
+	now := time.Now()
+	var cronRuns []sizing.CronRun
+	if policy.CronLookahead > 0 {
+		// Fails open like the projection above: the run's FailedCreate
+		// still triggers a grow, only later.
+		if cronRuns, err = r.scheduledRuns(ctx, &quota, now, policy.CronLookahead); err != nil {
+			logger.Error(err, "failed to project the CronJobs' scheduled runs")
+		}
+	}
+
+	input := sizing.Input{
+		Now:        now,
 		Hard:       quota.Status.Hard,
 		Used:       quota.Status.Used,
 		Deficits:   deficits,
@@ -146,38 +211,87 @@ func (r *ResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		Policy:     policy,
 		LastGrow:   state.LastGrow,
 		LastShrink: state.LastShrink,
-	})
+		Projected:  projected,
+		CronRuns:   cronRuns,
+	}
+	decision := sizing.Decide(input)
+	// The scale-out floor can only hold a shrink back, so the workloads are
+	// only listed when there is one to hold back.
+	scaleOutFailed := false
+	if policy.ScaleOutFloor && len(decision.ShrinkPreview) > 0 {
+		input.ScaleOut, err = r.scaleOutDemand(ctx, &quota)
+		if err != nil {
+			// Same asymmetry as the event scan: a missing floor can only
+			// lower the target, so the shrink is suppressed below.
+			logger.Error(err, "failed to compute the scale-out floor")
+			scaleOutFailed = true
+		} else {
+			decision = sizing.Decide(input)
+		}
+	}
 
 	recordDecision(req.Namespace, quota.Name, quota.Status.Hard, decision)
 	logger.V(1).Info("Sizing decision",
 		"direction", decision.Direction.String(),
 		"targets", decision.Targets,
 		"blockedBy", decision.BlockedBy)
+	if len(decision.Capped) > 0 {
+		// A cap hides a demand the limits do not meet, so it is surfaced
+		// on every reconcile; the recorder folds the repeats into a count.
+		msg := "Grow capped: " + strings.Join(decision.Capped, "; ")
+		logger.Info(msg, "shortageRemains", decision.ShortageRemains)
+		r.Recorder.Event(&quota, corev1.EventTypeWarning, "GrowCapped", msg)
+	}
 
-	if state.PRID != 0 {
-		return r.handleActivePR(ctx, req, quota, ns, policy, state, decision)
+	if state.PRID == 0 {
+		if decision.Direction == sizing.DirectionNone {
+			return r.dropPending(ctx, req, &quota, &ns, state)
+		}
+		if decision.Direction == sizing.DirectionShrink && deficitScanFailed {
+			logger.Info("Shrink suppressed: the event scan failed, so the " +
+				"target may be understated")
+			return r.dropPending(ctx, req, &quota, &ns, state)
+		}
+		if decision.Direction == sizing.DirectionShrink && scaleOutFailed {
+			logger.Info("Shrink suppressed: the workloads could not be listed, " +
+				"so the scale-out floor is unknown")
+			return r.dropPending(ctx, req, &quota, &ns, state)
+		}
 	}
 
-	if decision.Direction == sizing.DirectionShrink && deficitScanFailed {
-		logger.Info("Shrink suppressed: the event scan failed, so the " +
-			"target may be understated")
-		return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+	provider, err := r.providerFor(ctx, &quota, &ns)
+	if err != nil {
+		logger.Error(err, "failed to resolve the git provider")
+		return ctrl.Result{}, err
+	}
+	if explainer, ok := provider.(git.Explainer); ok {
+		provider = explainer.WithReason(decision.Reason, decision.Exclusions)
+	}
+	if state.PRID == 0 {
+		provider = r.withReviewers(ctx, provider, &quota, &ns, decision.Direction.String())
+		if r.opensDraft(provider, decision.Direction) {
+			provider = provider.(git.DraftProposer).AsDraft()
+		}
 	}
 
-	if decision.Direction != sizing.DirectionNone {
-		return r.handleNewProposal(ctx, req, quota, ns, policy, state, decision)
+	if state.PRID != 0 {
+		return r.handleActivePR(ctx, req, provider, quota, ns, policy, state, decision)
 	}
 
-	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+	return r.handleNewProposal(ctx, req, provider, quota, ns, policy, state, decision)
 }
 
 // handleActivePR manages the lifecycle of an existing Pull Request
-func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.Request, quota corev1.ResourceQuota, ns corev1.Namespace, policy sizing.Policy, state lock.State, decision sizing.Decision) (ctrl.Result, error) {
+func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.Request, provider git.Provider, quota corev1.ResourceQuota, ns corev1.Namespace, policy sizing.Policy, state lock.State, decision sizing.Decision) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	prID := state.PRID
 	logger.Info("Lock found, checking PR status", "prID", prID)
 
-	status, err := r.GitProvider.GetPRStatus(ctx, prID)
+	if repo := git.RepositoryOf(provider); state.PRRepo != "" && repo != "" && repo != state.PRRepo {
+		return r.releaseMovedPR(ctx, req, quota, state, repo)
+	}
+
+	status, err := provider.GetPRStatus(ctx, prID)
 	if err != nil {
 		logger.Error(err, "failed to get PR status")
 		return ctrl.Result{}, err
@@ -188,14 +302,14 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 		logger.Info("PR is closed/merged, releasing lock", "prID", prID)
 
 		now := time.Now()
-		err := r.Locker.MutateState(ctx, req.Namespace, quota.Name, func(s *lock.State) {
+		err := r.mutatePRHolders(ctx, req.Namespace, quota.Name, state, func(s *lock.State) {
 			// Same caveat as the auto-merge gate below: this only knows what
 			// the lease recorded, so a merged PR whose direction was never
 			// persisted there is stamped as a grow.
 			wasShrink := s.PRDirection == git.DirectionShrink
-			s.PRID = 0
-			s.PRDirection = ""
+			s.ReleasePR()
 			if !status.IsMerged {
+				s.ForgetDigest()
 				// A closed shrink is a rejection. Without the cooldown stamp
 				// the requeue below would recompute the same shrink and
 				// reopen it immediately. A closed grow needs no stamp: the
@@ -221,20 +335,30 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Nothing merges an issue; the quota carrying its limits is what closes it.
+	resolved, err := r.resolveAppliedIssue(ctx, req, provider, quota, state)
+	if err != nil {
+		logger.Error(err, "failed to resolve applied issue")
+		return ctrl.Result{}, err
+	}
+	if resolved {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	if state.PRDirection == git.DirectionShrink {
-		if reason, expire := shrinkPRShouldClose(policy, status, decision); expire {
+		if reason, expire := shrinkPRShouldClose(policy, shrinkTTLStart(state, status), decision); expire {
 			logger.Info("Closing shrink PR", "prID", state.PRID, "reason", reason)
-			if err := r.GitProvider.ClosePR(ctx, state.PRID, reason); err != nil {
+			if err := provider.ClosePR(ctx, state.PRID, reason); err != nil {
 				logger.Error(err, "failed to close shrink PR", "prID", state.PRID)
 				return ctrl.Result{}, err
 			}
 			// Recording the shrink timestamp is what stops the very next
 			// reconcile from opening the same PR again.
 			now := time.Now()
-			err := r.Locker.MutateState(ctx, req.Namespace, quota.Name,
+			err := r.mutatePRHolders(ctx, req.Namespace, quota.Name, state,
 				func(s *lock.State) {
-					s.PRID = 0
-					s.PRDirection = ""
+					s.ReleasePR()
+					s.ForgetDigest()
 					s.LastShrink = now
 				})
 			if err != nil {
@@ -243,6 +367,56 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 			}
 			return ctrl.Result{Requeue: true}, nil
 		}
+		if state.PRDraft {
+			result, err := r.soakDraft(ctx, req, provider, quota, state, status, decision)
+			if err != nil {
+				logger.Error(err, "failed to soak draft shrink PR", "prID", state.PRID)
+			}
+			return result, err
+		}
+	}
+
+	if state.PRDirection != git.DirectionShrink {
+		// GrowIdleSince records when decisions stopped asking for this PR. It
+		// is only written when that changes, not on every reconcile. A batch
+		// is not tracked: one quota no longer needing it says nothing about
+		// the others, so only the TTL closes it.
+		now := time.Now()
+		idleSince := state.GrowIdleSince
+		switch {
+		case state.PRBatch:
+		case decision.Direction == sizing.DirectionNone && idleSince.IsZero():
+			idleSince = now
+		case decision.Direction != sizing.DirectionNone && !idleSince.IsZero():
+			idleSince = time.Time{}
+		}
+		if !idleSince.Equal(state.GrowIdleSince) {
+			err := r.Locker.MutateState(ctx, req.Namespace, quota.Name, func(s *lock.State) {
+				s.GrowIdleSince = idleSince
+			})
+			if err != nil {
+				logger.Error(err, "failed to record grow PR idle time")
+				return ctrl.Result{}, err
+			}
+		}
+
+		if reason, expire := growPRShouldClose(policy, status, decision, idleSince, quota, now); expire {
+			logger.Info("Closing grow PR", "prID", prID, "reason", reason)
+			if err := provider.ClosePR(ctx, prID, reason); err != nil {
+				logger.Error(err, "failed to close grow PR", "prID", prID)
+				return ctrl.Result{}, err
+			}
+			// No timestamp is recorded: a shortage that comes back has to be
+			// able to open a new PR straight away.
+			err := r.mutatePRHolders(ctx, req.Namespace, quota.Name, state, func(s *lock.State) {
+				s.ReleasePR()
+			})
+			if err != nil {
+				logger.Error(err, "failed to release lock after closing grow PR")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
 	}
 
 	// PR is open -> Check Auto-Merge
@@ -252,10 +426,16 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 	// decision, not the controller's. This gate only knows what the lease
 	// recorded, though: it trusts state.PRDirection, so a PR whose direction
 	// was never persisted there would pass through as a grow.
-	shouldAutoMerge := r.EnableAutoMerge && state.PRDirection != git.DirectionShrink
+	shouldAutoMerge := r.EnableAutoMerge && state.PRDirection != git.DirectionShrink && !isIssueMode(provider)
 	if val, ok := ns.Annotations[resizerConfig.AnnotationAutoMerge]; ok && val == "false" {
 		shouldAutoMerge = false
 	}
+	// A capped grow that still leaves rejected pods without room is not the
+	// fix it looks like; someone has to decide about the cap.
+	if shouldAutoMerge && decision.ShortageRemains {
+		logger.Info("Auto-merge skipped: the capped grow leaves a pending shortage", "prID", prID)
+		shouldAutoMerge = false
+	}
 
 	if shouldAutoMerge {
 		if strings.ToLower(status.MergeableState) == "unknown" {
@@ -269,7 +449,7 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 
 		if canAttemptMerge {
 			logger.Info("Auto-merging PR", "prID", prID, "state", status.MergeableState, "checks", status.ChecksState, "checksCount", status.ChecksTotalCount)
-			if err := r.GitProvider.MergePR(ctx, prID, "squash"); err != nil {
+			if err := provider.MergePR(ctx, prID, "squash"); err != nil {
 				logger.Error(err, "failed to auto-merge PR")
 			} else {
 				// The merge succeeded, so we release the lock and record the
@@ -278,10 +458,9 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 				// open for a short window, causing the controller to attempt a
 				// second merge on the next reconcile.
 				now := time.Now()
-				err := r.Locker.MutateState(ctx, req.Namespace, quota.Name,
+				err := r.mutatePRHolders(ctx, req.Namespace, quota.Name, state,
 					func(s *lock.State) {
-						s.PRID = 0
-						s.PRDirection = ""
+						s.ReleasePR()
 						s.LastModified = now
 						s.LastGrow = now
 					})
@@ -311,14 +490,21 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 	switch decision.Direction {
 	case sizing.DirectionGrow:
 		logger.Info("PR is open, updating if needed", "prID", prID)
-		if err := r.GitProvider.UpdatePR(ctx, prID, quota.Name, req.Namespace, ns.Annotations, decision.Targets); err != nil {
+		if err := provider.UpdatePR(ctx, prID, quota.Name, req.Namespace, ns.Annotations, decision.Targets); err != nil {
 			if errors.Is(err, git.ErrFileNotFound) {
-				logger.Info("Quota file not found in Git repository during update. Retrying later.", "error", err.Error())
-				return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
+				return r.handleUnmapped(ctx, provider, req.NamespacedName, err)
 			}
 			logger.Error(err, "failed to update PR")
 			return ctrl.Result{}, err
 		}
+		if err := r.forgetUnmapped(ctx, provider, req.Namespace, quota.Name, state); err != nil {
+			logger.Error(err, "failed to forget the unmapped quota")
+			return ctrl.Result{}, err
+		}
+		if err := r.recordIssueLimits(ctx, req, provider, state, decision.Targets); err != nil {
+			logger.Error(err, "failed to record the issue's recommended limits")
+			return ctrl.Result{}, err
+		}
 	case sizing.DirectionShrink:
 		logger.Info("Shrink PR is still open and awaiting review", "prID", prID)
 	default:
@@ -331,18 +517,18 @@ func (r *ResourceQuotaReconciler) handleActivePR(ctx context.Context, req ctrl.R
 // shrinkPRShouldClose reports whether an open shrink pull request has to be
 // abandoned, and why. Growth supersedes it because a shortage is a live
 // outage; the TTL catches the case where nobody reviewed it and the lock would
-// otherwise be held indefinitely.
+// otherwise be held indefinitely. It counts from since (see shrinkTTLStart);
+// a zero since has no TTL.
 func shrinkPRShouldClose(
 	policy sizing.Policy,
-	status *git.PRStatus,
+	since time.Time,
 	decision sizing.Decision,
 ) (string, bool) {
 	if decision.Direction == sizing.DirectionGrow {
 		return "Superseded: a shortage was detected and the quota has to grow " +
 			"instead.\n\n" + decision.Reason, true
 	}
-	if !status.CreatedAt.IsZero() &&
-		time.Since(status.CreatedAt) > policy.ShrinkPRTTL {
+	if !since.IsZero() && time.Since(since) > policy.ShrinkPRTTL {
 		return "Closing automatically: this shrink proposal has been open for " +
 			formatDays(policy.ShrinkPRTTL) + " without review. A fresh " +
 			"proposal will be opened once the cooldown expires.", true
@@ -350,6 +536,33 @@ func shrinkPRShouldClose(
 	return "", false
 }
 
+// growPRShouldClose reports whether an open grow pull request has to be
+// abandoned, and why. The TTL bounds how long an unmerged PR can hold the
+// lock; the obsolete check closes it earlier once the demand behind it has
+// gone and usage fits the current limits with headroom again.
+func growPRShouldClose(
+	policy sizing.Policy,
+	status *git.PRStatus,
+	decision sizing.Decision,
+	idleSince time.Time,
+	quota corev1.ResourceQuota,
+	now time.Time,
+) (string, bool) {
+	if !status.CreatedAt.IsZero() && now.Sub(status.CreatedAt) > policy.GrowPRTTL {
+		return "Closing automatically: this grow proposal has been open for " +
+			formatDays(policy.GrowPRTTL) + " without being merged. A fresh " +
+			"proposal will be opened if the quota still needs to grow.", true
+	}
+	if decision.Direction == sizing.DirectionNone && !idleSince.IsZero() &&
+		now.Sub(idleSince) >= policy.GrowPRObsoleteAfter &&
+		sizing.FitsWithHeadroom(quota.Status.Hard, quota.Status.Used, policy) {
+		return fmt.Sprintf("Closing automatically: no resize has been needed for %s and "+
+			"current usage fits the existing limits with headroom. A new proposal "+
+			"will be opened if demand returns.", formatHours(policy.GrowPRObsoleteAfter)), true
+	}
+	return "", false
+}
+
 // formatDays renders a duration as whole days for a human-facing comment.
 // time.Duration.String() would render policy.ShrinkPRTTL's default as
 // "168h0m0s", which nobody reviewing a pull request wants to do arithmetic
@@ -363,10 +576,51 @@ func formatDays(d time.Duration) string {
 	return fmt.Sprintf("%d days", days)
 }
 
+// formatHours is formatDays for durations configured in hours, such as
+// policy.GrowPRObsoleteAfter: whole days stay days, anything else is given
+// in whole hours.
+func formatHours(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return formatDays(d)
+	}
+	hours := int(d / time.Hour)
+	if hours == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
+}
+
+// releaseMovedPR frees the lock of a pull request opened in a repository the
+// quota is no longer routed to. The controller may hold no credentials for
+// the old repository, and the same number in the new one is an unrelated pull
+// request, so the old one is left to its owners and a new proposal is made
+// where the quota now lives.
+func (r *ResourceQuotaReconciler) releaseMovedPR(
+	ctx context.Context,
+	req ctrl.Request,
+	quota corev1.ResourceQuota,
+	state lock.State,
+	repo string,
+) (ctrl.Result, error) {
+	msg := fmt.Sprintf("Pull request #%d was opened in %s, but the quota is now routed to %s; "+
+		"releasing the lock, close the old pull request by hand", state.PRID, state.PRRepo, repo)
+	log.FromContext(ctx).Info(msg)
+	r.Recorder.Event(&quota, corev1.EventTypeWarning, "PRRepositoryChanged", msg)
+
+	err := r.Locker.MutateState(ctx, req.Namespace, quota.Name, func(s *lock.State) {
+		s.ReleasePR()
+	})
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to release lock of moved PR: %w", err)
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
 // handleNewProposal manages the creation of new Pull Requests
 func (r *ResourceQuotaReconciler) handleNewProposal(
 	ctx context.Context,
 	req ctrl.Request,
+	provider git.Provider,
 	quota corev1.ResourceQuota,
 	ns corev1.Namespace,
 	policy sizing.Policy,
@@ -376,22 +630,73 @@ func (r *ResourceQuotaReconciler) handleNewProposal(
 	logger := log.FromContext(ctx)
 	recommendations := decision.Targets
 
+	// In issue mode the lock also records what the issue recommends, which
+	// is how the controller knows when to resolve it. An adopted issue's
+	// limits are not known; until a grow rewrites it, the current
+	// recommendation is what it most likely carries. A draft records them
+	// too, for the soak to compare against.
+	issues := isIssueMode(provider)
+	draft := r.opensDraft(provider, decision.Direction)
+	var proposedLimits string
+	if issues || draft {
+		encoded, err := encodeLimits(recommendations)
+		if err != nil {
+			logger.Error(err, "failed to encode the recommended limits")
+			return ctrl.Result{}, err
+		}
+		proposedLimits = encoded
+	}
+
 	// 0. Recover orphaned PRs before creating a new one.
 	// A PR may have been created in a previous reconcile where the subsequent
 	// AcquireLock failed (transient API error, optimistic-concurrency conflict,
 	// or a controller restart between CreatePR and AcquireLock). That leaves an
 	// open PR with no lock recorded. Without this check the controller would open
 	// a brand-new duplicate PR on every reconcile.
-	existingPRID, existingDirection, err := r.GitProvider.FindOpenPR(ctx, req.Namespace, quota.Name)
+	existingPRID, existingDirection, err := provider.FindOpenPR(ctx, req.Namespace, quota.Name, ns.Annotations)
 	if err != nil {
 		logger.Error(err, "failed to check for existing open PR")
 		return ctrl.Result{}, err
 	}
 	if existingPRID != 0 {
 		logger.Info("Found existing open PR without lock; adopting it instead of creating a duplicate", "prID", existingPRID, "direction", existingDirection)
+		// A batch already held by a sibling quota is shared with it, and so
+		// is a digest held by any other quota of the cluster.
+		held := lock.State{PRID: existingPRID, PRRepo: git.RepositoryOf(provider)}
+		batch, digest := false, false
+		if r.BatchDebounce > 0 && !issues {
+			states, err := r.Locker.ListStates(ctx, req.Namespace)
+			if err != nil {
+				logger.Error(err, "failed to list the namespace's quotas")
+				return ctrl.Result{}, err
+			}
+			batch = len(sharingPR(states, held, quota.Name)) > 0
+		}
+		if r.EnableShrinkDigest && !issues && existingDirection == git.DirectionShrink {
+			states, err := r.Locker.ListAllStates(ctx)
+			if err != nil {
+				logger.Error(err, "failed to list the cluster's quotas")
+				return ctrl.Result{}, err
+			}
+			for key, other := range states {
+				if other.PRDigest && other.PRID == held.PRID && other.PRRepo == held.PRRepo &&
+					key != req.NamespacedName {
+					digest, batch = true, false
+				}
+			}
+		}
 		err = r.Locker.MutateState(ctx, req.Namespace, quota.Name, func(s *lock.State) {
 			s.PRID = existingPRID
 			s.PRDirection = existingDirection
+			s.PRRepo = git.RepositoryOf(provider)
+			s.PRBatch = batch
+			s.PRDigest = digest
+			s.PRLimits = proposedLimits
+			if digest {
+				s.DigestLimits = s.PendingLimits
+			}
+			s.ClearPending()
+			s.CreateKey = ""
 		})
 		if err != nil {
 			logger.Error(err, "failed to acquire lock for existing PR")
@@ -433,29 +738,31 @@ func (r *ResourceQuotaReconciler) handleNewProposal(
 		}
 	}
 
-	// 3. Create PR
-	// Log recommendation. A shrink is an optimisation, not a shortage: it
-	// gets the opposite verb and a Normal event instead of a Warning.
-	verb, eventType := "Increase", corev1.EventTypeWarning
-	if decision.Direction == sizing.DirectionShrink {
-		verb, eventType = "Decrease", corev1.EventTypeNormal
+	if r.EnableShrinkDigest && !issues && decision.Direction == sizing.DirectionShrink {
+		return r.queueForDigest(ctx, req, state, decision)
 	}
-	for res, newLimit := range recommendations {
-		currentLimit := quota.Status.Hard[res]
-		msg := fmt.Sprintf("Recommendation: %s %s from %s to %s",
-			verb, res, currentLimit.String(), newLimit.String())
-		logger.Info(msg)
-		r.Recorder.Event(&quota, eventType, "QuotaResizeRecommended", msg)
+	if r.BatchDebounce > 0 && !issues {
+		return r.proposeBatch(ctx, req, provider, quota, ns, state, decision)
+	}
+
+	// 3. Create PR
+	r.recordRecommendations(ctx, &quota, decision.Direction, recommendations)
+
+	provider, err = r.withCreateKey(ctx, provider, req.Namespace, decision.Direction.String(),
+		[]git.QuotaChange{{Quota: quota.Name, Limits: recommendations}},
+		map[string]lock.State{quota.Name: state})
+	if err != nil {
+		logger.Error(err, "failed to record the create key")
+		return ctrl.Result{}, err
 	}
 
 	logger.Info("No lock found, creating PR")
-	newPRID, err := r.GitProvider.CreatePR(
+	newPRID, err := provider.CreatePR(
 		ctx, quota.Name, req.Namespace, decision.Direction.String(),
 		ns.Annotations, recommendations)
 	if err != nil {
 		if errors.Is(err, git.ErrFileNotFound) {
-			logger.Info("Quota file not found in Git repository. Retrying later.", "error", err.Error())
-			return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
+			return r.handleUnmapped(ctx, provider, req.NamespacedName, err)
 		}
 		logger.Error(err, "failed to create PR")
 		return ctrl.Result{}, err
@@ -465,6 +772,11 @@ func (r *ResourceQuotaReconciler) handleNewProposal(
 	err = r.Locker.MutateState(ctx, req.Namespace, quota.Name, func(s *lock.State) {
 		s.PRID = newPRID
 		s.PRDirection = decision.Direction.String()
+		s.PRRepo = git.RepositoryOf(provider)
+		s.ClearPending()
+		s.CreateKey = ""
+		s.PRLimits = proposedLimits
+		s.PRDraft = draft
 		if decision.Direction == sizing.DirectionShrink {
 			s.LastShrink = time.Now()
 		} else {
@@ -475,10 +787,37 @@ func (r *ResourceQuotaReconciler) handleNewProposal(
 		logger.Error(err, "failed to record the new pull request")
 		return ctrl.Result{}, err
 	}
+	if err := r.forgetUnmapped(ctx, provider, req.Namespace, quota.Name, state); err != nil {
+		logger.Error(err, "failed to forget the unmapped quota")
+		return ctrl.Result{}, err
+	}
 
 	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
 }
 
+// recordRecommendations logs and records an event for every limit a pull
+// request is about to change. A shrink is an optimisation, not a shortage: it
+// gets the opposite verb and a Normal event instead of a Warning.
+func (r *ResourceQuotaReconciler) recordRecommendations(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+	direction sizing.Direction,
+	recommendations map[corev1.ResourceName]resource.Quantity,
+) {
+	logger := log.FromContext(ctx)
+	verb, eventType := "Increase", corev1.EventTypeWarning
+	if direction == sizing.DirectionShrink {
+		verb, eventType = "Decrease", corev1.EventTypeNormal
+	}
+	for res, newLimit := range recommendations {
+		currentLimit := quota.Status.Hard[res]
+		msg := fmt.Sprintf("Recommendation: %s %s from %s to %s",
+			verb, res, currentLimit.String(), newLimit.String())
+		logger.Info(msg)
+		r.Recorder.Event(quota, eventType, "QuotaResizeRecommended", msg)
+	}
+}
+
 // collectDeficits scans recent FailedCreate events and returns, per quota key,
 // the additional milli-value that the blocked workloads asked for. Events older
 // than the last successful change are skipped so a single shortage cannot be
@@ -584,5 +923,17 @@ func (r *ResourceQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&corev1.ResourceQuota{}).
 		Named("resourcequota").
 		Watches(&corev1.Event{}, handler.EnqueueRequestsFromMapFunc(r.mapEventToQuota)).
+		// Status updates do not change what a workload projects; only spec
+		// changes, creations and deletions bump the generation or pass.
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(r.mapWorkloadToQuotas),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.StatefulSet{}, handler.EnqueueRequestsFromMapFunc(r.mapWorkloadToQuotas),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.ReplicaSet{}, handler.EnqueueRequestsFromMapFunc(r.mapWorkloadToQuotas),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&autoscalingv2.HorizontalPodAutoscaler{}, handler.EnqueueRequestsFromMapFunc(r.mapWorkloadToQuotas),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&batchv1.CronJob{}, handler.EnqueueRequestsFromMapFunc(r.mapWorkloadToQuotas),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
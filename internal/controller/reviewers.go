@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+)
+
+// withReviewers returns provider set up to request the reviewers the rules
+// give the namespace for direction. Unreadable rules leave the pull request
+// without reviewers rather than holding it back: a shortage still needs its
+// grow.
+func (r *ResourceQuotaReconciler) withReviewers(
+	ctx context.Context,
+	provider git.Provider,
+	quota *corev1.ResourceQuota,
+	ns *corev1.Namespace,
+	direction string,
+) git.Provider {
+	assigner, ok := provider.(git.ReviewerAssigner)
+	if r.Reviewers == nil || !ok {
+		return provider
+	}
+	reviewers, err := r.Reviewers.ReviewersFor(ctx, ns, direction)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to resolve reviewers")
+		r.Recorder.Event(quota, corev1.EventTypeWarning, "ReviewersUnresolved", err.Error())
+		return provider
+	}
+	if reviewers.IsZero() {
+		return provider
+	}
+	return assigner.WithReviewers(reviewers)
+}
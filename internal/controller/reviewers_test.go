@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+	"github.com/payback159/namespace-resizer/internal/ownership"
+)
+
+// reviewingProvider is a FakeGitProvider that records the reviewers it was
+// given.
+type reviewingProvider struct {
+	*FakeGitProvider
+	reviewers git.Reviewers
+}
+
+func (f *reviewingProvider) WithReviewers(reviewers git.Reviewers) git.Provider {
+	f.reviewers = reviewers
+	return f
+}
+
+func TestReviewers_ShrinkUsesTheShrinkSet(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	rules := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviewers", Namespace: lock.ControllerNamespace},
+		Data: map[string]string{ownership.RulesKey: `
+rules:
+- name: everyone
+  reviewers: {teams: [platform]}
+  shrink: {teams: ["{{ .Namespace }}-leads"]}
+`},
+	}
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true}, rules)
+	provider := &reviewingProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = provider
+	h.reconciler.Reviewers = ownership.NewResolver(h.reconciler.Client, "reviewers")
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	g.Expect(provider.reviewers.Teams).To(Equal([]string{"team-a-leads"}))
+}
+
+func TestReviewers_InvalidRulesDoNotBlockThePR(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	rules := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviewers", Namespace: lock.ControllerNamespace},
+		Data:       map[string]string{ownership.RulesKey: "rules: [{reviewers: {}}]"},
+	}
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true}, rules)
+	provider := &reviewingProvider{FakeGitProvider: h.provider}
+	h.reconciler.GitProvider = provider
+	h.reconciler.Reviewers = ownership.NewResolver(h.reconciler.Client, "reviewers")
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	g.Expect(provider.reviewers.IsZero()).To(BeTrue())
+	g.Expect(h.events()).To(ContainElement(ContainSubstring("ReviewersUnresolved")))
+}
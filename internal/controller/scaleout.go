@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/payback159/namespace-resizer/internal/sizing"
+)
+
+const kindDeployment = "Deployment"
+
+// replicaScale picks how many replicas of a workload a demand counts, given
+// the HorizontalPodAutoscaler targeting it, if any, and its spec.replicas.
+type replicaScale func(hpa *autoscalingv2.HorizontalPodAutoscaler, replicas *int32) int64
+
+// atMaxScale counts a workload at the maxReplicas of its HPA, or at
+// spec.replicas without one.
+func atMaxScale(hpa *autoscalingv2.HorizontalPodAutoscaler, replicas *int32) int64 {
+	if hpa != nil {
+		return int64(hpa.Spec.MaxReplicas)
+	}
+	return desiredReplicas(replicas)
+}
+
+// atDesiredScale counts a workload at spec.replicas, raised to the
+// minReplicas of its HPA: the HPA scales it there as soon as it notices.
+func atDesiredScale(hpa *autoscalingv2.HorizontalPodAutoscaler, replicas *int32) int64 {
+	desired := desiredReplicas(replicas)
+	if hpa != nil {
+		desired = max(desired, desiredReplicas(hpa.Spec.MinReplicas))
+	}
+	return desired
+}
+
+// desiredReplicas applies the API default of one replica.
+func desiredReplicas(replicas *int32) int64 {
+	if replicas == nil {
+		return 1
+	}
+	return int64(*replicas)
+}
+
+// scaleOutDemand returns what the workloads counted by quota need at once
+// when each runs at its maximum scale.
+func (r *ResourceQuotaReconciler) scaleOutDemand(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+) (map[corev1.ResourceName]int64, error) {
+	return r.workloadDemand(ctx, quota, atMaxScale)
+}
+
+// projectedDemand returns what the workloads counted by quota need once
+// every one of them runs at its desired scale, whether or not its pods exist
+// yet.
+func (r *ResourceQuotaReconciler) projectedDemand(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+) (map[corev1.ResourceName]int64, error) {
+	return r.workloadDemand(ctx, quota, atDesiredScale)
+}
+
+// workloadDemand sums sizing.WorkloadDemand over the Deployments,
+// StatefulSets and standalone ReplicaSets of the quota's namespace whose pods
+// are in the quota's scope, each counted at the number of replicas scale
+// picks. ReplicaSets owned by a Deployment are counted through it.
+func (r *ResourceQuotaReconciler) workloadDemand(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+	scale replicaScale,
+) (map[corev1.ResourceName]int64, error) {
+	namespace := quota.Namespace
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpas, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list HorizontalPodAutoscalers: %w", err)
+	}
+	byTarget := map[string]*autoscalingv2.HorizontalPodAutoscaler{}
+	for i := range hpas.Items {
+		target := hpas.Items[i].Spec.ScaleTargetRef
+		byTarget[target.Kind+"/"+target.Name] = &hpas.Items[i]
+	}
+
+	demand := map[corev1.ResourceName]int64{}
+	add := func(kind, name string, replicas *int32, spec corev1.PodSpec, claims []corev1.PersistentVolumeClaim) {
+		if !inQuotaScope(quota, &spec) {
+			return
+		}
+		n := scale(byTarget[kind+"/"+name], replicas)
+		for res, milli := range sizing.WorkloadDemand(spec, claims, n) {
+			demand[res] += milli
+		}
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		add(kindDeployment, d.Name, d.Spec.Replicas, d.Spec.Template.Spec, nil)
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := r.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list StatefulSets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		add(kindStatefulSet, s.Name, s.Spec.Replicas, s.Spec.Template.Spec, s.Spec.VolumeClaimTemplates)
+	}
+
+	var replicaSets appsv1.ReplicaSetList
+	if err := r.List(ctx, &replicaSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ReplicaSets: %w", err)
+	}
+	for _, rs := range replicaSets.Items {
+		if metav1.GetControllerOf(&rs) != nil {
+			continue
+		}
+		add(kindReplicaSet, rs.Name, rs.Spec.Replicas, rs.Spec.Template.Spec, nil)
+	}
+	return demand, nil
+}
+
+// mapWorkloadToQuotas enqueues the quotas of the namespace a workload or HPA
+// changed in, so a spec change that projects past a limit is acted on before
+// its pods are rejected. A workload only enqueues the quotas whose scope its
+// pods are in; an HPA, which carries no pod template, enqueues them all.
+func (r *ResourceQuotaReconciler) mapWorkloadToQuotas(ctx context.Context, obj client.Object) []reconcile.Request {
+	var template *corev1.PodSpec
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		template = &w.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		template = &w.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		// A Deployment's ReplicaSets change along with it; the
+		// Deployment's own event is enough.
+		if metav1.GetControllerOf(w) != nil {
+			return nil
+		}
+		template = &w.Spec.Template.Spec
+	case *batchv1.CronJob:
+		template = &w.Spec.JobTemplate.Spec.Template.Spec
+	}
+	var quotas corev1.ResourceQuotaList
+	if err := r.List(ctx, &quotas, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(quotas.Items))
+	for _, quota := range quotas.Items {
+		if template != nil && !inQuotaScope(&quota, template) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{
+			Name:      quota.Name,
+			Namespace: quota.Namespace,
+		}})
+	}
+	return requests
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+)
+
+// scaleOutObjects returns a Deployment of two replicas requesting cpu each,
+// and an HPA that may scale it to maxReplicas.
+func scaleOutObjects(cpu string, maxReplicas int32) []client.Object {
+	replicas := int32(2)
+	return []client.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: "api",
+						Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse(cpu),
+						}},
+					}},
+				}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+					APIVersion: "apps/v1", Kind: "Deployment", Name: "api",
+				},
+				MaxReplicas: maxReplicas,
+			},
+		},
+	}
+}
+
+func TestScaleOut_FloorHoldsAShrinkBack(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	// 28 replicas of 500m need 14 of the 16 CPU, inside the tolerance band.
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{window: true}, scaleOutObjects("500m", 28)...)
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(0))
+}
+
+func TestScaleOut_FloorSetsTheShrinkTarget(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	// 26 replicas of 500m need 13, below the band but above the step cap of 12.
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{window: true}, scaleOutObjects("500m", 26)...)
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	limit := h.provider.LastLimits[corev1.ResourceRequestsCPU]
+	g.Expect(limit.String()).To(Equal("13"))
+}
+
+func TestScaleOut_NamespaceOptOut(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, &git.PRStatus{}, shrinkHarnessOpts{window: true}, scaleOutObjects("500m", 28)...)
+	var ns corev1.Namespace
+	g.Expect(h.reconciler.Get(ctx, types.NamespacedName{Name: "team-a"}, &ns)).To(Succeed())
+	ns.Annotations = map[string]string{"resizer.io/scale-out-floor": "false"}
+	g.Expect(h.reconciler.Update(ctx, &ns)).To(Succeed())
+
+	g.Expect(h.reconcile(ctx)).To(Succeed())
+
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	limit := h.provider.LastLimits[corev1.ResourceRequestsCPU]
+	g.Expect(limit.String()).To(Equal("12"))
+}
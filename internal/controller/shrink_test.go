@@ -11,6 +11,8 @@ import (
 	"github.com/payback159/namespace-resizer/internal/lock"
 	"github.com/payback159/namespace-resizer/internal/sizing"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -88,6 +90,8 @@ func newShrinkHarness(
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
 	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
 	_ = coordinationv1.AddToScheme(scheme)
 
 	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
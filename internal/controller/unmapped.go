@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// The retry interval of an unmapped quota grows with how long it has been
+// unmapped, between these bounds.
+const (
+	unmappedMinBackoff = time.Minute
+	unmappedMaxBackoff = time.Hour
+)
+
+// handleUnmapped is the result of a reconcile that found no manifest for the
+// quota key in the repository (err matches git.ErrFileNotFound). The Lease
+// records since when; past UnmappedEscalateAfter the quota gets a Warning
+// event, the resizer_quota_unmapped metric and, with ReportUnmapped, a
+// tracking issue. Each retry waits as long as the quota has been unmapped,
+// which doubles the interval every time.
+func (r *ResourceQuotaReconciler) handleUnmapped(
+	ctx context.Context,
+	provider git.Provider,
+	key types.NamespacedName,
+	err error,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	state, stateErr := r.Locker.GetState(ctx, key.Namespace, key.Name)
+	if stateErr != nil {
+		return ctrl.Result{}, stateErr
+	}
+
+	now := time.Now()
+	since := state.UnmappedSince
+	if since.IsZero() {
+		since = now
+	}
+	elapsed := now.Sub(since)
+	issue := state.UnmappedIssue
+	if elapsed >= r.UnmappedEscalateAfter {
+		var paths []string
+		var unmapped *git.UnmappedError
+		if errors.As(err, &unmapped) {
+			paths = unmapped.Paths
+		}
+		msg := "No manifest declaring the quota was found in the Git repository"
+		if len(paths) > 0 {
+			msg += "; searched " + strings.Join(paths, ", ")
+		}
+		var quota corev1.ResourceQuota
+		if getErr := r.Get(ctx, key, &quota); getErr == nil {
+			r.Recorder.Event(&quota, corev1.EventTypeWarning, "QuotaUnmapped", msg)
+		}
+		quotaUnmapped.WithLabelValues(key.Namespace, key.Name).Set(1)
+
+		if reporter, ok := provider.(git.UnmappedReporter); ok && r.ReportUnmapped && issue == 0 {
+			opened, reportErr := reporter.ReportUnmapped(ctx, key.Namespace, key.Name, paths)
+			if reportErr != nil {
+				// The event and the metric already escalate; the issue is
+				// tried again on the next retry.
+				logger.Error(reportErr, "failed to open the unmapped quota issue")
+			} else {
+				issue = opened
+			}
+		}
+	}
+	if !since.Equal(state.UnmappedSince) || issue != state.UnmappedIssue {
+		err := r.Locker.MutateState(ctx, key.Namespace, key.Name, func(s *lock.State) {
+			s.UnmappedSince = since
+			s.UnmappedIssue = issue
+		})
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to record the unmapped quota: %w", err)
+		}
+	}
+
+	wait := min(max(elapsed, unmappedMinBackoff), unmappedMaxBackoff)
+	logger.Info("Quota file not found in Git repository. Retrying later.",
+		"error", err.Error(), "unmappedFor", elapsed.Round(time.Second), "retryIn", wait)
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
+// forgetUnmapped clears what handleUnmapped recorded for quotaName, whose
+// manifest was found again, and resolves its tracking issue.
+func (r *ResourceQuotaReconciler) forgetUnmapped(
+	ctx context.Context,
+	provider git.Provider,
+	namespace, quotaName string,
+	state lock.State,
+) error {
+	return r.clearUnmapped(ctx, provider, namespace, quotaName, state, true)
+}
+
+// withdrawUnmapped clears what handleUnmapped recorded for a quota that no
+// longer needs a change, so that a later failure starts the escalation over,
+// and withdraws its tracking issue. quota is only a key when the quota was
+// deleted. The provider that opened the issue is only resolved if there is
+// one.
+func (r *ResourceQuotaReconciler) withdrawUnmapped(
+	ctx context.Context,
+	quota *corev1.ResourceQuota,
+	ns *corev1.Namespace,
+	state lock.State,
+) error {
+	var provider git.Provider
+	if state.UnmappedIssue != 0 {
+		var err error
+		if provider, err = r.providerFor(ctx, quota, ns); err != nil {
+			// The Lease is cleared all the same: closing the issue by hand
+			// is all that is lost.
+			log.FromContext(ctx).Error(err, "failed to resolve the git provider of the unmapped quota issue",
+				"issue", state.UnmappedIssue)
+		}
+	}
+	return r.clearUnmapped(ctx, provider, quota.Namespace, quota.Name, state, false)
+}
+
+// forgetDeletedQuota withdraws the escalation of a quota that was deleted.
+func (r *ResourceQuotaReconciler) forgetDeletedQuota(ctx context.Context, key types.NamespacedName) error {
+	quotaUnmapped.DeleteLabelValues(key.Namespace, key.Name)
+	state, err := r.Locker.GetState(ctx, key.Namespace, key.Name)
+	if err != nil {
+		return err
+	}
+	if state.UnmappedSince.IsZero() && state.UnmappedIssue == 0 {
+		return nil
+	}
+	// The namespace routes the issue's repository; without it, as when the
+	// whole namespace is being deleted, the fallback is the best guess.
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: key.Namespace}}
+	if err := r.Get(ctx, client.ObjectKey{Name: key.Namespace}, &ns); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	quota := corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	return r.withdrawUnmapped(ctx, &quota, &ns, state)
+}
+
+// clearUnmapped is forgetUnmapped when found and withdrawUnmapped otherwise.
+func (r *ResourceQuotaReconciler) clearUnmapped(
+	ctx context.Context,
+	provider git.Provider,
+	namespace, quotaName string,
+	state lock.State,
+	found bool,
+) error {
+	quotaUnmapped.DeleteLabelValues(namespace, quotaName)
+	if state.UnmappedSince.IsZero() && state.UnmappedIssue == 0 {
+		return nil
+	}
+	if reporter, ok := provider.(git.UnmappedReporter); ok && state.UnmappedIssue != 0 {
+		closeIssue := reporter.WithdrawUnmapped
+		if found {
+			closeIssue = reporter.ResolveUnmapped
+		}
+		if err := closeIssue(ctx, state.UnmappedIssue); err != nil {
+			log.FromContext(ctx).Error(err, "failed to close the unmapped quota issue",
+				"issue", state.UnmappedIssue)
+		}
+	}
+	err := r.Locker.MutateState(ctx, namespace, quotaName, func(s *lock.State) {
+		s.UnmappedSince = time.Time{}
+		s.UnmappedIssue = 0
+	})
+	if err != nil {
+		return fmt.Errorf("failed to forget the unmapped quota: %w", err)
+	}
+	return nil
+}
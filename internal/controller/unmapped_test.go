@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// unmappedProvider is a FakeGitProvider whose manifest lookup fails while
+// missing is set, and that records the issues it was asked to open and close.
+type unmappedProvider struct {
+	*FakeGitProvider
+	missing   bool
+	reported  []string
+	resolved  []int
+	withdrawn []int
+}
+
+func (f *unmappedProvider) CreatePR(
+	ctx context.Context,
+	quotaName, namespace, direction string,
+	annotations map[string]string,
+	newLimits map[corev1.ResourceName]resource.Quantity,
+) (int, error) {
+	if f.missing {
+		return 0, &git.UnmappedError{Quota: quotaName, Paths: []string{"tenants/" + namespace}}
+	}
+	return f.FakeGitProvider.CreatePR(ctx, quotaName, namespace, direction, annotations, newLimits)
+}
+
+func (f *unmappedProvider) ReportUnmapped(_ context.Context, namespace, quotaName string, paths []string) (int, error) {
+	f.reported = append(f.reported, paths...)
+	return 7, nil
+}
+
+func (f *unmappedProvider) ResolveUnmapped(_ context.Context, issue int) error {
+	f.resolved = append(f.resolved, issue)
+	return nil
+}
+
+func (f *unmappedProvider) WithdrawUnmapped(_ context.Context, issue int) error {
+	f.withdrawn = append(f.withdrawn, issue)
+	return nil
+}
+
+func TestUnmapped_EscalatesWithBackoff(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	h := newShrinkHarness(t, nil, shrinkHarnessOpts{window: true})
+	provider := &unmappedProvider{FakeGitProvider: h.provider, missing: true}
+	h.reconciler.GitProvider = provider
+	h.reconciler.UnmappedEscalateAfter = time.Hour
+	h.reconciler.ReportUnmapped = true
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "compute"}}
+
+	result, err := h.reconciler.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Minute))
+	g.Expect(h.events()).NotTo(ContainElement(ContainSubstring("QuotaUnmapped")), "not escalated yet")
+	g.Expect(provider.reported).To(BeEmpty())
+	state, err := h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.UnmappedSince.IsZero()).To(BeFalse())
+
+	// Ten minutes in, the wait has grown to match.
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.UnmappedSince = time.Now().Add(-10 * time.Minute)
+	})).To(Succeed())
+	result, err = h.reconciler.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically("~", 10*time.Minute, time.Second))
+
+	g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+		s.UnmappedSince = time.Now().Add(-3 * time.Hour)
+	})).To(Succeed())
+	result, err = h.reconciler.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Hour))
+	g.Expect(h.events()).To(ContainElement(ContainSubstring("searched tenants/team-a")))
+	g.Expect(testutil.ToFloat64(quotaUnmapped.WithLabelValues("team-a", "compute"))).To(Equal(1.0))
+	g.Expect(provider.reported).To(Equal([]string{"tenants/team-a"}))
+
+	// The issue is opened once.
+	_, err = h.reconciler.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.reported).To(HaveLen(1))
+
+	// Once the manifest is found, the escalation is withdrawn.
+	provider.missing = false
+	_, err = h.reconciler.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(h.provider.CreatePRCalls).To(Equal(1))
+	g.Expect(provider.resolved).To(Equal([]int{7}))
+	g.Expect(testutil.CollectAndCount(quotaUnmapped)).To(Equal(0))
+	state, err = h.locker.GetState(ctx, "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(state.UnmappedSince.IsZero()).To(BeTrue())
+	g.Expect(state.UnmappedIssue).To(Equal(0))
+}
+
+// TestUnmapped_WithdrawnWhenNoLongerNeeded covers the quotas whose manifest
+// stops mattering before it is found: an escalation left behind would keep
+// the metric and the issue up, and make the next failure escalate at once.
+func TestUnmapped_WithdrawnWhenNoLongerNeeded(t *testing.T) {
+	cases := map[string]func(g *WithT, h *shrinkHarness){
+		// Without an observation window the decision is DirectionNone.
+		"demand gone": func(*WithT, *shrinkHarness) {},
+		"quota deleted": func(g *WithT, h *shrinkHarness) {
+			quota := &corev1.ResourceQuota{}
+			quota.Namespace, quota.Name = "team-a", "compute"
+			g.Expect(h.reconciler.Delete(context.Background(), quota)).To(Succeed())
+		},
+	}
+	for name, setup := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			h := newShrinkHarness(t, nil, shrinkHarnessOpts{})
+			provider := &unmappedProvider{FakeGitProvider: h.provider}
+			h.reconciler.GitProvider = provider
+			g.Expect(h.locker.MutateState(ctx, "team-a", "compute", func(s *lock.State) {
+				s.UnmappedSince = time.Now().Add(-3 * time.Hour)
+				s.UnmappedIssue = 7
+			})).To(Succeed())
+			quotaUnmapped.WithLabelValues("team-a", "compute").Set(1)
+			setup(g, h)
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team-a", Name: "compute"}}
+			_, err := h.reconciler.Reconcile(ctx, req)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(provider.withdrawn).To(Equal([]int{7}))
+			g.Expect(provider.resolved).To(BeEmpty(), "the manifest was not found")
+			g.Expect(testutil.CollectAndCount(quotaUnmapped)).To(Equal(0))
+			state, err := h.locker.GetState(ctx, "team-a", "compute")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(state.UnmappedSince.IsZero()).To(BeTrue())
+			g.Expect(state.UnmappedIssue).To(Equal(0))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// QuotaChange is the proposal for one quota of a batched pull request.
+// Reason and Exclusions explain it in the quota's section, as WithReason
+// does for a pull request of its own.
+type QuotaChange struct {
+	Quota      string
+	Limits     map[corev1.ResourceName]resource.Quantity
+	Reason     string
+	Exclusions []string
+}
+
+// batchSegment takes the place of the quota name in the branch of a batched
+// pull request. Kubernetes object names cannot contain "_", so no quota can
+// produce it.
+const batchSegment = "_batch"
+
+// CreateBatchPR opens one pull request with a single commit that carries the
+// limits of every quota in changes, all of them in namespace and all in the
+// same direction. The body holds one section per quota, which UpdatePR later
+// rewrites on its own.
+func (g *GitHubProvider) CreateBatchPR(ctx context.Context, namespace, direction string, annotations map[string]string, changes []QuotaChange) (int, error) {
+	changes = sortedChanges(changes)
+	verb := "Resize"
+	if direction == DirectionShrink {
+		verb = "Shrink"
+	}
+	baseBranch, quotas, err := g.planQuotas(namespace, annotations, changes)
+	if err != nil {
+		return 0, err
+	}
+	return g.createPR(ctx, direction, pullRequestPlan{
+		branchPrefix: g.branchPrefix(direction, namespace, batchSegment),
+		baseBranch:   baseBranch,
+		title:        fmt.Sprintf("%s %d Quotas in %s", verb, len(changes), namespace),
+		message:      fmt.Sprintf("chore(%s): resize quotas %s", namespace, strings.Join(quotaNames(changes), ", ")),
+		quotas:       quotas,
+		labels:       g.prLabels(direction, namespace),
+		body: func(format manifestFormat) string {
+			return generateBatchPRBody(namespace, changes, format)
+		},
+	})
+}
+
+func sortedChanges(changes []QuotaChange) []QuotaChange {
+	sorted := append([]QuotaChange(nil), changes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Quota < sorted[j].Quota })
+	return sorted
+}
+
+func quotaNames(changes []QuotaChange) []string {
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		names = append(names, change.Quota)
+	}
+	return names
+}
+
+// quotaSectionStart and quotaSectionEnd delimit the part of a batched pull
+// request's body that belongs to one quota. They are HTML comments, so they
+// do not render.
+func quotaSectionStart(quota string) string { return "<!-- resizer:quota " + quota + " -->" }
+func quotaSectionEnd(quota string) string   { return "<!-- /resizer:quota " + quota + " -->" }
+
+func quotaSection(change QuotaChange) string {
+	quota, limits := change.Quota, change.Limits
+	var sb strings.Builder
+	sb.WriteString(quotaSectionStart(quota) + "\n")
+	_, _ = fmt.Fprintf(&sb, "#### `%s`\n\n", quota)
+	sb.WriteString("| Resource | New Limit |\n")
+	sb.WriteString("| :--- | :--- |\n")
+	resources := make([]string, 0, len(limits))
+	for res := range limits {
+		resources = append(resources, string(res))
+	}
+	sort.Strings(resources)
+	for _, res := range resources {
+		qty := limits[corev1.ResourceName(res)]
+		_, _ = fmt.Fprintf(&sb, "| %s | %s |\n", res, qty.String())
+	}
+	writeExclusions(&sb, change.Exclusions)
+	writeReason(&sb, change.Reason)
+	sb.WriteString(quotaSectionEnd(quota))
+	return sb.String()
+}
+
+func generateBatchPRBody(ns string, changes []QuotaChange, format manifestFormat) string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "### Quota Resize Recommendation for `%s`\n\n", ns)
+	_, _ = fmt.Fprintf(&sb, "The Namespace Resizer Controller batched the proposals for %d quotas in this namespace:\n\n",
+		len(changes))
+	for _, change := range changes {
+		sb.WriteString(quotaSection(change))
+		sb.WriteString("\n\n")
+	}
+	if format == formatTerraform {
+		sb.WriteString(terraformApplyNote)
+	}
+	sb.WriteString("\n*Generated automatically by Namespace Resizer*")
+	return sb.String()
+}
+
+// replaceQuotaSection swaps the section of change's quota in a batched pull
+// request's body for one carrying change. It reports false when body has no
+// such section, i.e. the pull request is not a batch.
+func replaceQuotaSection(body string, change QuotaChange) (string, bool) {
+	quota := change.Quota
+	start := strings.Index(body, quotaSectionStart(quota))
+	if start < 0 {
+		return body, false
+	}
+	end := strings.Index(body[start:], quotaSectionEnd(quota))
+	if end < 0 {
+		return body, false
+	}
+	end += start + len(quotaSectionEnd(quota))
+	return body[:start] + quotaSection(change) + body[end:], true
+}
@@ -0,0 +1,198 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const batchQuotasYAML = `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: compute
+spec:
+  hard:
+    requests.cpu: "4"
+---
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: storage
+spec:
+  hard:
+    requests.storage: "10Gi"
+`
+
+const batchCountsYAML = `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: counts
+spec:
+  hard:
+    pods: "10"
+`
+
+func TestCreateBatchPR_OneCommitForAllQuotas(t *testing.T) {
+	g := NewWithT(t)
+	dir := "/repos/o/r/contents/managed-resources/cluster/team-a"
+	files := map[string]string{"quotas.yaml": batchQuotasYAML, "counts.yaml": batchCountsYAML}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"default_branch": "main"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	var branch string
+	mux.HandleFunc("/repos/o/r/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Ref string `json:"ref"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		branch = body.Ref
+		_, _ = fmt.Fprint(w, `{"ref": "refs/heads/new-branch"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/refs/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "commit-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/commits/base-sha", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"sha": "base-sha", "tree": {"sha": "base-tree"}}`)
+	})
+	commits := 0
+	mux.HandleFunc("/repos/o/r/git/commits", func(w http.ResponseWriter, r *http.Request) {
+		commits++
+		_, _ = fmt.Fprint(w, `{"sha": "commit-sha"}`)
+	})
+	tree := map[string]string{}
+	mux.HandleFunc("/repos/o/r/git/trees", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tree []struct {
+				Path    string `json:"path"`
+				Content string `json:"content"`
+			} `json:"tree"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		for _, entry := range body.Tree {
+			tree[entry.Path] = entry.Content
+		}
+		_, _ = fmt.Fprint(w, `{"sha": "new-tree"}`)
+	})
+	mux.HandleFunc(dir, func(w http.ResponseWriter, r *http.Request) {
+		var entries []string
+		for name := range files {
+			entries = append(entries, fmt.Sprintf(
+				`{"name": %q, "path": "managed-resources/cluster/team-a/%s", "type": "file"}`, name, name))
+		}
+		_, _ = fmt.Fprintf(w, "[%s]", strings.Join(entries, ","))
+	})
+	for name, content := range files {
+		mux.HandleFunc(dir+"/"+name, func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(r.Method).To(Equal(http.MethodGet))
+			_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64", "sha": "sha-%s"}`,
+				base64.StdEncoding.EncodeToString([]byte(content)), name)
+		})
+	}
+	var title, prBody string
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		title, prBody = body.Title, body.Body
+		_, _ = fmt.Fprint(w, `{"number": 11, "state": "open"}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/11/labels", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	prID, err := provider.CreateBatchPR(context.Background(), "team-a", DirectionGrow, nil, []QuotaChange{
+		{Quota: "storage", Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceRequestsStorage: resource.MustParse("20Gi")}},
+		{Quota: "counts", Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourcePods: resource.MustParse("20")}},
+		{Quota: "compute", Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceRequestsCPU: resource.MustParse("8")},
+			Reason: "- `requests.cpu`: 6 -> 8 (driven by projected demand)"},
+	})
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(11))
+	g.Expect(branch).To(HavePrefix("refs/heads/resize/cluster/grow/team-a/_batch/"))
+	g.Expect(commits).To(Equal(1), "all quotas go into one commit")
+	g.Expect(tree).To(HaveLen(2))
+	quotas := tree["managed-resources/cluster/team-a/quotas.yaml"]
+	g.Expect(quotas).To(ContainSubstring(`requests.cpu: "8"`), "both quotas of a shared file are edited")
+	g.Expect(quotas).To(ContainSubstring(`requests.storage: "20Gi"`))
+	g.Expect(tree["managed-resources/cluster/team-a/counts.yaml"]).To(ContainSubstring(`pods: "20"`))
+	g.Expect(title).To(Equal("Resize 3 Quotas in team-a"))
+	for _, quota := range []string{"compute", "counts", "storage"} {
+		g.Expect(prBody).To(ContainSubstring(quotaSectionStart(quota)))
+	}
+	g.Expect(prBody).To(ContainSubstring("| requests.cpu | 8 |\n\n#### Why\n\n" +
+		"- `requests.cpu`: 6 -> 8 (driven by projected demand)\n" + quotaSectionEnd("compute")))
+}
+
+func TestReplaceQuotaSection(t *testing.T) {
+	g := NewWithT(t)
+	cpu := func(v string) map[corev1.ResourceName]resource.Quantity {
+		return map[corev1.ResourceName]resource.Quantity{corev1.ResourceRequestsCPU: resource.MustParse(v)}
+	}
+	body := generateBatchPRBody("team-a", []QuotaChange{
+		{Quota: "compute", Limits: cpu("8")},
+		{Quota: "batch-jobs", Limits: cpu("2")},
+	}, formatYAML)
+
+	updated, ok := replaceQuotaSection(body, QuotaChange{
+		Quota:      "compute",
+		Limits:     cpu("12"),
+		Reason:     "- `requests.cpu`: 8 -> 12 (driven by projected demand)",
+		Exclusions: []string{"- `requests.cpu`: left out up to 3 used by workloads ignored in sizing, on 2026-08-05"},
+	})
+
+	g.Expect(ok).To(BeTrue())
+	g.Expect(updated).To(ContainSubstring("| requests.cpu | 12 |"))
+	g.Expect(updated).NotTo(ContainSubstring("| requests.cpu | 8 |"))
+	g.Expect(updated).To(ContainSubstring("| requests.cpu | 2 |"), "other quotas keep their section")
+	g.Expect(updated).To(ContainSubstring("- `requests.cpu`: 8 -> 12 (driven by projected demand)"))
+	g.Expect(updated).To(ContainSubstring("- `requests.cpu`: left out up to 3 used by workloads ignored in sizing"))
+
+	_, ok = replaceQuotaSection(generatePRBody("team-a", "compute", cpu("8"), formatYAML, "", nil),
+		QuotaChange{Quota: "compute", Limits: cpu("12")})
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestFindOpenPR_Batch(t *testing.T) {
+	g := NewWithT(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(generateBatchPRBody("team-a", []QuotaChange{{Quota: "compute"}}, formatYAML))
+		_, _ = fmt.Fprintf(w, `[{"number": 12, "head": {"ref": "resize/cluster/shrink/team-a/_batch/1700000000"}, "body": %s}]`, body)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(12))
+	g.Expect(direction).To(Equal(DirectionShrink))
+
+	id, _, err = provider.FindOpenPR(context.Background(), "team-a", "storage", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(0), "the batch does not carry this quota")
+}
@@ -0,0 +1,229 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DigestEntry is one quota of a shrink digest.
+type DigestEntry struct {
+	Namespace string
+	Quota     string
+	// Path is the directory holding the quota's manifests, as TargetOf
+	// resolved it for the quota's own provider.
+	Path string
+	// Current is the quota's hard limits today; the digest reports the
+	// difference to Limits as reclaimed capacity.
+	Current corev1.ResourceList
+	Limits  map[corev1.ResourceName]resource.Quantity
+}
+
+// Target is where a provider sends the pull requests of a namespace. Quotas
+// with equal Repository and BaseBranch can share one digest even when their
+// Paths differ.
+type Target struct {
+	// Repository is "owner/repo", or "" for providers that are not bound to
+	// a repository.
+	Repository string
+	// BaseBranch is "" for the repository's default branch.
+	BaseBranch string
+	Path       string
+}
+
+// TargetOf resolves where p sends the pull requests of namespace. Providers
+// that are not bound to a repository yield the zero Target.
+func TargetOf(p Provider, namespace string, annotations map[string]string) (Target, error) {
+	bound, ok := p.(interface {
+		target(namespace string, annotations map[string]string) (Target, error)
+	})
+	if !ok {
+		return Target{}, nil
+	}
+	return bound.target(namespace, annotations)
+}
+
+func (g *GitHubProvider) target(namespace string, annotations map[string]string) (Target, error) {
+	baseBranch, err := g.resolveBaseBranch(namespace, annotations)
+	if err != nil {
+		return Target{}, err
+	}
+	basePath, err := g.resolvePath(namespace, annotations)
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	return Target{Repository: g.Repository(), BaseBranch: baseBranch, Path: basePath}, nil
+}
+
+// digestSegment takes the place of the namespace in the branch of a digest.
+// Like batchSegment, no Kubernetes name can produce it.
+const digestSegment = "_digest"
+
+// labelDigest marks a digest, which carries no namespace label: it may span
+// more namespaces than GitHub allows labels on one pull request.
+const labelDigest = "resizer/digest"
+
+// digestBranchPrefix is the head branch of every digest this provider opens,
+// up to the timestamp: resize/<cluster>/shrink/_digest/.
+func (g *GitHubProvider) digestBranchPrefix() string {
+	if g.clusterName == "" {
+		return fmt.Sprintf("resize/%s/%s/", DirectionShrink, digestSegment)
+	}
+	return fmt.Sprintf("resize/%s/%s/%s/", g.clusterName, DirectionShrink, digestSegment)
+}
+
+// CreateDigestPR opens one shrink pull request, in a single commit, for
+// quotas of any number of namespaces, all targeting baseBranch ("" for the
+// default branch). An entry whose manifest cannot be found is left out rather
+// than holding back everyone else's; the entries that made it in are
+// returned. FindOpenPR finds the digest for each of them.
+func (g *GitHubProvider) CreateDigestPR(ctx context.Context, baseBranch string, entries []DigestEntry) (int, []DigestEntry, error) {
+	entries = sortedEntries(entries)
+	quotas := make([]plannedQuota, 0, len(entries))
+	for _, entry := range entries {
+		quotas = append(quotas, plannedQuota{
+			QuotaChange: QuotaChange{Quota: entry.Quota, Limits: entry.Limits},
+			namespace:   entry.Namespace,
+			basePath:    entry.Path,
+		})
+	}
+	skipped := map[string]bool{}
+	included := func() []DigestEntry {
+		var kept []DigestEntry
+		for _, entry := range entries {
+			if !skipped[digestKey(entry.Namespace, entry.Quota)] {
+				kept = append(kept, entry)
+			}
+		}
+		return kept
+	}
+
+	labels := []string{labelManaged, labelDirectionPrefix + DirectionShrink, labelDigest}
+	if g.clusterName != "" {
+		labels = append(labels, labelClusterPrefix+g.clusterName)
+	}
+	// The title carries no counts: the entries left out are only known once
+	// the edits are made.
+	prID, err := g.createPR(ctx, DirectionShrink, pullRequestPlan{
+		branchPrefix: g.digestBranchPrefix(),
+		baseBranch:   baseBranch,
+		title:        "Quota Shrink Digest",
+		message:      "chore: shrink quotas from the digest",
+		quotas:       quotas,
+		labels:       labels,
+		body: func(format manifestFormat) string {
+			return generateDigestPRBody(included(), format)
+		},
+		skip: func(quota plannedQuota, err error) {
+			log.FromContext(ctx).Info("Leaving quota out of the shrink digest",
+				"namespace", quota.namespace, "quota", quota.Quota, "reason", err.Error())
+			skipped[digestKey(quota.namespace, quota.Quota)] = true
+		},
+	})
+	if err != nil {
+		if len(skipped) == len(entries) {
+			return 0, nil, fmt.Errorf("%w: no quota of the digest was found: %v", ErrFileNotFound, err)
+		}
+		return 0, nil, err
+	}
+	return prID, included(), nil
+}
+
+// digestKey names a quota within a digest, whose sections are keyed by it.
+func digestKey(namespace, quota string) string {
+	return namespace + "/" + quota
+}
+
+func sortedEntries(entries []DigestEntry) []DigestEntry {
+	sorted := append([]DigestEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return digestKey(sorted[i].Namespace, sorted[i].Quota) < digestKey(sorted[j].Namespace, sorted[j].Quota)
+	})
+	return sorted
+}
+
+func countNamespaces(entries []DigestEntry) int {
+	namespaces := map[string]bool{}
+	for _, entry := range entries {
+		namespaces[entry.Namespace] = true
+	}
+	return len(namespaces)
+}
+
+// reclaimed sums, per resource, how far the entries lower their limits.
+func reclaimed(entries []DigestEntry) map[corev1.ResourceName]resource.Quantity {
+	total := map[corev1.ResourceName]resource.Quantity{}
+	for _, entry := range entries {
+		for res, limit := range entry.Limits {
+			current, ok := entry.Current[res]
+			if !ok {
+				continue
+			}
+			diff := current.DeepCopy()
+			diff.Sub(limit)
+			sum := total[res]
+			sum.Add(diff)
+			total[res] = sum
+		}
+	}
+	return total
+}
+
+func sortedResources(limits map[corev1.ResourceName]resource.Quantity) []corev1.ResourceName {
+	resources := make([]corev1.ResourceName, 0, len(limits))
+	for res := range limits {
+		resources = append(resources, res)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+	return resources
+}
+
+func generateDigestPRBody(entries []DigestEntry, format manifestFormat) string {
+	var sb strings.Builder
+	sb.WriteString("### Quota Shrink Digest\n\n")
+	_, _ = fmt.Fprintf(&sb, "The Namespace Resizer Controller collected %d shrink proposals across %d namespaces.\n\n",
+		len(entries), countNamespaces(entries))
+	sb.WriteString("Merging lowers every quota below at once. A team that disagrees with its own " +
+		"shrink can revert the change to its manifest after the merge; the controller takes that " +
+		"as a rejection for that quota only.\n\n")
+
+	sb.WriteString("#### Reclaimed Capacity\n\n")
+	sb.WriteString("| Resource | Reclaimed |\n")
+	sb.WriteString("| :--- | :--- |\n")
+	total := reclaimed(entries)
+	for _, res := range sortedResources(total) {
+		qty := total[res]
+		_, _ = fmt.Fprintf(&sb, "| %s | %s |\n", res, qty.String())
+	}
+
+	namespace := ""
+	for _, entry := range entries {
+		if entry.Namespace != namespace {
+			namespace = entry.Namespace
+			_, _ = fmt.Fprintf(&sb, "\n### Namespace `%s`\n\n", namespace)
+		}
+		key := digestKey(entry.Namespace, entry.Quota)
+		sb.WriteString(quotaSectionStart(key) + "\n")
+		_, _ = fmt.Fprintf(&sb, "#### `%s`\n\n", entry.Quota)
+		sb.WriteString("| Resource | Current | New Limit |\n")
+		sb.WriteString("| :--- | :--- | :--- |\n")
+		for _, res := range sortedResources(entry.Limits) {
+			current := entry.Current[res]
+			limit := entry.Limits[res]
+			_, _ = fmt.Fprintf(&sb, "| %s | %s | %s |\n", res, current.String(), limit.String())
+		}
+		sb.WriteString(quotaSectionEnd(key) + "\n")
+	}
+
+	if format == formatTerraform {
+		sb.WriteString("\n")
+		sb.WriteString(terraformApplyNote)
+	}
+	sb.WriteString("\n*Generated automatically by Namespace Resizer*")
+	return sb.String()
+}
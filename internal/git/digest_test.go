@@ -0,0 +1,146 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func digestEntry(namespace, quota, current, limit string) DigestEntry {
+	return DigestEntry{
+		Namespace: namespace,
+		Quota:     quota,
+		Path:      "managed-resources/cluster/" + namespace,
+		Current:   corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(current)},
+		Limits: map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceRequestsCPU: resource.MustParse(limit)},
+	}
+}
+
+func TestCreateDigestPR_OneCommitAcrossNamespaces(t *testing.T) {
+	g := NewWithT(t)
+	quotaYAML := "apiVersion: v1\nkind: ResourceQuota\nmetadata:\n  name: compute\nspec:\n  hard:\n    requests.cpu: \"16\"\n"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/git/ref/heads/env/prod", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	var branch string
+	mux.HandleFunc("/repos/o/r/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Ref string `json:"ref"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		branch = body.Ref
+		_, _ = fmt.Fprint(w, `{"ref": "refs/heads/new-branch"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/refs/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "commit-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/commits/base-sha", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"sha": "base-sha", "tree": {"sha": "base-tree"}}`)
+	})
+	commits := 0
+	mux.HandleFunc("/repos/o/r/git/commits", func(w http.ResponseWriter, r *http.Request) {
+		commits++
+		_, _ = fmt.Fprint(w, `{"sha": "commit-sha"}`)
+	})
+	tree := map[string]string{}
+	mux.HandleFunc("/repos/o/r/git/trees", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tree []struct {
+				Path    string `json:"path"`
+				Content string `json:"content"`
+			} `json:"tree"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		for _, entry := range body.Tree {
+			tree[entry.Path] = entry.Content
+		}
+		_, _ = fmt.Fprint(w, `{"sha": "new-tree"}`)
+	})
+	// team-c has no directory in the repository and is left out.
+	for _, ns := range []string{"team-a", "team-b"} {
+		dir := "managed-resources/cluster/" + ns
+		mux.HandleFunc("/repos/o/r/contents/"+dir, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `[{"name": "quota.yaml", "path": "%s/quota.yaml", "type": "file"}]`, dir)
+		})
+		mux.HandleFunc("/repos/o/r/contents/"+dir+"/quota.yaml", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64", "sha": "sha-%s"}`,
+				base64.StdEncoding.EncodeToString([]byte(quotaYAML)), ns)
+		})
+	}
+	var base, prBody string
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Base string `json:"base"`
+			Body string `json:"body"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		base, prBody = body.Base, body.Body
+		_, _ = fmt.Fprint(w, `{"number": 21, "state": "open"}`)
+	})
+	var labels []string
+	mux.HandleFunc("/repos/o/r/issues/21/labels", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(json.NewDecoder(r.Body).Decode(&labels)).To(Succeed())
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	prID, included, err := provider.CreateDigestPR(context.Background(), "env/prod", []DigestEntry{
+		digestEntry("team-b", "compute", "16", "12"),
+		digestEntry("team-c", "compute", "16", "12"),
+		digestEntry("team-a", "compute", "16", "8"),
+	})
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(21))
+	g.Expect(branch).To(HavePrefix("refs/heads/resize/cluster/shrink/_digest/"))
+	g.Expect(base).To(Equal("env/prod"))
+	g.Expect(commits).To(Equal(1), "every namespace goes into one commit")
+	g.Expect(tree["managed-resources/cluster/team-a/quota.yaml"]).To(ContainSubstring(`requests.cpu: "8"`))
+	g.Expect(tree["managed-resources/cluster/team-b/quota.yaml"]).To(ContainSubstring(`requests.cpu: "12"`))
+	g.Expect(included).To(HaveLen(2))
+	g.Expect(included[0].Namespace).To(Equal("team-a"))
+	g.Expect(included[1].Namespace).To(Equal("team-b"))
+	g.Expect(labels).To(ContainElements(labelDigest, labelDirectionPrefix+DirectionShrink))
+
+	g.Expect(prBody).To(ContainSubstring("| requests.cpu | 12 |"), "16-8 plus 16-12 reclaimed")
+	g.Expect(prBody).To(ContainSubstring(quotaSectionStart(digestKey("team-a", "compute"))))
+	g.Expect(prBody).To(ContainSubstring(quotaSectionStart(digestKey("team-b", "compute"))))
+	g.Expect(prBody).NotTo(ContainSubstring("team-c"))
+}
+
+func TestFindOpenPR_Digest(t *testing.T) {
+	g := NewWithT(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(generateDigestPRBody([]DigestEntry{
+			digestEntry("team-a", "compute", "16", "8"),
+		}, formatYAML))
+		_, _ = fmt.Fprintf(w, `[{"number": 22, "head": {"ref": "resize/cluster/shrink/_digest/1700000000"}, "body": %s}]`, body)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(22))
+	g.Expect(direction).To(Equal(DirectionShrink))
+
+	id, _, err = provider.FindOpenPR(context.Background(), "team-b", "compute", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(0), "the digest does not carry this namespace's quota")
+}
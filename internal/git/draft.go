@@ -0,0 +1,107 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// DraftProposer is implemented by providers that can open pull requests as
+// drafts, which notify nobody, and mark them ready for review later.
+type DraftProposer interface {
+	// AsDraft returns a provider whose new pull requests are drafts. Their
+	// reviewers are only requested by MarkReady.
+	AsDraft() Provider
+	// MarkReady takes the pull request out of draft and requests its
+	// reviewers. It is a no-op for the draft state of a pull request that is
+	// already ready.
+	MarkReady(ctx context.Context, prID int) error
+}
+
+// AsDraft implements DraftProposer.
+func (g *GitHubProvider) AsDraft() Provider {
+	scoped := *g
+	scoped.draft = true
+	return &scoped
+}
+
+// MarkReady implements DraftProposer. The reviewers requested are the ones
+// this provider was configured with, not the ones the draft was opened with.
+func (g *GitHubProvider) MarkReady(ctx context.Context, prID int) error {
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, g.repo, prID)
+	if err != nil {
+		return fmt.Errorf("failed to get PR %d: %w", prID, err)
+	}
+	if pr.GetDraft() {
+		if err := g.markReadyForReview(ctx, pr.GetNodeID()); err != nil {
+			return fmt.Errorf("failed to mark PR %d ready for review: %w", prID, err)
+		}
+	}
+	paths, err := g.changedFiles(ctx, prID)
+	if err != nil {
+		return err
+	}
+	g.requestReview(ctx, prID, pr.GetBase().GetRef(), paths)
+	return nil
+}
+
+// markReadyForReview runs the GraphQL mutation behind "Ready for review";
+// the REST API cannot take a pull request out of draft.
+func (g *GitHubProvider) markReadyForReview(ctx context.Context, nodeID string) error {
+	body := map[string]any{
+		"query": "mutation($id: ID!) { markPullRequestReadyForReview(input: {pullRequestId: $id}) " +
+			"{ pullRequest { isDraft } } }",
+		"variables": map[string]string{"id": nodeID},
+	}
+	req, err := g.client.NewRequest(http.MethodPost, graphQLURL(g.client.BaseURL), body)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := g.client.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", resp.Errors[0].Message)
+	}
+	return nil
+}
+
+// graphQLURL returns the GraphQL endpoint next to the REST base URL. GitHub
+// Enterprise Server serves REST under /api/v3/ and GraphQL at /api/graphql.
+func graphQLURL(base *url.URL) string {
+	endpoint := *base
+	if prefix, ok := strings.CutSuffix(base.Path, "/api/v3/"); ok {
+		endpoint.Path = prefix + "/api/graphql"
+		return endpoint.String()
+	}
+	endpoint.Path = strings.TrimSuffix(base.Path, "/") + "/graphql"
+	return endpoint.String()
+}
+
+// changedFiles lists the files pull request prID changes.
+func (g *GitHubProvider) changedFiles(ctx context.Context, prID int) ([]string, error) {
+	var paths []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := g.client.PullRequests.ListFiles(ctx, g.owner, g.repo, prID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of PR %d: %w", prID, err)
+		}
+		for _, file := range files {
+			paths = append(paths, file.GetFilename())
+		}
+		if resp.NextPage == 0 {
+			return paths, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
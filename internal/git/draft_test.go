@@ -0,0 +1,113 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCreatePR_DraftDefersReviewers(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	setupCreatePRRoutes(g, mux, 101)
+	mux.HandleFunc("/repos/o/r/issues/101/labels", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls/101/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a draft must not request reviewers")
+	})
+	var draft bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/r/pulls" {
+			body, _ := io.ReadAll(r.Body)
+			var pr struct {
+				Draft bool `json:"draft"`
+			}
+			g.Expect(json.Unmarshal(body, &pr)).To(Succeed())
+			draft = pr.Draft
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		mux.ServeHTTP(w, r)
+	})
+	provider, teardown := newTestProvider(t, handler)
+	defer teardown()
+
+	drafted := provider.WithReviewers(Reviewers{Users: []string{"alice"}}).(DraftProposer).AsDraft()
+	prID, err := drafted.CreatePR(context.Background(), "my-quota", "default", DirectionShrink, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(101))
+	g.Expect(draft).To(BeTrue())
+}
+
+func TestMarkReady(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls/101", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"number": 101, "draft": true, "node_id": "PR_101", "base": {"ref": "main"}}`)
+	})
+	var mutated string
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodPost))
+		var query struct {
+			Variables map[string]string `json:"variables"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&query)).To(Succeed())
+		mutated = query.Variables["id"]
+		_, _ = fmt.Fprint(w, `{"data": {"markPullRequestReadyForReview": {"pullRequest": {"isDraft": false}}}}`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls/101/files", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{"filename": "managed-resources/cluster/default/quota.yaml"}]`)
+	})
+	var request struct {
+		Reviewers []string `json:"reviewers"`
+	}
+	mux.HandleFunc("/repos/o/r/pulls/101/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+		_, _ = fmt.Fprint(w, `{"number": 101}`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	reviewed := provider.WithReviewers(Reviewers{Users: []string{"alice"}}).(DraftProposer)
+	g.Expect(reviewed.MarkReady(context.Background(), 101)).To(Succeed())
+
+	g.Expect(mutated).To(Equal("PR_101"))
+	g.Expect(request.Reviewers).To(Equal([]string{"alice"}))
+}
+
+func TestMarkReady_GraphQLError(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls/101", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"number": 101, "draft": true, "node_id": "PR_101"}`)
+	})
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"errors": [{"message": "Resource not accessible by integration"}]}`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	err := provider.MarkReady(context.Background(), 101)
+	g.Expect(err).To(MatchError(ContainSubstring("Resource not accessible by integration")))
+}
+
+func TestGraphQLURL(t *testing.T) {
+	g := NewWithT(t)
+	for base, want := range map[string]string{
+		"https://api.github.com/":           "https://api.github.com/graphql",
+		"https://ghe.example.com/api/v3/":   "https://ghe.example.com/api/graphql",
+		"https://proxy.example.com/github/": "https://proxy.example.com/github/graphql",
+	} {
+		u, err := url.Parse(base)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(graphQLURL(u)).To(Equal(want), base)
+	}
+}
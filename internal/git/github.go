@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strings"
 	"text/template"
 	"time"
@@ -38,6 +39,12 @@ const (
 
 	labelManaged         = "resizer/managed"
 	labelDirectionPrefix = "resizer/direction:"
+	labelNamespacePrefix = "resizer/ns:"
+	labelClusterPrefix   = "resizer/cluster:"
+
+	// AnnotationBaseBranch on a namespace sets the branch its pull requests
+	// target. It may use the same template fields as the path template.
+	AnnotationBaseBranch = "resizer.io/git-base-branch"
 )
 
 type Provider interface {
@@ -51,10 +58,22 @@ type Provider interface {
 		newLimits map[corev1.ResourceName]resource.Quantity) error
 	// FindOpenPR returns the number and the direction of an existing open PR
 	// managed by the resizer, or 0 and an empty direction if none exists.
-	FindOpenPR(ctx context.Context, namespace, quotaName string) (int, string, error)
+	// annotations are the namespace's, as for CreatePR.
+	FindOpenPR(ctx context.Context, namespace, quotaName string,
+		annotations map[string]string) (int, string, error)
 	// ClosePR posts comment on the pull request and then closes it without
 	// merging.
 	ClosePR(ctx context.Context, prID int, comment string) error
+	// CreateBatchPR opens a single pull request for several quotas of one
+	// namespace, all changed in the same direction. FindOpenPR finds it for
+	// each of them.
+	CreateBatchPR(ctx context.Context, namespace, direction string,
+		annotations map[string]string, changes []QuotaChange) (int, error)
+	// CreateDigestPR opens a single shrink pull request for quotas of several
+	// namespaces that share a repository and base branch, and returns the
+	// entries it carries. FindOpenPR finds it for each of them.
+	CreateDigestPR(ctx context.Context, baseBranch string,
+		entries []DigestEntry) (int, []DigestEntry, error)
 }
 
 type PRStatus struct {
@@ -75,6 +94,33 @@ type GitHubProvider struct {
 	repo         string
 	clusterName  string
 	pathTemplate *template.Template
+
+	// baseBranch is the branch pull requests target, a template over
+	// .Cluster and .Namespace; empty means the repository's default branch.
+	// The resizer.io/git-base-branch annotation overrides it.
+	baseBranch string
+	// baseBranchDiscovered marks baseBranch as a revision read from a GitOps
+	// tool (see WithSource). It names a real branch, or is empty for the
+	// discovered repository's default, and is used verbatim: "{{" in it is
+	// not a template.
+	baseBranchDiscovered bool
+	// sourcePath replaces the path template once the provider was scoped to
+	// a discovered source (see WithSource). The root directory is "", so
+	// sourceScoped tells it apart from "no source".
+	sourcePath   string
+	sourceScoped bool
+	// reviewers are requested on every pull request the provider opens; see
+	// WithReviewers.
+	reviewers Reviewers
+	// draft opens pull requests as drafts; see AsDraft.
+	draft bool
+	// createKey names the branch of the next pull request instead of a
+	// timestamp; see WithCreateKey.
+	createKey string
+	// reason explains the proposal in the pull request body, and exclusions
+	// list the usage its limits leave out; see WithReason.
+	reason     string
+	exclusions []string
 }
 
 func NewGitHubProvider(token, owner, repo, clusterName, pathTmpl string) *GitHubProvider {
@@ -120,18 +166,53 @@ func (g *GitHubProvider) resolvePath(namespace string, annotations map[string]st
 		return val, nil
 	}
 
-	// 2. Use Template
-	data := struct {
+	// 2. Use the discovered source
+	if g.sourceScoped {
+		return g.sourcePath, nil
+	}
+
+	// 3. Use Template
+	var buf bytes.Buffer
+	if err := g.pathTemplate.Execute(&buf, g.templateData(namespace)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateData is what the path and base branch templates are executed on.
+func (g *GitHubProvider) templateData(namespace string) any {
+	return struct {
 		Cluster   string
 		Namespace string
 	}{
 		Cluster:   g.clusterName,
 		Namespace: namespace,
 	}
+}
 
+// resolveBaseBranch returns the branch pull requests for namespace target,
+// or "" for the repository's default branch. The annotation wins over the
+// provider's branch, which is the discovered revision, the route's branch or
+// the global one, in that order of precedence (see WithSource and
+// WithBaseBranch). The annotation and the configured branches are templates;
+// a discovered revision is taken as it is.
+func (g *GitHubProvider) resolveBaseBranch(namespace string, annotations map[string]string) (string, error) {
+	raw := g.baseBranch
+	if val, ok := annotations[AnnotationBaseBranch]; ok && val != "" {
+		raw = val
+	} else if g.baseBranchDiscovered {
+		return raw, nil
+	}
+	if raw == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("base").Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid base branch %q: %w", raw, err)
+	}
 	var buf bytes.Buffer
-	if err := g.pathTemplate.Execute(&buf, data); err != nil {
-		return "", err
+	if err := tmpl.Execute(&buf, g.templateData(namespace)); err != nil {
+		return "", fmt.Errorf("invalid base branch %q: %w", raw, err)
 	}
 	return buf.String(), nil
 }
@@ -180,14 +261,97 @@ func (g *GitHubProvider) MergePR(ctx context.Context, prID int, method string) e
 }
 
 func (g *GitHubProvider) CreatePR(ctx context.Context, quotaName, namespace, direction string, annotations map[string]string, newLimits map[corev1.ResourceName]resource.Quantity) (int, error) {
-	// 1. Get default branch ref
-	repo, _, err := g.client.Repositories.Get(ctx, g.owner, g.repo)
+	title := fmt.Sprintf("Resize Quota %s in %s", quotaName, namespace)
+	if direction == DirectionShrink {
+		title = fmt.Sprintf("Shrink Quota %s in %s", quotaName, namespace)
+	}
+	baseBranch, quotas, err := g.planQuotas(namespace, annotations, []QuotaChange{{Quota: quotaName, Limits: newLimits}})
+	if err != nil {
+		return 0, err
+	}
+	return g.createPR(ctx, direction, pullRequestPlan{
+		branchPrefix: g.branchPrefix(direction, namespace, quotaName),
+		baseBranch:   baseBranch,
+		title:        title,
+		message:      fmt.Sprintf("chore(%s): resize quota %s", namespace, quotaName),
+		quotas:       quotas,
+		labels:       g.prLabels(direction, namespace),
+		body: func(format manifestFormat) string {
+			return generatePRBody(namespace, quotaName, newLimits, format, g.reason, g.exclusions)
+		},
+	})
+}
+
+// pullRequestPlan is what distinguishes a single-quota pull request from a
+// batched or a digest one; createPR does the rest.
+type pullRequestPlan struct {
+	branchPrefix string
+	// baseBranch is the branch to target, "" for the repository's default.
+	baseBranch string
+	title      string
+	message    string
+	quotas     []plannedQuota
+	labels     []string
+	body       func(format manifestFormat) string
+	// skip, when set, is told about every quota whose manifest cannot be
+	// found, and the pull request goes ahead without it. Unset, a missing
+	// manifest fails the whole pull request.
+	skip func(quota plannedQuota, err error)
+}
+
+// plannedQuota is one quota a pull request changes, with the directory its
+// manifest lives in.
+type plannedQuota struct {
+	QuotaChange
+	namespace string
+	basePath  string
+}
+
+// planQuotas resolves the base branch and the manifest directory of changes,
+// all of them quotas of namespace.
+func (g *GitHubProvider) planQuotas(namespace string, annotations map[string]string, changes []QuotaChange) (string, []plannedQuota, error) {
+	baseBranch, err := g.resolveBaseBranch(namespace, annotations)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get repo: %w", err)
+		return "", nil, err
 	}
-	baseRef, _, err := g.client.Git.GetRef(ctx, g.owner, g.repo, "refs/heads/"+repo.GetDefaultBranch())
+	basePath, err := g.resolvePath(namespace, annotations)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get base ref: %w", err)
+		return "", nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	quotas := make([]plannedQuota, 0, len(changes))
+	for _, change := range changes {
+		quotas = append(quotas, plannedQuota{QuotaChange: change, namespace: namespace, basePath: basePath})
+	}
+	return baseBranch, quotas, nil
+}
+
+// prLabels returns the labels of a pull request for namespace in direction.
+func (g *GitHubProvider) prLabels(direction, namespace string) []string {
+	labels := []string{
+		labelManaged,
+		labelDirectionPrefix + direction,
+	}
+	if g.clusterName != "" {
+		return append(labels,
+			labelNamespacePrefix+g.clusterName+"/"+namespace,
+			labelClusterPrefix+g.clusterName)
+	}
+	return append(labels, labelNamespacePrefix+namespace)
+}
+
+func (g *GitHubProvider) createPR(ctx context.Context, direction string, plan pullRequestPlan) (int, error) {
+	// 1. Get base branch ref
+	baseBranch := plan.baseBranch
+	if baseBranch == "" {
+		repo, _, err := g.client.Repositories.Get(ctx, g.owner, g.repo)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get repo: %w", err)
+		}
+		baseBranch = repo.GetDefaultBranch()
+	}
+	baseRef, _, err := g.client.Git.GetRef(ctx, g.owner, g.repo, "refs/heads/"+baseBranch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get base ref %s: %w", baseBranch, err)
 	}
 
 	// 2. Create new branch
@@ -208,72 +372,114 @@ func (g *GitHubProvider) CreatePR(ctx context.Context, quotaName, namespace, dir
 	// produce "resize/shrink-team-...-<ts>"). Kubernetes object names cannot
 	// contain "/", so that collision is structurally impossible once "/"
 	// separates the new shape's segments.
-	branchName := fmt.Sprintf("resize/%s/%s/%s/%d", direction, namespace, quotaName, time.Now().Unix())
-	newRef := github.CreateRef{
-		Ref: "refs/heads/" + branchName,
-		SHA: baseRef.Object.GetSHA(),
+	//
+	// The cluster leads the name because several clusters may share one
+	// repository with identical namespace names; see branchPrefix.
+	//
+	// A create key replaces the timestamp, which makes the name the same for
+	// every attempt at the same proposal: a branch left behind by an
+	// interrupted attempt is picked up where it stopped.
+	branchName := fmt.Sprintf("%s%d", plan.branchPrefix, time.Now().Unix())
+	if g.createKey != "" {
+		branchName = plan.branchPrefix + g.createKey
 	}
-	_, _, err = g.client.Git.CreateRef(ctx, g.owner, g.repo, newRef)
+	head, resumed, err := g.createBranch(ctx, branchName, baseRef.Object.GetSHA())
 	if err != nil {
-		return 0, fmt.Errorf("failed to create branch: %w", err)
+		return 0, err
 	}
-
-	// 3. Find the file
-	basePath, err := g.resolvePath(namespace, annotations)
-	if err != nil {
-		return 0, fmt.Errorf("failed to resolve path: %w", err)
+	if resumed {
+		log.FromContext(ctx).Info("Resuming an interrupted pull request", "branch", branchName)
 	}
-
-	targetFile, fileContent, err := g.findQuotaFile(ctx, basePath, branchName, quotaName)
-	if err != nil {
-		return 0, fmt.Errorf("failed to find quota file in %s: %w", basePath, err)
+	// A branch that carries no commit yet is deleted again when this attempt
+	// gives up before committing, so an attempt that finds nothing to change
+	// does not leave one more empty branch behind on every retry.
+	abandon := func() {
+		if head == baseRef.Object.GetSHA() {
+			g.deleteBranch(ctx, branchName)
+		}
 	}
 
-	content, err := fileContent.GetContent()
-	if err != nil {
-		return 0, err
+	// 3. Apply changes to content. Every quota's edits land in one commit;
+	// quotas sharing a file are applied on top of each other.
+	var edits []fileEdit
+	pending := map[string]int{}
+	format := formatYAML
+	for _, quota := range plan.quotas {
+		quotaEdits, quotaFormat, err := g.editQuota(ctx, quota.basePath, branchName, quota.namespace, quota.Quota, quota.Limits, edits)
+		if err != nil {
+			if plan.skip != nil && errors.Is(err, ErrFileNotFound) {
+				plan.skip(quota, err)
+				continue
+			}
+			abandon()
+			return 0, fmt.Errorf("failed to find quota file for %s in %s: %w", quota.Quota, quota.basePath, err)
+		}
+		if format != formatTerraform && quotaFormat != "" {
+			format = quotaFormat
+		}
+		for _, edit := range quotaEdits {
+			if i, ok := pending[edit.path]; ok {
+				edits[i] = edit
+				continue
+			}
+			pending[edit.path] = len(edits)
+			edits = append(edits, edit)
+		}
 	}
-
-	// 4. Apply changes to content
-	newContent := applyChangesToYaml(content, newLimits)
-
-	// 5. Commit changes
-	opts := &github.RepositoryContentFileOptions{
-		Message:   github.Ptr(fmt.Sprintf("chore(%s): resize quota %s", namespace, quotaName)),
-		Content:   []byte(newContent),
-		SHA:       fileContent.SHA,
-		Branch:    github.Ptr(branchName),
-		Committer: &github.CommitAuthor{Name: github.Ptr("Namespace Resizer"), Email: github.Ptr("bot@resizer.io")},
+	// A resumed branch that moved past the base already carries the commit.
+	committed := resumed && head != baseRef.Object.GetSHA()
+	if len(edits) == 0 && !committed {
+		abandon()
+		return 0, errors.New("the repository already carries the requested limits")
 	}
-	_, _, err = g.client.Repositories.UpdateFile(ctx, g.owner, g.repo, targetFile, opts)
-	if err != nil {
-		return 0, fmt.Errorf("failed to commit file: %w", err)
+
+	// 4. Commit changes
+	if len(edits) > 0 {
+		if err := g.commitFiles(ctx, branchName, plan.message, edits); err != nil {
+			abandon()
+			return 0, fmt.Errorf("failed to commit file: %w", err)
+		}
 	}
 
-	// 6. Create PR
-	title := fmt.Sprintf("Resize Quota %s in %s", quotaName, namespace)
-	if direction == DirectionShrink {
-		title = fmt.Sprintf("Shrink Quota %s in %s", quotaName, namespace)
+	// 5. Create PR, unless the interrupted attempt got that far.
+	var pr *github.PullRequest
+	if resumed {
+		if pr, err = g.openPRFor(ctx, branchName); err != nil {
+			return 0, err
+		}
 	}
-	newPR := &github.NewPullRequest{
-		Title:               github.Ptr(title),
-		Head:                github.Ptr(branchName),
-		Base:                github.Ptr(repo.GetDefaultBranch()),
-		Body:                github.Ptr(generatePRBody(namespace, quotaName, newLimits)),
-		MaintainerCanModify: github.Ptr(true),
+	if pr == nil {
+		body := plan.body(format)
+		if g.createKey != "" {
+			body += createKeyMarker(g.createKey)
+		}
+		newPR := &github.NewPullRequest{
+			Title:               github.Ptr(plan.title),
+			Head:                github.Ptr(branchName),
+			Base:                github.Ptr(baseBranch),
+			Body:                github.Ptr(body),
+			MaintainerCanModify: github.Ptr(true),
+			Draft:               github.Ptr(g.draft),
+		}
+		if pr, _, err = g.client.PullRequests.Create(ctx, g.owner, g.repo, newPR); err != nil {
+			return 0, fmt.Errorf("failed to create PR: %w", err)
+		}
 	}
 
-	pr, _, err := g.client.PullRequests.Create(ctx, g.owner, g.repo, newPR)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create PR: %w", err)
+	// The files to review are the ones edited, or, when the commit was
+	// already there, the ones the pull request changes.
+	paths := make([]string, 0, len(edits))
+	for _, edit := range edits {
+		paths = append(paths, edit.path)
 	}
-
-	// 7. Add Labels
-	labels := []string{
-		labelManaged,
-		fmt.Sprintf("resizer/ns:%s", namespace),
-		labelDirectionPrefix + direction,
+	if len(paths) == 0 && !g.draft {
+		if paths, err = g.changedFiles(ctx, pr.GetNumber()); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list the files to review", "pr", pr.GetNumber())
+		}
 	}
+
+	// 6. Add Labels
+	labels := plan.labels
 	if err := g.addLabels(ctx, pr.GetNumber(), labels); err != nil {
 		logger := log.FromContext(ctx)
 		if direction != DirectionShrink {
@@ -281,6 +487,7 @@ func (g *GitHubProvider) CreatePR(ctx context.Context, quotaName, namespace, dir
 			// so the only cost is a less precise audit trail.
 			logger.Error(err, "failed to label pull request",
 				"pr", pr.GetNumber(), "direction", direction)
+			g.requestDraftReview(ctx, pr.GetNumber(), baseBranch, paths)
 			return pr.GetNumber(), nil
 		}
 		// An unlabelled shrink is indistinguishable from a grow once this
@@ -298,6 +505,8 @@ func (g *GitHubProvider) CreatePR(ctx context.Context, quotaName, namespace, dir
 			pr.GetNumber(), err)
 	}
 
+	// 7. Request reviews
+	g.requestDraftReview(ctx, pr.GetNumber(), baseBranch, paths)
 	return pr.GetNumber(), nil
 }
 
@@ -348,34 +557,20 @@ func (g *GitHubProvider) UpdatePR(ctx context.Context, prID int, quotaName, name
 		return err
 	}
 
-	targetFile, fileContent, err := g.findQuotaFile(ctx, basePath, branchName, quotaName)
-	if err != nil {
-		return err
-	}
-
-	content, err := fileContent.GetContent()
+	// 3. Apply new changes
+	edits, format, err := g.editQuota(ctx, basePath, branchName, namespace, quotaName, newLimits, nil)
 	if err != nil {
 		return err
 	}
 
-	// 3. Apply new changes
-	newContent := applyChangesToYaml(content, newLimits)
-
 	// Check if content actually changed to avoid empty commits
-	if newContent == content {
+	if len(edits) == 0 {
 		return nil
 	}
 
 	// 4. Commit update
-	opts := &github.RepositoryContentFileOptions{
-		Message:   github.Ptr(fmt.Sprintf("chore(%s): update quota resize %s", namespace, quotaName)),
-		Content:   []byte(newContent),
-		SHA:       fileContent.SHA,
-		Branch:    github.Ptr(branchName),
-		Committer: &github.CommitAuthor{Name: github.Ptr("Namespace Resizer"), Email: github.Ptr("bot@resizer.io")},
-	}
-	_, _, err = g.client.Repositories.UpdateFile(ctx, g.owner, g.repo, targetFile, opts)
-	if err != nil {
+	message := fmt.Sprintf("chore(%s): update quota resize %s", namespace, quotaName)
+	if err := g.commitFiles(ctx, branchName, message, edits); err != nil {
 		return fmt.Errorf("failed to update file: %w", err)
 	}
 
@@ -383,7 +578,16 @@ func (g *GitHubProvider) UpdatePR(ctx context.Context, prID int, quotaName, name
 	// Only send the fields we intend to change. Passing the full PR object
 	// returned by Get would also marshal head/base/state, which the Edit endpoint
 	// rejects (422) because base must be a branch name, not an object.
-	newBody := generatePRBody(namespace, quotaName, newLimits)
+	// A batched pull request only has this quota's section rewritten.
+	newBody, batched := replaceQuotaSection(pr.GetBody(), QuotaChange{
+		Quota:      quotaName,
+		Limits:     newLimits,
+		Reason:     g.reason,
+		Exclusions: g.exclusions,
+	})
+	if !batched {
+		newBody = generatePRBody(namespace, quotaName, newLimits, format, g.reason, g.exclusions) + keptCreateKeyMarker(pr.GetBody())
+	}
 	update := &github.PullRequest{Body: github.Ptr(newBody)}
 	_, _, err = g.client.PullRequests.Edit(ctx, g.owner, g.repo, prID, update)
 	if err != nil {
@@ -393,6 +597,35 @@ func (g *GitHubProvider) UpdatePR(ctx context.Context, prID int, quotaName, name
 	return nil
 }
 
+// branchPrefix is the head branch of every pull request this provider opens
+// for namespace/quota in direction, up to the timestamp:
+// resize/<cluster>/<direction>/<namespace>/<quota>/. Without a configured
+// cluster the segment is left out, which is the shape branches had before it
+// existed.
+func (g *GitHubProvider) branchPrefix(direction, namespace, quotaName string) string {
+	if g.clusterName == "" {
+		return fmt.Sprintf("resize/%s/%s/%s/", direction, namespace, quotaName)
+	}
+	return fmt.Sprintf("resize/%s/%s/%s/%s/", g.clusterName, direction, namespace, quotaName)
+}
+
+// hasBranchPrefix reports whether ref is prefix followed by nothing but a
+// timestamp or a create key. The shapes with and without a cluster differ by one segment, so
+// a prefix alone is not enough: cluster "grow" with a shrink for namespace
+// "team" would otherwise read as the unscoped grow branch of namespace
+// "shrink", quota "team".
+func hasBranchPrefix(ref, prefix string) bool {
+	rest, ok := strings.CutPrefix(ref, prefix)
+	return ok && createKeyPattern.MatchString(rest)
+}
+
+// unscopedCandidate is an open pull request whose branch matches the
+// namespace/quota but does not name a cluster.
+type unscopedCandidate struct {
+	pr        *github.PullRequest
+	direction string
+}
+
 // FindOpenPR lists open pull requests and returns the number and direction of
 // the one whose head branch matches the deterministic resizer branch prefix
 // for the given namespace/quota. Returns 0 and an empty direction if no
@@ -421,17 +654,48 @@ func (g *GitHubProvider) UpdatePR(ctx context.Context, prID int, quotaName, name
 // the two. Deciding per pull request would hand the outcome to list order and
 // let a namespace adopt a neighbour's pull request, then update, close or
 // merge it.
-func (g *GitHubProvider) FindOpenPR(ctx context.Context, namespace, quotaName string) (int, string, error) {
-	growPrefix := fmt.Sprintf("resize/%s/%s/%s/", DirectionGrow, namespace, quotaName)
-	shrinkPrefix := fmt.Sprintf("resize/%s/%s/%s/", DirectionShrink, namespace, quotaName)
+//
+// Branches without a cluster segment are ambiguous in a second way once a
+// cluster is configured: any cluster sharing the repository may have opened
+// them. See adoptUnscoped for when one is still adopted.
+func (g *GitHubProvider) FindOpenPR(ctx context.Context, namespace, quotaName string, annotations map[string]string) (int, string, error) {
+	growPrefix := g.branchPrefix(DirectionGrow, namespace, quotaName)
+	shrinkPrefix := g.branchPrefix(DirectionShrink, namespace, quotaName)
+	// A batch carries the quota if its body has a section for it.
+	batchGrowPrefix := g.branchPrefix(DirectionGrow, namespace, batchSegment)
+	batchShrinkPrefix := g.branchPrefix(DirectionShrink, namespace, batchSegment)
+	inBatch := func(pr *github.PullRequest) bool {
+		return strings.Contains(pr.GetBody(), quotaSectionStart(quotaName))
+	}
+	// So does a digest, keyed by namespace as well.
+	digestPrefix := g.digestBranchPrefix()
+	inDigest := func(pr *github.PullRequest) bool {
+		return strings.Contains(pr.GetBody(), quotaSectionStart(digestKey(namespace, quotaName)))
+	}
 	legacyPrefix := fmt.Sprintf("resize/%s-%s-", namespace, quotaName)
+	// Branches opened before the cluster segment existed. Without a cluster
+	// they are the current shape and matched above.
+	var unscopedGrow, unscopedShrink string
+	if g.clusterName != "" {
+		unscopedGrow = fmt.Sprintf("resize/%s/%s/%s/", DirectionGrow, namespace, quotaName)
+		unscopedShrink = fmt.Sprintf("resize/%s/%s/%s/", DirectionShrink, namespace, quotaName)
+	}
+	// Pull requests are listed whatever their base: one opened before
+	// GIT_BASE_BRANCH or the base annotation changed is still this quota's,
+	// and hiding it would open a duplicate. It is closed rather than adopted,
+	// since merging it would change the branch the quota no longer comes
+	// from. Without a configured base every base is current.
+	baseBranch, err := g.resolveBaseBranch(namespace, annotations)
+	if err != nil {
+		return 0, "", err
+	}
 	opts := &github.PullRequestListOptions{
 		State:       "open",
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
-	legacyID := 0
-	legacyDirection := ""
+	// Tiers of fallback candidates, most specific first.
+	var unscoped, legacy []unscopedCandidate
 
 	for {
 		prs, resp, err := g.client.PullRequests.List(ctx, g.owner, g.repo, opts)
@@ -443,16 +707,45 @@ func (g *GitHubProvider) FindOpenPR(ctx context.Context, namespace, quotaName st
 				continue
 			}
 			ref := pr.Head.GetRef()
+			stale := baseBranch != "" && pr.GetBase().GetRef() != baseBranch
+			var direction string
 			switch {
-			case strings.HasPrefix(ref, growPrefix):
-				return pr.GetNumber(), DirectionGrow, nil
-			case strings.HasPrefix(ref, shrinkPrefix):
-				return pr.GetNumber(), DirectionShrink, nil
-			case strings.HasPrefix(ref, legacyPrefix):
-				if legacyID == 0 {
-					legacyID = pr.GetNumber()
-					legacyDirection = directionFromLabels(pr.Labels)
+			case hasBranchPrefix(ref, growPrefix):
+				direction = DirectionGrow
+			case hasBranchPrefix(ref, shrinkPrefix):
+				direction = DirectionShrink
+			case hasBranchPrefix(ref, batchGrowPrefix) && inBatch(pr):
+				direction = DirectionGrow
+			case hasBranchPrefix(ref, batchShrinkPrefix) && inBatch(pr):
+				direction = DirectionShrink
+			case hasBranchPrefix(ref, digestPrefix) && inDigest(pr):
+				// A digest serves every namespace on its base, so one
+				// namespace moving away does not close it.
+				if !stale {
+					return pr.GetNumber(), DirectionShrink, nil
 				}
+			case stale:
+				// Branches without a cluster segment predate configurable
+				// bases; one on another base is left alone.
+			case unscopedGrow != "" && hasBranchPrefix(ref, unscopedGrow):
+				unscoped = append(unscoped, unscopedCandidate{pr, DirectionGrow})
+			case unscopedShrink != "" && hasBranchPrefix(ref, unscopedShrink):
+				unscoped = append(unscoped, unscopedCandidate{pr, DirectionShrink})
+			case strings.HasPrefix(ref, legacyPrefix):
+				legacy = append(legacy, unscopedCandidate{pr, directionFromLabels(pr.Labels)})
+			}
+			if direction == "" {
+				continue
+			}
+			if !stale {
+				return pr.GetNumber(), direction, nil
+			}
+			log.FromContext(ctx).Info("Closing a pull request against a previous base branch",
+				"pullRequest", pr.GetNumber(), "base", pr.GetBase().GetRef(), "newBase", baseBranch)
+			comment := fmt.Sprintf("The base branch of namespace %s changed to `%s`. "+
+				"A new pull request will be opened against it.", namespace, baseBranch)
+			if err := g.ClosePR(ctx, pr.GetNumber(), comment); err != nil {
+				return 0, "", err
 			}
 		}
 		if resp.NextPage == 0 {
@@ -460,12 +753,111 @@ func (g *GitHubProvider) FindOpenPR(ctx context.Context, namespace, quotaName st
 		}
 		opts.Page = resp.NextPage
 	}
-	if legacyID != 0 {
-		return legacyID, legacyDirection, nil
+
+	// A tier whose candidates all belong elsewhere gives way to the next.
+	for _, tier := range [][]unscopedCandidate{unscoped, legacy} {
+		if len(tier) == 0 {
+			continue
+		}
+		id, direction, err := g.adoptUnscoped(ctx, tier, namespace, annotations)
+		if err != nil || id != 0 {
+			return id, direction, err
+		}
 	}
 	return 0, "", nil
 }
 
+// adoptUnscoped picks the pull request to adopt from candidates whose branch
+// does not say which cluster opened them.
+//
+// Without a configured cluster there is nothing to tell apart, and the first
+// candidate is adopted as it always was. With one, a candidate is this
+// cluster's only if it carries no other cluster's label and changes a file
+// under the path this cluster resolves for the namespace, and only if exactly
+// one candidate passes. Adopting another cluster's pull request would update,
+// merge or close it from the wrong cluster's observations; leaving it alone
+// costs one duplicate pull request at worst. When the path template does not
+// name the cluster, clusters sharing the repository also share the manifest,
+// and the pull request is as much this cluster's as the other's.
+func (g *GitHubProvider) adoptUnscoped(
+	ctx context.Context,
+	candidates []unscopedCandidate,
+	namespace string,
+	annotations map[string]string,
+) (int, string, error) {
+	if g.clusterName == "" {
+		return candidates[0].pr.GetNumber(), candidates[0].direction, nil
+	}
+
+	basePath, err := g.resolvePath(namespace, annotations)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	var matches []unscopedCandidate
+	for _, candidate := range candidates {
+		if cluster := clusterFromLabels(candidate.pr.Labels); cluster != "" && cluster != g.clusterName {
+			continue
+		}
+		touches, err := g.touchesPath(ctx, candidate.pr.GetNumber(), basePath)
+		if err != nil {
+			return 0, "", err
+		}
+		if touches {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, "", nil
+	case 1:
+		return matches[0].pr.GetNumber(), matches[0].direction, nil
+	default:
+		ids := make([]int, 0, len(matches))
+		for _, match := range matches {
+			ids = append(ids, match.pr.GetNumber())
+		}
+		log.FromContext(ctx).Info("Several pull requests without a cluster in their branch match; adopting none",
+			"namespace", namespace, "pullRequests", ids)
+		return 0, "", nil
+	}
+}
+
+// clusterFromLabels returns the cluster named by a resizer/cluster: label, or
+// "".
+func clusterFromLabels(labels []*github.Label) string {
+	for _, label := range labels {
+		if cluster, ok := strings.CutPrefix(label.GetName(), labelClusterPrefix); ok {
+			return cluster
+		}
+	}
+	return ""
+}
+
+// touchesPath reports whether pull request prID changes a file in basePath or
+// below it.
+func (g *GitHubProvider) touchesPath(ctx context.Context, prID int, basePath string) (bool, error) {
+	dir := strings.TrimSuffix(basePath, "/")
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := g.client.PullRequests.ListFiles(ctx, g.owner, g.repo, prID, opts)
+		if err != nil {
+			return false, fmt.Errorf("failed to list files of PR %d: %w", prID, err)
+		}
+		for _, file := range files {
+			name := file.GetFilename()
+			if dir == "" || strings.HasPrefix(name, dir+"/") {
+				return true, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
 // directionFromLabels reads the direction label and never invents a value it
 // did not recognise.
 //
@@ -514,27 +906,76 @@ func (g *GitHubProvider) ClosePR(ctx context.Context, prID int, comment string)
 	return nil
 }
 
-func (g *GitHubProvider) findQuotaFile(ctx context.Context, basePath, ref, quotaName string) (string, *github.RepositoryContent, error) {
-	// List files in directory
-	_, dirContent, _, err := g.client.Repositories.GetContents(ctx, g.owner, g.repo, basePath, &github.RepositoryContentGetOptions{Ref: ref})
-	if err != nil {
-		// Check if it's a 404
-		var ghErr *github.ErrorResponse
-		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
-			return "", nil, fmt.Errorf("%w: %v", ErrFileNotFound, err)
+// maxSearchDirs bounds how many directories findQuotaFile lists below a
+// discovered source path, each of which costs an API call.
+const maxSearchDirs = 64
+
+// findQuotaFile returns the first manifest under basePath that declares the
+// quota: a YAML or JSON file with a matching ResourceQuota (on its own or
+// inside a List), or a .tf file with a kubernetes_resource_quota resource
+// for namespace/quotaName. The path template names the quota's own
+// directory, so only the files directly in it are read. A path discovered
+// from a GitOps tool is the root of everything the tool deploys, overlays
+// and subdirectories included, so it is searched breadth first, up to
+// maxSearchDirs directories; hidden directories are skipped.
+func (g *GitHubProvider) findQuotaFile(ctx context.Context, basePath, ref, namespace, quotaName string) (string, *github.RepositoryContent, error) {
+	dirs := []string{basePath}
+	var tried []string
+	for listed := 0; len(dirs) > 0 && listed < maxSearchDirs; listed++ {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		_, dirContent, _, err := g.client.Repositories.GetContents(ctx, g.owner, g.repo, dir, &github.RepositoryContentGetOptions{Ref: ref})
+		if err != nil {
+			// Check if it's a 404
+			var ghErr *github.ErrorResponse
+			if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+				if dir == basePath {
+					return "", nil, &UnmappedError{Quota: quotaName, Paths: []string{basePath}, Err: err}
+				}
+				continue
+			}
+			return "", nil, err
+		}
+
+		tried = append(tried, dir)
+		file, fc, found := g.quotaFileIn(ctx, dirContent, ref, namespace, quotaName, &tried)
+		if found {
+			return file, fc, nil
+		}
+		if !g.sourceScoped {
+			break
+		}
+		for _, entry := range dirContent {
+			if entry.GetType() == "dir" && !strings.HasPrefix(entry.GetName(), ".") {
+				dirs = append(dirs, entry.GetPath())
+			}
 		}
-		return "", nil, err
 	}
 
+	return "", nil, &UnmappedError{Quota: quotaName, Paths: tried}
+}
+
+// quotaFileIn reads the manifests among the entries of one directory and
+// returns the first that declares the quota. Every file read is added to
+// tried.
+func (g *GitHubProvider) quotaFileIn(
+	ctx context.Context,
+	dirContent []*github.RepositoryContent,
+	ref, namespace, quotaName string,
+	tried *[]string,
+) (string, *github.RepositoryContent, bool) {
 	for _, file := range dirContent {
 		if file.GetType() != "file" {
 			continue
 		}
-		if !strings.HasSuffix(file.GetName(), ".yaml") && !strings.HasSuffix(file.GetName(), ".yml") {
+		name := file.GetName()
+		isYAML := strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+		if !isYAML && !isTerraformFile(name) && !strings.HasSuffix(name, ".json") {
 			continue
 		}
 
 		// Read file content to check if it contains the Quota
+		*tried = append(*tried, file.GetPath())
 		fc, _, _, err := g.client.Repositories.GetContents(ctx, g.owner, g.repo, file.GetPath(), &github.RepositoryContentGetOptions{Ref: ref})
 		if err != nil {
 			continue
@@ -545,19 +986,238 @@ func (g *GitHubProvider) findQuotaFile(ctx context.Context, basePath, ref, quota
 			continue
 		}
 
+		if isTerraformFile(name) {
+			if terraformDefinesQuota(content, namespace, quotaName) {
+				return file.GetPath(), fc, true
+			}
+			continue
+		}
+		if isJSONManifest(name, content) {
+			if jsonDefinesQuota(content, quotaName) {
+				return file.GetPath(), fc, true
+			}
+			continue
+		}
+
 		// Simple check: Does it contain "kind: ResourceQuota" and "name: <quotaName>"?
 		// This is a heuristic. A proper YAML parser would be better.
 		if strings.Contains(content, "kind: ResourceQuota") && strings.Contains(content, fmt.Sprintf("name: %s", quotaName)) {
-			return file.GetPath(), fc, nil
+			return file.GetPath(), fc, true
+		}
+	}
+	return "", nil, false
+}
+
+// manifestFormat is how a quota is declared in the repository. It decides how
+// the file is edited and what the pull request tells reviewers to do next.
+type manifestFormat string
+
+const (
+	formatYAML      manifestFormat = "yaml"
+	formatJSON      manifestFormat = "json"
+	formatTerraform manifestFormat = "terraform"
+)
+
+// editQuota locates the manifest declaring the quota under basePath on ref and
+// returns every file that has to change to carry limits. An empty result means
+// the repository already holds exactly those limits. Files in pending were
+// already edited for another quota of the same pull request and are edited
+// further instead of being read from ref again.
+func (g *GitHubProvider) editQuota(
+	ctx context.Context,
+	basePath, ref, namespace, quotaName string,
+	limits map[corev1.ResourceName]resource.Quantity,
+	pending []fileEdit,
+) ([]fileEdit, manifestFormat, error) {
+	targetFile, fileContent, err := g.findQuotaFile(ctx, basePath, ref, namespace, quotaName)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return nil, "", err
+	}
+	sha := fileContent.GetSHA()
+	if edit, ok := findEdit(pending, targetFile); ok {
+		content = edit.content
+	}
+
+	if !isTerraformFile(targetFile) {
+		format := formatYAML
+		var newContent string
+		if isJSONManifest(targetFile, content) {
+			format = formatJSON
+			if newContent, err = applyChangesToJSON(content, quotaName, limits); err != nil {
+				return nil, "", fmt.Errorf("failed to edit %s: %w", targetFile, err)
+			}
+		} else {
+			newContent = applyChangesToYaml(content, quotaName, limits)
+		}
+		if newContent == content {
+			return nil, format, nil
 		}
+		return []fileEdit{{path: targetFile, sha: sha, content: newContent}}, format, nil
 	}
 
-	return "", nil, fmt.Errorf("%w: quota %s not found in %s", ErrFileNotFound, quotaName, basePath)
+	module, err := g.loadTerraformModule(ctx, path.Dir(targetFile), ref)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range module {
+		if edit, ok := findEdit(pending, module[i].path); ok {
+			module[i].content = edit.content
+		}
+	}
+	edits, err := applyChangesToTerraform(module, targetFile, namespace, quotaName, limits)
+	if err != nil {
+		return nil, "", err
+	}
+	return edits, formatTerraform, nil
+}
+
+// findEdit returns the edit of path in edits.
+func findEdit(edits []fileEdit, path string) (fileEdit, bool) {
+	for _, edit := range edits {
+		if edit.path == path {
+			return edit, true
+		}
+	}
+	return fileEdit{}, false
+}
+
+// loadTerraformModule reads every .tf and .tfvars file in dir. The quota's
+// spec.hard may refer to locals declared in another file and to variables set
+// in a tfvars file, so the whole module is needed to edit it.
+func (g *GitHubProvider) loadTerraformModule(ctx context.Context, dir, ref string) ([]terraformFile, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: ref}
+	_, dirContent, _, err := g.client.Repositories.GetContents(ctx, g.owner, g.repo, dir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list terraform module %s: %w", dir, err)
+	}
+
+	var files []terraformFile
+	for _, entry := range dirContent {
+		if entry.GetType() != "file" ||
+			(!isTerraformFile(entry.GetName()) && !isTerraformVarsFile(entry.GetName())) {
+			continue
+		}
+		fc, _, _, err := g.client.Repositories.GetContents(ctx, g.owner, g.repo, entry.GetPath(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.GetPath(), err)
+		}
+		content, err := fc.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", entry.GetPath(), err)
+		}
+		files = append(files, terraformFile{path: entry.GetPath(), sha: fc.GetSHA(), content: content})
+	}
+	return files, nil
+}
+
+// commitFiles commits edits to branch. A single file goes through the
+// contents API as it always has; several files — a Terraform quota whose
+// values live in a tfvars file — are written as one commit through the git
+// data API, so the branch never holds a half-applied change.
+func (g *GitHubProvider) commitFiles(ctx context.Context, branch, message string, edits []fileEdit) error {
+	committer := &github.CommitAuthor{Name: github.Ptr("Namespace Resizer"), Email: github.Ptr("bot@resizer.io")}
+
+	if len(edits) == 1 {
+		opts := &github.RepositoryContentFileOptions{
+			Message:   github.Ptr(message),
+			Content:   []byte(edits[0].content),
+			SHA:       github.Ptr(edits[0].sha),
+			Branch:    github.Ptr(branch),
+			Committer: committer,
+		}
+		_, _, err := g.client.Repositories.UpdateFile(ctx, g.owner, g.repo, edits[0].path, opts)
+		return err
+	}
+
+	ref, _, err := g.client.Git.GetRef(ctx, g.owner, g.repo, "refs/heads/"+branch)
+	if err != nil {
+		return fmt.Errorf("failed to get branch %s: %w", branch, err)
+	}
+	parent, _, err := g.client.Git.GetCommit(ctx, g.owner, g.repo, ref.Object.GetSHA())
+	if err != nil {
+		return fmt.Errorf("failed to get head commit of %s: %w", branch, err)
+	}
+
+	entries := make([]*github.TreeEntry, 0, len(edits))
+	for _, edit := range edits {
+		entries = append(entries, &github.TreeEntry{
+			Path:    github.Ptr(edit.path),
+			Mode:    github.Ptr("100644"),
+			Type:    github.Ptr("blob"),
+			Content: github.Ptr(edit.content),
+		})
+	}
+	tree, _, err := g.client.Git.CreateTree(ctx, g.owner, g.repo, parent.GetTree().GetSHA(), entries)
+	if err != nil {
+		return fmt.Errorf("failed to create tree: %w", err)
+	}
+	commit, _, err := g.client.Git.CreateCommit(ctx, g.owner, g.repo, github.Commit{
+		Message:   github.Ptr(message),
+		Tree:      tree,
+		Parents:   []*github.Commit{{SHA: parent.SHA}},
+		Committer: committer,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+	_, _, err = g.client.Git.UpdateRef(ctx, g.owner, g.repo, "refs/heads/"+branch,
+		github.UpdateRef{SHA: commit.GetSHA()})
+	if err != nil {
+		return fmt.Errorf("failed to move %s to the new commit: %w", branch, err)
+	}
+	return nil
 }
 
 // Helper functions
 
-func generatePRBody(ns, quota string, limits map[corev1.ResourceName]resource.Quantity) string {
+// terraformApplyNote tells reviewers that merging alone does not change the
+// cluster: nothing reconciles a Terraform module on its own.
+const terraformApplyNote = "**This quota is managed by Terraform.** Merging this pull request " +
+	"does not change the cluster; run `terraform apply` for the module after the merge " +
+	"to roll the new limits out.\n"
+
+// writeReason renders reason under a heading that reviewers of pull
+// requests and issues alike look for.
+func writeReason(sb *strings.Builder, reason string) {
+	if reason == "" {
+		return
+	}
+	writeList(sb, "Why", strings.Split(reason, "\n"))
+}
+
+// writeExclusions renders the usage the limits leave out. It belongs right
+// below the limits: a reviewer comparing them against a dashboard sees at
+// once which peaks they will not cover.
+func writeExclusions(sb *strings.Builder, exclusions []string) {
+	if len(exclusions) == 0 {
+		return
+	}
+	writeList(sb, "Left out of these limits", exclusions)
+}
+
+// writeList renders lines as a list under heading. sizing.Decision writes
+// its lines as list items already; any other line becomes one.
+func writeList(sb *strings.Builder, heading string, lines []string) {
+	_, _ = fmt.Fprintf(sb, "\n#### %s\n\n", heading)
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "- ") {
+			line = "- " + line
+		}
+		sb.WriteString(line + "\n")
+	}
+}
+
+func generatePRBody(
+	ns, quota string,
+	limits map[corev1.ResourceName]resource.Quantity,
+	format manifestFormat,
+	reason string,
+	exclusions []string,
+) string {
 	var sb strings.Builder
 	_, _ = fmt.Fprintf(&sb, "### Quota Resize Recommendation for `%s` in `%s`\n\n", quota, ns)
 	sb.WriteString("The Namespace Resizer Controller detected a need to increase the following limits:\n\n")
@@ -566,11 +1226,19 @@ func generatePRBody(ns, quota string, limits map[corev1.ResourceName]resource.Qu
 	for res, qty := range limits {
 		_, _ = fmt.Fprintf(&sb, "| %s | %s |\n", res, qty.String())
 	}
+	writeExclusions(&sb, exclusions)
+	writeReason(&sb, reason)
+	if format == formatTerraform {
+		sb.WriteString("\n")
+		sb.WriteString(terraformApplyNote)
+	}
 	sb.WriteString("\n\n*Generated automatically by Namespace Resizer*")
 	return sb.String()
 }
 
-func applyChangesToYaml(content string, limits map[corev1.ResourceName]resource.Quantity) string {
+// applyChangesToYaml updates spec.hard of the ResourceQuota named quotaName.
+// Other quotas declared in the same file are left alone.
+func applyChangesToYaml(content, quotaName string, limits map[corev1.ResourceName]resource.Quantity) string {
 	decoder := yaml.NewDecoder(strings.NewReader(content))
 	var nodes []*yaml.Node
 
@@ -590,6 +1258,9 @@ func applyChangesToYaml(content string, limits map[corev1.ResourceName]resource.
 	// Helper to find a value node for a given key in a mapping node
 	var findValueNode func(n *yaml.Node, key string) *yaml.Node
 	findValueNode = func(n *yaml.Node, key string) *yaml.Node {
+		if n == nil {
+			return nil
+		}
 		if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
 			return findValueNode(n.Content[0], key)
 		}
@@ -603,15 +1274,32 @@ func applyChangesToYaml(content string, limits map[corev1.ResourceName]resource.
 		return nil
 	}
 
-	for _, node := range nodes {
-		// Check Kind
-		kindNode := findValueNode(node, "kind")
-		if kindNode == nil || kindNode.Value != "ResourceQuota" {
-			continue
+	// applyToQuota updates spec.hard of a ResourceQuota and descends into the
+	// items of List wrappers (kind: List or any <Kind>List).
+	var applyToQuota func(n *yaml.Node)
+	applyToQuota = func(n *yaml.Node) {
+		kindNode := findValueNode(n, "kind")
+		if kindNode == nil {
+			return
+		}
+		if kindNode.Value != "ResourceQuota" {
+			if !strings.HasSuffix(kindNode.Value, "List") {
+				return
+			}
+			if items := findValueNode(n, "items"); items != nil && items.Kind == yaml.SequenceNode {
+				for _, item := range items.Content {
+					applyToQuota(item)
+				}
+			}
+			return
+		}
+
+		if name := findValueNode(findValueNode(n, "metadata"), "name"); name == nil || name.Value != quotaName {
+			return
 		}
 
 		// Navigate to spec -> hard
-		specNode := findValueNode(node, "spec")
+		specNode := findValueNode(n, "spec")
 		if specNode != nil {
 			hardNode := findValueNode(specNode, "hard")
 			if hardNode != nil && hardNode.Kind == yaml.MappingNode {
@@ -648,6 +1336,10 @@ func applyChangesToYaml(content string, limits map[corev1.ResourceName]resource.
 		}
 	}
 
+	for _, node := range nodes {
+		applyToQuota(node)
+	}
+
 	var buf bytes.Buffer
 	encoder := yaml.NewEncoder(&buf)
 	encoder.SetIndent(2)
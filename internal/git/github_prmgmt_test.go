@@ -42,6 +42,16 @@ func newTestProvider(t *testing.T, handler http.Handler) (*GitHubProvider, func(
 	return provider, server.Close
 }
 
+// servePRFiles answers the files listing of each pull request with a single
+// file.
+func servePRFiles(mux *http.ServeMux, files map[int]string) {
+	for number, name := range files {
+		mux.HandleFunc(fmt.Sprintf("/repos/o/r/pulls/%d/files", number), func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `[{"filename": %q}]`, name)
+		})
+	}
+}
+
 // TestFindOpenPR verifies that an open PR is matched by its resizer branch prefix.
 func TestFindOpenPR(t *testing.T) {
 	g := NewWithT(t)
@@ -55,16 +65,17 @@ func TestFindOpenPR(t *testing.T) {
 			{"number": 42, "head": {"ref": "resize/default-my-quota-1700000000"}}
 		]`)
 	})
+	servePRFiles(mux, map[int]string{42: "managed-resources/cluster/default/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
 	// Matching namespace/quota -> returns the PR number.
-	id, _, err := provider.FindOpenPR(context.TODO(), "default", "my-quota")
+	id, _, err := provider.FindOpenPR(context.TODO(), "default", "my-quota", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(42))
 
 	// Non-matching quota -> returns 0.
-	id, _, err = provider.FindOpenPR(context.TODO(), "default", "other-quota")
+	id, _, err = provider.FindOpenPR(context.TODO(), "default", "other-quota", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(0))
 }
@@ -81,7 +92,7 @@ func TestFindOpenPR_ListError(t *testing.T) {
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	_, _, err := provider.FindOpenPR(context.TODO(), "default", "my-quota")
+	_, _, err := provider.FindOpenPR(context.TODO(), "default", "my-quota", nil)
 	g.Expect(err).To(HaveOccurred())
 }
 
@@ -125,12 +136,14 @@ func TestUpdatePR_EditPayload(t *testing.T) {
 		corev1.ResourceRequestsCPU: resource.MustParse("2"),
 	}
 
-	err := provider.UpdatePR(context.TODO(), 101, "my-quota", "default", nil, limits)
+	err := provider.WithReason("requests.cpu: 30-day forecast reaches 2", nil).
+		UpdatePR(context.TODO(), 101, "my-quota", "default", nil, limits)
 	g.Expect(err).ToNot(HaveOccurred())
 
 	// The PATCH body must contain the body field but NOT head/base, which the
 	// Edit endpoint cannot accept as objects.
 	g.Expect(patchBody).To(ContainSubstring(`"body"`))
+	g.Expect(patchBody).To(ContainSubstring(`- requests.cpu: 30-day forecast reaches 2`))
 	g.Expect(patchBody).ToNot(ContainSubstring(`"head"`))
 	g.Expect(patchBody).ToNot(ContainSubstring(`"base"`))
 }
@@ -189,10 +202,11 @@ func TestFindOpenPR_ReturnsDirectionFromLabel(t *testing.T) {
 			]
 		}]`)
 	})
+	servePRFiles(mux, map[int]string{42: "managed-resources/cluster/team-a/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute")
+	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
 
 	if err != nil {
 		t.Fatalf("FindOpenPR: %v", err)
@@ -215,10 +229,11 @@ func TestFindOpenPR_DefaultsToGrowWithoutLabel(t *testing.T) {
 			"labels": [{"name": "resizer/managed"}]
 		}]`)
 	})
+	servePRFiles(mux, map[int]string{7: "managed-resources/cluster/team-a/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	_, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute")
+	_, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
 
 	if err != nil {
 		t.Fatalf("FindOpenPR: %v", err)
@@ -297,10 +312,11 @@ func TestFindOpenPR_UnknownDirectionReadsAsShrink(t *testing.T) {
 					"labels": [%s]
 				}]`, strings.Join(names, ", "))
 			})
+			servePRFiles(mux, map[int]string{42: "managed-resources/cluster/team-a/quota.yaml"})
 			provider, teardown := newTestProvider(t, mux)
 			defer teardown()
 
-			_, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute")
+			_, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
 
 			g.Expect(err).ToNot(HaveOccurred())
 			g.Expect(direction).To(Equal(DirectionShrink))
@@ -524,7 +540,7 @@ func TestFindOpenPR_ShrinkSurvivesLabelAndCloseFailure(t *testing.T) {
 	g.Expect(err).To(HaveOccurred())
 	g.Expect(prID).To(Equal(0), "nothing must be persisted on the lease for an unrecovered PR")
 
-	foundID, direction, err := provider.FindOpenPR(context.Background(), "default", "my-quota")
+	foundID, direction, err := provider.FindOpenPR(context.Background(), "default", "my-quota", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(foundID).To(Equal(101))
 	g.Expect(direction).To(Equal(DirectionShrink),
@@ -551,10 +567,11 @@ func TestFindOpenPR_LegacyBranchFallsBackToLabel(t *testing.T) {
 			]
 		}]`)
 	})
+	servePRFiles(mux, map[int]string{9: "managed-resources/cluster/team-a/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute")
+	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
 
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(9))
@@ -579,19 +596,20 @@ func TestFindOpenPR_ShrinkPrefixedNamespaceDoesNotCollideWithSiblingsShrinkBranc
 			"head": {"ref": "resize/shrink-team-compute-1700000000"}
 		}]`)
 	})
+	servePRFiles(mux, map[int]string{77: "managed-resources/cluster/shrink-team/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
 	// A query for namespace "team" must not find a pull request that
 	// belongs to namespace "shrink-team".
-	id, _, err := provider.FindOpenPR(context.Background(), "team", "compute")
+	id, _, err := provider.FindOpenPR(context.Background(), "team", "compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(0),
 		"a pull request belonging to namespace shrink-team must not answer for namespace team")
 
 	// The actual owner, "shrink-team", must still find it and resolve its
 	// direction through the label fallback (none present -> grow).
-	id, direction, err := provider.FindOpenPR(context.Background(), "shrink-team", "compute")
+	id, direction, err := provider.FindOpenPR(context.Background(), "shrink-team", "compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(77))
 	g.Expect(direction).To(Equal(DirectionGrow))
@@ -608,19 +626,20 @@ func TestFindOpenPR_NewShapeAndLegacySiblingBothResolveCorrectly(t *testing.T) {
 	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = fmt.Fprint(w, `[
-			{"number": 201, "head": {"ref": "resize/shrink/team/compute/1700000000"}},
+			{"number": 201, "head": {"ref": "resize/cluster/shrink/team/compute/1700000000"}},
 			{"number": 202, "head": {"ref": "resize/shrink-team-compute-1700000001"}}
 		]`)
 	})
+	servePRFiles(mux, map[int]string{202: "managed-resources/cluster/shrink-team/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	id, direction, err := provider.FindOpenPR(context.Background(), "team", "compute")
+	id, direction, err := provider.FindOpenPR(context.Background(), "team", "compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(201))
 	g.Expect(direction).To(Equal(DirectionShrink))
 
-	id, direction, err = provider.FindOpenPR(context.Background(), "shrink-team", "compute")
+	id, direction, err = provider.FindOpenPR(context.Background(), "shrink-team", "compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(202))
 	g.Expect(direction).To(Equal(DirectionGrow))
@@ -646,13 +665,14 @@ func TestFindOpenPR_OwnNewShapePRBeatsAnAmbiguousLegacyOne(t *testing.T) {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = fmt.Fprint(w, `[
 			{"number": 501, "head": {"ref": "resize/team-a-compute-1700000000"}},
-			{"number": 500, "head": {"ref": "resize/shrink/team/a-compute/1700000001"}}
+			{"number": 500, "head": {"ref": "resize/cluster/shrink/team/a-compute/1700000001"}}
 		]`)
 	})
+	servePRFiles(mux, map[int]string{501: "managed-resources/cluster/team-a/quota.yaml"})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	id, direction, err := provider.FindOpenPR(context.Background(), "team", "a-compute")
+	id, direction, err := provider.FindOpenPR(context.Background(), "team", "a-compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(500),
 		"the namespace's own unambiguous pull request must win over an ambiguous legacy match listed before it")
@@ -660,7 +680,7 @@ func TestFindOpenPR_OwnNewShapePRBeatsAnAmbiguousLegacyOne(t *testing.T) {
 
 	// The legacy pull request still answers for the namespace that really
 	// owns it, which has no new-shape pull request of its own.
-	id, direction, err = provider.FindOpenPR(context.Background(), "team-a", "compute")
+	id, direction, err = provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(501))
 	g.Expect(direction).To(Equal(DirectionGrow))
@@ -677,23 +697,158 @@ func TestFindOpenPR_QuotaNameIsNotMatchedAsAPrefix(t *testing.T) {
 	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = fmt.Fprint(w, `[
-			{"number": 300, "head": {"ref": "resize/grow/team/compute2/1700000000"}}
+			{"number": 300, "head": {"ref": "resize/cluster/grow/team/compute2/1700000000"}}
 		]`)
 	})
 	provider, teardown := newTestProvider(t, mux)
 	defer teardown()
 
-	id, _, err := provider.FindOpenPR(context.Background(), "team", "compute")
+	id, _, err := provider.FindOpenPR(context.Background(), "team", "compute", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(0),
 		"quota compute must not match a branch belonging to quota compute2")
 
 	// The namespace is guarded the same way.
-	id, _, err = provider.FindOpenPR(context.Background(), "tea", "compute2")
+	id, _, err = provider.FindOpenPR(context.Background(), "tea", "compute2", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(0), "namespace tea must not match a branch belonging to namespace team")
 
-	id, _, err = provider.FindOpenPR(context.Background(), "team", "compute2")
+	id, _, err = provider.FindOpenPR(context.Background(), "team", "compute2", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(id).To(Equal(300))
 }
+
+// TestCreatePR_LabelsCarryTheCluster verifies that pull requests of clusters
+// sharing a repository can be told apart by their labels.
+func TestCreatePR_LabelsCarryTheCluster(t *testing.T) {
+	g := NewWithT(t)
+	var labels []string
+	mux := http.NewServeMux()
+	setupCreatePRRoutes(g, mux, 101)
+	mux.HandleFunc("/repos/o/r/issues/101/labels", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(json.NewDecoder(r.Body).Decode(&labels)).To(Succeed())
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	_, err := provider.CreatePR(context.Background(), "my-quota", "default", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(labels).To(ContainElements("resizer/ns:cluster/default", "resizer/cluster:cluster"))
+	g.Expect(provider.branchPrefix(DirectionGrow, "default", "my-quota")).
+		To(Equal("resize/cluster/grow/default/my-quota/"))
+}
+
+// TestFindOpenPR_IgnoresOtherClusters covers two clusters sharing one
+// repository with identical namespace names: neither may adopt the other's
+// pull request.
+func TestFindOpenPR_IgnoresOtherClusters(t *testing.T) {
+	cases := []struct {
+		name   string
+		pr     string
+		files  map[int]string
+		query  [2]string
+		wantID int
+	}{
+		{
+			name: "branch of another cluster",
+			pr:   `{"number": 1, "head": {"ref": "resize/prod-us/grow/team-a/compute/1700000000"}}`,
+		},
+		{
+			name:  "unscoped branch labelled for another cluster",
+			pr:    `{"number": 2, "head": {"ref": "resize/grow/team-a/compute/1700000000"}, "labels": [{"name": "resizer/cluster:prod-us"}]}`,
+			files: map[int]string{2: "managed-resources/cluster/team-a/quota.yaml"},
+		},
+		{
+			name:  "unscoped branch changing another cluster's path",
+			pr:    `{"number": 3, "head": {"ref": "resize/grow/team-a/compute/1700000000"}}`,
+			files: map[int]string{3: "managed-resources/prod-us/team-a/quota.yaml"},
+		},
+		{
+			name:   "unscoped branch changing this cluster's path",
+			pr:     `{"number": 4, "head": {"ref": "resize/shrink/team-a/compute/1700000000"}}`,
+			files:  map[int]string{4: "managed-resources/cluster/team-a/quota.yaml"},
+			wantID: 4,
+		},
+		{
+			// The grow branch of cluster "shrink" for team-a/compute starts
+			// with the unscoped shrink prefix of namespace "grow", quota
+			// "team-a". The extra segment is what tells them apart.
+			name:  "cluster named like a direction",
+			pr:    `{"number": 5, "head": {"ref": "resize/shrink/grow/team-a/compute/1700000000"}}`,
+			files: map[int]string{5: "managed-resources/cluster/grow/quota.yaml"},
+			query: [2]string{"grow", "team-a"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `[%s]`, tc.pr)
+			})
+			servePRFiles(mux, tc.files)
+			provider, teardown := newTestProvider(t, mux)
+			defer teardown()
+
+			if tc.query == [2]string{} {
+				tc.query = [2]string{"team-a", "compute"}
+			}
+			id, _, err := provider.FindOpenPR(context.Background(), tc.query[0], tc.query[1], nil)
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(id).To(Equal(tc.wantID))
+		})
+	}
+}
+
+// TestFindOpenPR_SeveralUnscopedCandidatesAreAmbiguous verifies that no
+// unscoped pull request is adopted when more than one could be this
+// cluster's.
+func TestFindOpenPR_SeveralUnscopedCandidatesAreAmbiguous(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"number": 10, "head": {"ref": "resize/grow/team-a/compute/1700000000"}},
+			{"number": 11, "head": {"ref": "resize/grow/team-a/compute/1700000001"}}
+		]`)
+	})
+	servePRFiles(mux, map[int]string{
+		10: "managed-resources/cluster/team-a/quota.yaml",
+		11: "managed-resources/cluster/team-a/quota.yaml",
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	id, _, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
+
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(id).To(Equal(0))
+}
+
+// TestFindOpenPR_FallsThroughToLegacyBranches verifies that an unscoped pull
+// request of another cluster does not hide this cluster's legacy one.
+func TestFindOpenPR_FallsThroughToLegacyBranches(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"number": 10, "head": {"ref": "resize/grow/team-a/compute/1700000000"}},
+			{"number": 11, "head": {"ref": "resize/team-a-compute-1700000001"}, "labels": [{"name": "resizer/direction:shrink"}]}
+		]`)
+	})
+	servePRFiles(mux, map[int]string{
+		10: "managed-resources/prod-us/team-a/quota.yaml",
+		11: "managed-resources/cluster/team-a/quota.yaml",
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	id, direction, err := provider.FindOpenPR(context.Background(), "team-a", "compute", nil)
+
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(id).To(Equal(11))
+	g.Expect(direction).To(Equal(DirectionShrink))
+}
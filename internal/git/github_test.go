@@ -92,7 +92,7 @@ spec:
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := applyChangesToYaml(tt.input, tt.limits)
+			got := applyChangesToYaml(tt.input, "test", tt.limits)
 			for _, exp := range tt.expected {
 				g.Expect(got).To(ContainSubstring(exp))
 			}
@@ -133,7 +133,7 @@ spec:
 		corev1.ResourceRequestsCPU: resource.MustParse("2"),
 	}
 
-	newContent := applyChangesToYaml(yamlContent, limits)
+	newContent := applyChangesToYaml(yamlContent, "my-quota", limits)
 
 	// Check that Pod cpu is STILL 100m
 	g.Expect(newContent).To(ContainSubstring(`cpu: "100m"`), "Pod CPU should not be changed")
@@ -165,7 +165,7 @@ spec:
 		corev1.ResourceRequestsCPU: resource.MustParse("2"),
 	}
 
-	newContent := applyChangesToYaml(yamlContent, limits)
+	newContent := applyChangesToYaml(yamlContent, "my-pod", limits)
 
 	g.Expect(newContent).To(ContainSubstring(`cpu: "100m"`), "Pod CPU should not be changed")
 	g.Expect(newContent).NotTo(ContainSubstring(`cpu: "2"`), "Pod CPU should not be updated to 2")
@@ -178,12 +178,25 @@ func TestGeneratePRBody(t *testing.T) {
 		corev1.ResourceCPU: resource.MustParse("10"),
 	}
 
-	body := generatePRBody("default", "my-quota", limits)
+	body := generatePRBody("default", "my-quota", limits, formatYAML, "", nil)
 
 	g.Expect(body).To(ContainSubstring("Quota Resize Recommendation"))
 	g.Expect(body).To(ContainSubstring("default"))
 	g.Expect(body).To(ContainSubstring("my-quota"))
 	g.Expect(body).To(ContainSubstring("| cpu | 10 |"))
+	g.Expect(body).NotTo(ContainSubstring("terraform apply"))
+	g.Expect(body).NotTo(ContainSubstring("#### Why"))
+
+	body = generatePRBody("default", "my-quota", limits, formatTerraform, "", nil)
+	g.Expect(body).To(ContainSubstring("terraform apply"))
+
+	body = generatePRBody("default", "my-quota", limits, formatYAML,
+		"- `cpu`: 8 -> 10 (driven by 30-day forecast)\ncpu usage peaked at 7 of 8",
+		[]string{"- `cpu`: left out the peak of 12 on 2026-08-05, far above the median daily peak of 6"})
+	g.Expect(body).To(ContainSubstring(
+		"#### Why\n\n- `cpu`: 8 -> 10 (driven by 30-day forecast)\n- cpu usage peaked at 7 of 8\n"))
+	g.Expect(body).To(ContainSubstring("| cpu | 10 |\n\n#### Left out of these limits\n\n" +
+		"- `cpu`: left out the peak of 12 on 2026-08-05, far above the median daily peak of 6\n"))
 }
 
 func TestGetPRStatus(t *testing.T) {
@@ -305,6 +318,49 @@ func TestCreatePR(t *testing.T) {
 	g.Expect(prID).To(Equal(101))
 }
 
+func TestCreatePR_NothingToChangeDeletesTheBranch(t *testing.T) {
+	g := NewWithT(t)
+
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"default_branch": "main"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"ref": "refs/heads/new-branch"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/refs/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodDelete))
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/repos/o/r/contents/managed-resources/cluster/default", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"name": "quota.yaml", "path": "managed-resources/cluster/default/quota.yaml", "type": "file"}
+		]`)
+	})
+	// The manifest already carries requests.cpu: "1".
+	mux.HandleFunc("/repos/o/r/contents/managed-resources/cluster/default/quota.yaml", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodGet), "nothing may be committed")
+		_, _ = fmt.Fprint(w, `{"content": "a2luZDogUmVzb3VyY2VRdW90YQptZXRhZGF0YToKICBuYW1lOiBteS1xdW90YQpzcGVjOgogIGhhcmQ6CiAgICByZXF1ZXN0cy5jcHU6ICIxIgo=", "encoding": "base64", "sha": "file-sha"}`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no pull request may be opened")
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	_, err := provider.CreatePR(context.TODO(), "my-quota", "default", DirectionGrow, nil,
+		map[corev1.ResourceName]resource.Quantity{corev1.ResourceRequestsCPU: resource.MustParse("1")})
+
+	g.Expect(err).To(MatchError(ContainSubstring("already carries the requested limits")))
+	g.Expect(deleted).To(HaveLen(1))
+	g.Expect(deleted[0]).To(HavePrefix("/repos/o/r/git/refs/heads/resize/cluster/grow/default/my-quota/"))
+}
+
 func TestUpdatePR(t *testing.T) {
 	g := NewWithT(t)
 
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// IdempotentCreator is implemented by providers whose pull request creation
+// can be resumed after it was interrupted half-way.
+type IdempotentCreator interface {
+	// WithCreateKey returns a provider that creates its next pull request
+	// under key. Creating it again with the same key reuses the branch,
+	// commit and pull request an earlier attempt left behind and completes
+	// the missing steps.
+	WithCreateKey(key string) Provider
+}
+
+// CreateKey is the idempotency key of a pull request changing changes of
+// namespace in direction. The same proposal always yields the same key,
+// whatever the order of changes.
+func CreateKey(namespace, direction string, changes []QuotaChange) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", namespace, direction)
+	for _, change := range sortedChanges(changes) {
+		resources := make([]string, 0, len(change.Limits))
+		for res := range change.Limits {
+			resources = append(resources, string(res))
+		}
+		sort.Strings(resources)
+		_, _ = fmt.Fprintf(h, "%s\n", change.Quota)
+		for _, res := range resources {
+			limit := change.Limits[corev1.ResourceName(res)]
+			_, _ = fmt.Fprintf(h, "%s=%s\n", res, limit.String())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// createKeyPattern matches what follows the prefix of a resizer branch: a
+// create key, or the timestamp branches carried before keys existed. It never
+// matches a "/"; see hasBranchPrefix.
+var createKeyPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// WithCreateKey implements IdempotentCreator.
+func (g *GitHubProvider) WithCreateKey(key string) Provider {
+	scoped := *g
+	scoped.createKey = key
+	return &scoped
+}
+
+// createKeyMarker records the key in a pull request's body. It is an HTML
+// comment, so it does not render.
+func createKeyMarker(key string) string { return "\n<!-- resizer:create-key " + key + " -->" }
+
+// keptCreateKeyMarker returns the marker of body, for a rewritten body to
+// keep it.
+func keptCreateKeyMarker(body string) string {
+	start := strings.Index(body, "\n<!-- resizer:create-key ")
+	if start < 0 {
+		return ""
+	}
+	end := strings.Index(body[start:], " -->")
+	if end < 0 {
+		return ""
+	}
+	return body[start : start+end+len(" -->")]
+}
+
+// createBranch creates branch at sha. A branch named after a create key may
+// exist from an interrupted attempt; it is reused, and the commit it points
+// at is returned with resumed set.
+func (g *GitHubProvider) createBranch(ctx context.Context, branch, sha string) (head string, resumed bool, err error) {
+	newRef := github.CreateRef{Ref: "refs/heads/" + branch, SHA: sha}
+	_, _, err = g.client.Git.CreateRef(ctx, g.owner, g.repo, newRef)
+	if err == nil {
+		return sha, false, nil
+	}
+	var ghErr *github.ErrorResponse
+	if g.createKey == "" || !errors.As(err, &ghErr) || ghErr.Response.StatusCode != http.StatusUnprocessableEntity {
+		return "", false, fmt.Errorf("failed to create branch: %w", err)
+	}
+	ref, _, getErr := g.client.Git.GetRef(ctx, g.owner, g.repo, "refs/heads/"+branch)
+	if getErr != nil {
+		return "", false, fmt.Errorf("failed to create branch (%w) and to read it: %w", err, getErr)
+	}
+	return ref.Object.GetSHA(), true, nil
+}
+
+// deleteBranch removes a branch an attempt created but did not commit to. A
+// failure is only logged: the attempt already failed for a reason of its
+// own, and the branch is picked up again by the next attempt with the same
+// create key.
+func (g *GitHubProvider) deleteBranch(ctx context.Context, branch string) {
+	if _, err := g.client.Git.DeleteRef(ctx, g.owner, g.repo, "refs/heads/"+branch); err != nil {
+		log.FromContext(ctx).Error(err, "failed to delete an empty branch", "branch", branch)
+	}
+}
+
+// openPRFor returns the open pull request whose head is branch, or nil.
+func (g *GitHubProvider) openPRFor(ctx context.Context, branch string) (*github.PullRequest, error) {
+	opts := &github.PullRequestListOptions{State: "open", Head: g.owner + ":" + branch}
+	prs, _, err := g.client.PullRequests.List(ctx, g.owner, g.repo, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests of %s: %w", branch, err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return prs[0], nil
+}
@@ -0,0 +1,202 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCreateKey(t *testing.T) {
+	g := NewWithT(t)
+	cpu := func(v string) map[corev1.ResourceName]resource.Quantity {
+		return map[corev1.ResourceName]resource.Quantity{corev1.ResourceRequestsCPU: resource.MustParse(v)}
+	}
+	a := QuotaChange{Quota: "a", Limits: cpu("2")}
+	b := QuotaChange{Quota: "b", Limits: cpu("4")}
+
+	key := CreateKey("team", DirectionGrow, []QuotaChange{a, b})
+	g.Expect(key).To(MatchRegexp(`^[0-9a-f]{16}$`))
+	g.Expect(CreateKey("team", DirectionGrow, []QuotaChange{b, a})).To(Equal(key), "order must not matter")
+	g.Expect(CreateKey("team", DirectionShrink, []QuotaChange{a, b})).NotTo(Equal(key))
+	g.Expect(CreateKey("other", DirectionGrow, []QuotaChange{a, b})).NotTo(Equal(key))
+	g.Expect(CreateKey("team", DirectionGrow, []QuotaChange{a, {Quota: "b", Limits: cpu("5")}})).NotTo(Equal(key))
+}
+
+// setupResumeRoutes serves a repository where an interrupted attempt already
+// created the branch of key. committed says whether it also got the commit
+// in; openPR, when non-zero, is the pull request it opened.
+func setupResumeRoutes(g *WithT, mux *http.ServeMux, key string, committed bool, openPR int) (puts, creates *int) {
+	puts, creates = new(int), new(int)
+	branch := "resize/cluster/grow/default/my-quota/" + key
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"default_branch": "main"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		var ref struct {
+			Ref string `json:"ref"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&ref)).To(Succeed())
+		g.Expect(ref.Ref).To(Equal("refs/heads/" + branch))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = fmt.Fprint(w, `{"message": "Reference already exists"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/"+branch, func(w http.ResponseWriter, r *http.Request) {
+		sha := "base-sha"
+		if committed {
+			sha = "commit-sha"
+		}
+		_, _ = fmt.Fprintf(w, `{"object": {"sha": %q}}`, sha)
+	})
+	mux.HandleFunc("/repos/o/r/contents/managed-resources/cluster/default", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{"name": "quota.yaml", "path": "managed-resources/cluster/default/quota.yaml", "type": "file"}]`)
+	})
+	mux.HandleFunc("/repos/o/r/contents/managed-resources/cluster/default/quota.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			*puts++
+			_, _ = fmt.Fprint(w, `{"commit": {"sha": "commit-sha"}}`)
+			return
+		}
+		g.Expect(r.URL.Query().Get("ref")).To(Equal(branch))
+		content := "kind: ResourceQuota\nmetadata:\n  name: my-quota\nspec:\n  hard:\n    requests.cpu: 1"
+		if committed {
+			content = "kind: ResourceQuota\nmetadata:\n  name: my-quota\nspec:\n  hard:\n    requests.cpu: \"2\"\n"
+		}
+		_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64", "sha": "file-sha"}`,
+			base64.StdEncoding.EncodeToString([]byte(content)))
+	})
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			g.Expect(r.URL.Query().Get("head")).To(Equal("o:" + branch))
+			if openPR != 0 {
+				_, _ = fmt.Fprintf(w, `[{"number": %d}]`, openPR)
+				return
+			}
+			_, _ = fmt.Fprint(w, `[]`)
+			return
+		}
+		*creates++
+		var pr struct {
+			Head string `json:"head"`
+			Body string `json:"body"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&pr)).To(Succeed())
+		g.Expect(pr.Head).To(Equal(branch))
+		g.Expect(pr.Body).To(ContainSubstring("<!-- resizer:create-key " + key + " -->"))
+		_, _ = fmt.Fprint(w, `{"number": 101}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(strings.HasSuffix(r.URL.Path, "/labels")).To(BeTrue(), r.URL.Path)
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	return puts, creates
+}
+
+func TestCreatePR_ResumesBranchWithoutCommit(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	puts, creates := setupResumeRoutes(g, mux, "0123456789abcdef", false, 0)
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	keyed := provider.WithCreateKey("0123456789abcdef")
+	prID, err := keyed.CreatePR(context.Background(), "my-quota", "default", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(101))
+	g.Expect(*puts).To(Equal(1))
+	g.Expect(*creates).To(Equal(1))
+}
+
+func TestCreatePR_ResumesCommittedBranch(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	puts, creates := setupResumeRoutes(g, mux, "0123456789abcdef", true, 0)
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	keyed := provider.WithCreateKey("0123456789abcdef")
+	prID, err := keyed.CreatePR(context.Background(), "my-quota", "default", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(101))
+	g.Expect(*puts).To(Equal(0), "the commit is already on the branch")
+	g.Expect(*creates).To(Equal(1))
+}
+
+func TestCreatePR_ResumesOpenedPR(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	puts, creates := setupResumeRoutes(g, mux, "0123456789abcdef", true, 7)
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	keyed := provider.WithCreateKey("0123456789abcdef")
+	prID, err := keyed.CreatePR(context.Background(), "my-quota", "default", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(7))
+	g.Expect(*puts).To(Equal(0))
+	g.Expect(*creates).To(Equal(0))
+}
+
+func TestHasBranchPrefix_CreateKey(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(hasBranchPrefix("resize/cluster/grow/default/my-quota/0123456789abcdef",
+		"resize/cluster/grow/default/my-quota/")).To(BeTrue())
+	g.Expect(hasBranchPrefix("resize/cluster/grow/default/my-quota/1767225600",
+		"resize/cluster/grow/default/my-quota/")).To(BeTrue(), "timestamped branches still match")
+	g.Expect(hasBranchPrefix("resize/grow/shrink/team/compute/0123456789abcdef",
+		"resize/grow/shrink/team/")).To(BeFalse())
+}
+
+// TestCreatePR_FailedResumeKeepsCommittedBranch checks what a failed attempt
+// leaves behind on a resumed branch: one that still points at the base is
+// deleted like a fresh one, one carrying an earlier attempt's commit is kept
+// for the next attempt with the same key.
+func TestCreatePR_FailedResumeKeepsCommittedBranch(t *testing.T) {
+	for _, committed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("committed=%t", committed), func(t *testing.T) {
+			g := NewWithT(t)
+			inner := http.NewServeMux()
+			setupResumeRoutes(g, inner, "0123456789abcdef", committed, 0)
+			var deleted []string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPut:
+					w.WriteHeader(http.StatusInternalServerError)
+				case http.MethodDelete:
+					deleted = append(deleted, r.URL.Path)
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					inner.ServeHTTP(w, r)
+				}
+			})
+			provider, teardown := newTestProvider(t, mux)
+			defer teardown()
+
+			keyed := provider.WithCreateKey("0123456789abcdef")
+			_, err := keyed.CreatePR(context.Background(), "my-quota", "default", DirectionGrow, nil,
+				map[corev1.ResourceName]resource.Quantity{corev1.ResourceRequestsCPU: resource.MustParse("3")})
+
+			g.Expect(err).To(MatchError(ContainSubstring("failed to commit file")))
+			if committed {
+				g.Expect(deleted).To(BeEmpty())
+			} else {
+				g.Expect(deleted).To(ConsistOf(
+					"/repos/o/r/git/refs/heads/resize/cluster/grow/default/my-quota/0123456789abcdef"))
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// IssueTracker is implemented by providers that propose changes as issues
+// instead of pull requests. Nothing merges an issue: the controller resolves
+// it once the cluster carries the recommendation.
+type IssueTracker interface {
+	// ResolveIssue posts comment and closes the issue as completed.
+	ResolveIssue(ctx context.Context, id int, comment string) error
+}
+
+// Explainer is implemented by providers that show reviewers why a change is
+// proposed: GitHubProvider in the pull request body, GitHubIssueProvider in
+// the issue.
+type Explainer interface {
+	// WithReason returns a provider whose proposals carry reason and, next
+	// to the limits, the usage the limits leave out.
+	WithReason(reason string, exclusions []string) Provider
+}
+
+// labelIssue marks the issues opened in issue mode, which FindOpenPR lists by
+// it.
+const labelIssue = "resizer/issue"
+
+// errIssueMode is returned for the operations an issue has no equivalent of.
+var errIssueMode = errors.New("not supported in issue mode")
+
+// GitHubIssueProvider opens one GitHub issue per quota with the recommended
+// limits instead of a pull request against the manifests. It satisfies
+// Provider so the controller's lock, cooldown and shrink gates apply
+// unchanged; issue numbers take the place of pull request numbers.
+type GitHubIssueProvider struct {
+	gh *GitHubProvider
+}
+
+// Issues returns a provider that files issues in the same repository, with
+// the same credentials, instead of opening pull requests.
+func (g *GitHubProvider) Issues() *GitHubIssueProvider {
+	return &GitHubIssueProvider{gh: g}
+}
+
+// Repository returns the "owner/repo" issues are opened in.
+func (p *GitHubIssueProvider) Repository() string {
+	return p.gh.Repository()
+}
+
+// WithSource files the issues in the discovered repository.
+func (p *GitHubIssueProvider) WithSource(src Source) (Provider, error) {
+	scoped, err := p.gh.WithSource(src)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubIssueProvider{gh: scoped.(*GitHubProvider)}, nil
+}
+
+// WithReason implements Explainer.
+func (g *GitHubProvider) WithReason(reason string, exclusions []string) Provider {
+	scoped := *g
+	scoped.reason = reason
+	scoped.exclusions = exclusions
+	return &scoped
+}
+
+// WithReason implements Explainer.
+func (p *GitHubIssueProvider) WithReason(reason string, exclusions []string) Provider {
+	scoped := *p
+	scoped.gh = p.gh.WithReason(reason, exclusions).(*GitHubProvider)
+	return &scoped
+}
+
+// WithReviewers implements ReviewerAssigner. An issue cannot be reviewed;
+// only the assignees apply to it.
+func (p *GitHubIssueProvider) WithReviewers(reviewers Reviewers) Provider {
+	scoped := *p
+	scoped.gh = p.gh.WithReviewers(reviewers).(*GitHubProvider)
+	return &scoped
+}
+
+// GetPRStatus reports an issue closed as completed as merged: whoever closed
+// it says the recommendation was applied. An issue is never mergeable.
+func (p *GitHubIssueProvider) GetPRStatus(ctx context.Context, prID int) (*PRStatus, error) {
+	issue, _, err := p.gh.client.Issues.Get(ctx, p.gh.owner, p.gh.repo, prID)
+	if err != nil {
+		return nil, err
+	}
+	return &PRStatus{
+		IsOpen:    issue.GetState() == "open",
+		IsMerged:  issue.GetState() == "closed" && issue.GetStateReason() == "completed",
+		CreatedAt: issue.GetCreatedAt().Time,
+	}, nil
+}
+
+func (p *GitHubIssueProvider) MergePR(ctx context.Context, prID int, method string) error {
+	return fmt.Errorf("merging issue %d: %w", prID, errIssueMode)
+}
+
+func (p *GitHubIssueProvider) CreatePR(ctx context.Context, quotaName, namespace, direction string, annotations map[string]string, newLimits map[corev1.ResourceName]resource.Quantity) (int, error) {
+	title := fmt.Sprintf("Resize Quota %s in %s", quotaName, namespace)
+	if direction == DirectionShrink {
+		title = fmt.Sprintf("Shrink Quota %s in %s", quotaName, namespace)
+	}
+	// Labels are part of the same call, so unlike a pull request's they
+	// cannot be missing on an issue that exists.
+	labels := append(p.gh.prLabels(direction, namespace), labelIssue)
+	request := &github.IssueRequest{
+		Title:  github.Ptr(title),
+		Body:   github.Ptr(generateIssueBody(namespace, quotaName, direction, newLimits, p.gh.reason, p.gh.exclusions)),
+		Labels: &labels,
+	}
+	if assignees := p.gh.reviewers.Assignees; len(assignees) > 0 {
+		request.Assignees = &assignees
+	}
+	issue, _, err := p.gh.client.Issues.Create(ctx, p.gh.owner, p.gh.repo, request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return issue.GetNumber(), nil
+}
+
+// UpdatePR rewrites the issue's recommendation when it changed.
+func (p *GitHubIssueProvider) UpdatePR(ctx context.Context, prID int, quotaName, namespace string, annotations map[string]string, newLimits map[corev1.ResourceName]resource.Quantity) error {
+	issue, _, err := p.gh.client.Issues.Get(ctx, p.gh.owner, p.gh.repo, prID)
+	if err != nil {
+		return err
+	}
+	direction := directionFromLabels(issue.Labels)
+	body := generateIssueBody(namespace, quotaName, direction, newLimits, p.gh.reason, p.gh.exclusions)
+	if body == issue.GetBody() {
+		return nil
+	}
+	_, _, err = p.gh.client.Issues.Edit(ctx, p.gh.owner, p.gh.repo, prID, &github.IssueRequest{Body: github.Ptr(body)})
+	if err != nil {
+		return fmt.Errorf("failed to update issue body: %w", err)
+	}
+	return nil
+}
+
+// FindOpenPR returns the open issue filed for namespace/quota by this
+// cluster. The quota is identified by a marker in the body, the cluster by
+// its label.
+func (p *GitHubIssueProvider) FindOpenPR(ctx context.Context, namespace, quotaName string, annotations map[string]string) (int, string, error) {
+	marker := quotaSectionStart(digestKey(namespace, quotaName))
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{labelManaged, labelIssue},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := p.gh.client.Issues.ListByRepo(ctx, p.gh.owner, p.gh.repo, opts)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to list issues: %w", err)
+		}
+		for _, issue := range issues {
+			if issue.IsPullRequest() || !strings.Contains(issue.GetBody(), marker) {
+				continue
+			}
+			if clusterFromLabels(issue.Labels) != p.gh.clusterName {
+				continue
+			}
+			return issue.GetNumber(), directionFromLabels(issue.Labels), nil
+		}
+		if resp.NextPage == 0 {
+			return 0, "", nil
+		}
+		opts.ListOptions.Page = resp.NextPage
+	}
+}
+
+// ClosePR closes the issue as not planned.
+func (p *GitHubIssueProvider) ClosePR(ctx context.Context, prID int, comment string) error {
+	return p.closeIssue(ctx, prID, comment, "not_planned")
+}
+
+// ResolveIssue implements IssueTracker.
+func (p *GitHubIssueProvider) ResolveIssue(ctx context.Context, id int, comment string) error {
+	return p.closeIssue(ctx, id, comment, "completed")
+}
+
+func (p *GitHubIssueProvider) closeIssue(ctx context.Context, id int, comment, reason string) error {
+	return p.gh.closeIssue(ctx, id, comment, reason)
+}
+
+// closeIssue posts comment and closes issue id with reason, "completed" or
+// "not_planned".
+func (g *GitHubProvider) closeIssue(ctx context.Context, id int, comment, reason string) error {
+	body := &github.IssueComment{Body: github.Ptr(comment)}
+	if _, _, err := g.client.Issues.CreateComment(ctx, g.owner, g.repo, id, body); err != nil {
+		return fmt.Errorf("failed to comment on issue %d: %w", id, err)
+	}
+	update := &github.IssueRequest{State: github.Ptr("closed"), StateReason: github.Ptr(reason)}
+	if _, _, err := g.client.Issues.Edit(ctx, g.owner, g.repo, id, update); err != nil {
+		return fmt.Errorf("failed to close issue %d: %w", id, err)
+	}
+	return nil
+}
+
+// CreateBatchPR is not supported: issue mode files one issue per quota, and
+// the controller does not batch for it.
+func (p *GitHubIssueProvider) CreateBatchPR(ctx context.Context, namespace, direction string, annotations map[string]string, changes []QuotaChange) (int, error) {
+	return 0, fmt.Errorf("batching quotas of %s: %w", namespace, errIssueMode)
+}
+
+// CreateDigestPR is not supported, for the same reason as CreateBatchPR.
+func (p *GitHubIssueProvider) CreateDigestPR(ctx context.Context, baseBranch string, entries []DigestEntry) (int, []DigestEntry, error) {
+	return 0, nil, fmt.Errorf("shrink digest: %w", errIssueMode)
+}
+
+func generateIssueBody(
+	ns, quota, direction string,
+	limits map[corev1.ResourceName]resource.Quantity,
+	reason string,
+	exclusions []string,
+) string {
+	verb := "increasing"
+	if direction == DirectionShrink {
+		verb = "decreasing"
+	}
+	var sb strings.Builder
+	sb.WriteString(quotaSectionStart(digestKey(ns, quota)) + "\n")
+	_, _ = fmt.Fprintf(&sb, "### Quota Resize Recommendation for `%s` in `%s`\n\n", quota, ns)
+	_, _ = fmt.Fprintf(&sb, "The Namespace Resizer Controller recommends %s the following limits:\n\n", verb)
+	sb.WriteString("| Resource | Recommended Limit |\n")
+	sb.WriteString("| :--- | :--- |\n")
+	resources := sortedResources(limits)
+	for _, res := range resources {
+		qty := limits[res]
+		_, _ = fmt.Fprintf(&sb, "| %s | %s |\n", res, qty.String())
+	}
+	writeExclusions(&sb, exclusions)
+	writeReason(&sb, reason)
+
+	sb.WriteString("\n#### How to Apply\n\n")
+	sb.WriteString("Change the quota's manifest in Git to carry these limits. Patching the live " +
+		"ResourceQuota would only be reverted by the next sync:\n\n")
+	sb.WriteString("```yaml\nspec:\n  hard:\n")
+	for _, res := range resources {
+		qty := limits[res]
+		_, _ = fmt.Fprintf(&sb, "    %s: %q\n", res, qty.String())
+	}
+	sb.WriteString("```\n\n")
+	sb.WriteString("This issue closes automatically once the quota's `spec.hard` carries these limits.\n")
+	sb.WriteString("\n*Generated automatically by Namespace Resizer*")
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestIssueProvider_CreatePR(t *testing.T) {
+	g := NewWithT(t)
+
+	var request struct {
+		Title  string   `json:"title"`
+		Body   string   `json:"body"`
+		Labels []string `json:"labels"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodPost))
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+		_, _ = fmt.Fprint(w, `{"number": 31}`)
+	})
+	gh, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	provider := gh.Issues().WithReason("cpu usage peaked at 7 of 8",
+		[]string{"- `requests.cpu`: left out up to 3 used by workloads ignored in sizing, on 2026-08-05"})
+	id, err := provider.CreatePR(context.Background(), "compute", "team-a", DirectionGrow, nil,
+		map[corev1.ResourceName]resource.Quantity{corev1.ResourceRequestsCPU: resource.MustParse("10")})
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(31))
+	g.Expect(request.Title).To(Equal("Resize Quota compute in team-a"))
+	g.Expect(request.Labels).To(ContainElements(labelManaged, labelIssue,
+		labelDirectionPrefix+DirectionGrow, labelClusterPrefix+"cluster"))
+	g.Expect(request.Body).To(ContainSubstring(quotaSectionStart(digestKey("team-a", "compute"))))
+	g.Expect(request.Body).To(ContainSubstring("- cpu usage peaked at 7 of 8"))
+	g.Expect(request.Body).To(ContainSubstring("#### Left out of these limits\n\n" +
+		"- `requests.cpu`: left out up to 3 used by workloads ignored in sizing, on 2026-08-05\n"))
+	g.Expect(request.Body).To(ContainSubstring("spec:\n  hard:\n    requests.cpu: \"10\"\n"))
+	g.Expect(request.Body).To(ContainSubstring("Change the quota's manifest in Git"))
+	g.Expect(request.Body).NotTo(ContainSubstring("kubectl"), "a patched live quota is reverted by the next sync")
+}
+
+func TestIssueProvider_GetPRStatus(t *testing.T) {
+	cases := map[string]struct {
+		issue        string
+		open, merged bool
+	}{
+		"open":         {`{"state": "open"}`, true, false},
+		"completed":    {`{"state": "closed", "state_reason": "completed"}`, false, true},
+		"not planned":  {`{"state": "closed", "state_reason": "not_planned"}`, false, false},
+		"closed, bare": {`{"state": "closed"}`, false, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/o/r/issues/31", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, tc.issue)
+			})
+			gh, teardown := newTestProvider(t, mux)
+			defer teardown()
+
+			status, err := gh.Issues().GetPRStatus(context.Background(), 31)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(status.IsOpen).To(Equal(tc.open))
+			g.Expect(status.IsMerged).To(Equal(tc.merged))
+			g.Expect(status.Mergeable).To(BeFalse())
+		})
+	}
+}
+
+func TestIssueProvider_FindOpenPR(t *testing.T) {
+	g := NewWithT(t)
+
+	body, _ := json.Marshal(generateIssueBody("team-a", "compute", DirectionShrink,
+		map[corev1.ResourceName]resource.Quantity{corev1.ResourceRequestsCPU: resource.MustParse("4")}, "", nil))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.URL.Query().Get("labels")).To(Equal(labelManaged + "," + labelIssue))
+		// 40 is a pull request carrying the labels, 41 another cluster's issue.
+		_, _ = fmt.Fprintf(w, `[
+			{"number": 40, "body": %[1]s, "pull_request": {"url": "x"}, "labels": [{"name": "resizer/cluster:cluster"}]},
+			{"number": 41, "body": %[1]s, "labels": [{"name": "resizer/cluster:other"}]},
+			{"number": 42, "body": %[1]s, "labels": [{"name": "resizer/cluster:cluster"}, {"name": "resizer/direction:shrink"}]}
+		]`, body)
+	})
+	gh, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	id, direction, err := gh.Issues().FindOpenPR(context.Background(), "team-a", "compute", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(42))
+	g.Expect(direction).To(Equal(DirectionShrink))
+
+	id, _, err = gh.Issues().FindOpenPR(context.Background(), "team-b", "compute", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(0))
+}
+
+func TestIssueProvider_ResolveAndClose(t *testing.T) {
+	for reason, closeIssue := range map[string]func(*GitHubIssueProvider) error{
+		"completed":   func(p *GitHubIssueProvider) error { return p.ResolveIssue(context.Background(), 31, "applied") },
+		"not_planned": func(p *GitHubIssueProvider) error { return p.ClosePR(context.Background(), 31, "expired") },
+	} {
+		t.Run(reason, func(t *testing.T) {
+			g := NewWithT(t)
+			commented := false
+			var edit struct {
+				State       string `json:"state"`
+				StateReason string `json:"state_reason"`
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/o/r/issues/31/comments", func(w http.ResponseWriter, r *http.Request) {
+				commented = true
+				_, _ = fmt.Fprint(w, `{"id": 1}`)
+			})
+			mux.HandleFunc("/repos/o/r/issues/31", func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Method).To(Equal(http.MethodPatch))
+				g.Expect(json.NewDecoder(r.Body).Decode(&edit)).To(Succeed())
+				_, _ = fmt.Fprint(w, `{"number": 31}`)
+			})
+			gh, teardown := newTestProvider(t, mux)
+			defer teardown()
+
+			g.Expect(closeIssue(gh.Issues())).To(Succeed())
+			g.Expect(commented).To(BeTrue())
+			g.Expect(edit.State).To(Equal("closed"))
+			g.Expect(edit.StateReason).To(Equal(reason))
+		})
+	}
+}
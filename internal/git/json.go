@@ -0,0 +1,269 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// jsonValue is a parsed JSON value together with the byte ranges it occupies
+// in the source. Only the ranges are used for editing; the source is never
+// re-marshalled, which is what keeps key order, indentation and the spelling
+// of every untouched number intact.
+type jsonValue struct {
+	start, end int
+	// delim is '{' or '[' for composite values and 0 for scalars.
+	delim   json.Delim
+	keys    []string
+	members []*jsonValue
+	items   []*jsonValue
+	scalar  any
+
+	// keyStart and keyEnd locate the member's key when the value sits in
+	// an object.
+	keyStart, keyEnd int
+}
+
+// member returns the value stored under key, or nil.
+func (v *jsonValue) member(key string) *jsonValue {
+	if v == nil || v.delim != '{' {
+		return nil
+	}
+	for i, k := range v.keys {
+		if k == key {
+			return v.members[i]
+		}
+	}
+	return nil
+}
+
+// str returns the value as a string if it is a JSON string.
+func (v *jsonValue) str() string {
+	if v == nil {
+		return ""
+	}
+	s, _ := v.scalar.(string)
+	return s
+}
+
+// isJSONManifest reports whether a manifest is JSON. The content decides, not
+// the extension: JSON is valid YAML, and generators happily write it into
+// .yaml files, which the YAML encoder would then rewrite in block style.
+func isJSONManifest(name, content string) bool {
+	if strings.HasSuffix(name, ".json") {
+		return true
+	}
+	trimmed := strings.TrimSpace(content)
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+}
+
+func parseJSONSpans(src []byte) (*jsonValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	var parse func() (*jsonValue, error)
+	parse = func() (*jsonValue, error) {
+		start := skipJSONSeparators(src, int(dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return &jsonValue{start: start, end: int(dec.InputOffset()), scalar: tok}, nil
+		}
+
+		v := &jsonValue{start: start, delim: delim}
+		for dec.More() {
+			if delim == '[' {
+				item, err := parse()
+				if err != nil {
+					return nil, err
+				}
+				v.items = append(v.items, item)
+				continue
+			}
+			keyStart := skipJSONSeparators(src, int(dec.InputOffset()))
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			keyEnd := int(dec.InputOffset())
+			member, err := parse()
+			if err != nil {
+				return nil, err
+			}
+			member.keyStart, member.keyEnd = keyStart, keyEnd
+			v.keys = append(v.keys, keyTok.(string))
+			v.members = append(v.members, member)
+		}
+		// Closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		v.end = int(dec.InputOffset())
+		return v, nil
+	}
+
+	root, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return root, nil
+}
+
+// skipJSONSeparators advances past whitespace and the ',' and ':' the decoder
+// consumes lazily, so offset lands on the first byte of the next token.
+func skipJSONSeparators(src []byte, offset int) int {
+	for offset < len(src) {
+		switch src[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// jsonQuotas collects every ResourceQuota object in a manifest: the top-level
+// object itself, or the items of a List wrapper (kind: List or any
+// <Kind>List), nested to any depth.
+func jsonQuotas(v *jsonValue) []*jsonValue {
+	if v == nil || v.delim != '{' {
+		return nil
+	}
+	kind := v.member("kind").str()
+	if kind == "ResourceQuota" {
+		return []*jsonValue{v}
+	}
+	if !strings.HasSuffix(kind, "List") {
+		return nil
+	}
+	var quotas []*jsonValue
+	if items := v.member("items"); items != nil && items.delim == '[' {
+		for _, item := range items.items {
+			quotas = append(quotas, jsonQuotas(item)...)
+		}
+	}
+	return quotas
+}
+
+// jsonDefinesQuota reports whether a JSON manifest declares the named quota.
+func jsonDefinesQuota(content, quotaName string) bool {
+	root, err := parseJSONSpans([]byte(content))
+	if err != nil {
+		return false
+	}
+	for _, quota := range jsonQuotas(root) {
+		if quota.member("metadata").member("name").str() == quotaName {
+			return true
+		}
+	}
+	return false
+}
+
+// applyChangesToJSON updates spec.hard of the ResourceQuota named quotaName in
+// a JSON manifest by rewriting only the affected byte ranges. A number stays a
+// number as long as the new value is an integer; new keys are appended in the
+// style of the existing ones.
+func applyChangesToJSON(content, quotaName string, limits map[corev1.ResourceName]resource.Quantity) (string, error) {
+	src := []byte(content)
+	root, err := parseJSONSpans(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse JSON manifest: %w", err)
+	}
+
+	resources := make([]string, 0, len(limits))
+	for res := range limits {
+		resources = append(resources, string(res))
+	}
+	sort.Strings(resources)
+
+	var reps []byteReplacement
+	for _, quota := range jsonQuotas(root) {
+		if quota.member("metadata").member("name").str() != quotaName {
+			continue
+		}
+		hard := quota.member("spec").member("hard")
+		if hard == nil || hard.delim != '{' {
+			continue
+		}
+		var missing []string
+		for _, name := range resources {
+			res := corev1.ResourceName(name)
+			qty := limits[res]
+			found := false
+			for i, key := range hard.keys {
+				if !matchesResourceKey(key, res) {
+					continue
+				}
+				found = true
+				val := hard.members[i]
+				reps = append(reps, byteReplacement{start: val.start, end: val.end, text: jsonQuantity(val, qty)})
+			}
+			if !found {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			reps = append(reps, jsonInsertion(src, hard, missing, limits))
+		}
+	}
+
+	sort.Slice(reps, func(i, j int) bool { return reps[i].start > reps[j].start })
+	for _, rep := range reps {
+		src = append(src[:rep.start], append([]byte(rep.text), src[rep.end:]...)...)
+	}
+	return string(src), nil
+}
+
+func jsonQuantity(old *jsonValue, qty resource.Quantity) string {
+	if _, isNumber := old.scalar.(json.Number); isNumber {
+		if _, err := strconv.ParseInt(qty.String(), 10, 64); err == nil {
+			return qty.String()
+		}
+	}
+	return strconv.Quote(qty.String())
+}
+
+// jsonInsertion appends names to the hard object after its last member,
+// copying that member's indentation and key/value separator so pretty-printed
+// and compact manifests both keep their shape.
+func jsonInsertion(
+	src []byte,
+	hard *jsonValue,
+	names []string,
+	limits map[corev1.ResourceName]resource.Quantity,
+) byteReplacement {
+	pairs := make([]string, 0, len(names))
+	if len(hard.members) == 0 {
+		for _, name := range names {
+			qty := limits[corev1.ResourceName(name)]
+			pairs = append(pairs, fmt.Sprintf("%q: %q", name, qty.String()))
+		}
+		return byteReplacement{start: hard.start + 1, end: hard.start + 1, text: strings.Join(pairs, ", ")}
+	}
+
+	last := hard.members[len(hard.members)-1]
+	sep := string(src[last.keyEnd:last.start])
+	prefix := ", "
+	if bytes.ContainsRune(src[hard.start:last.keyStart], '\n') {
+		prefix = ",\n" + lineIndent(src, last.keyStart)
+	}
+	var sb strings.Builder
+	for _, name := range names {
+		qty := limits[corev1.ResourceName(name)]
+		_, _ = fmt.Fprintf(&sb, "%s%q%s%q", prefix, name, sep, qty.String())
+	}
+	return byteReplacement{start: last.end, end: last.end, text: sb.String()}
+}
@@ -0,0 +1,177 @@
+package git
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestApplyChangesToJSON_PreservesLayout(t *testing.T) {
+	g := NewWithT(t)
+	content := `{
+  "apiVersion": "v1",
+  "kind": "ResourceQuota",
+  "metadata": {"name": "my-quota"},
+  "spec": {
+    "hard": {
+      "requests.cpu": "1",
+      "pods": 10,
+      "requests.memory": 1.5e9
+    }
+  }
+}
+`
+	limits := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceRequestsCPU: resource.MustParse("2"),
+		corev1.ResourcePods:        resource.MustParse("20"),
+	}
+
+	got, err := applyChangesToJSON(content, "my-quota", limits)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(Equal(`{
+  "apiVersion": "v1",
+  "kind": "ResourceQuota",
+  "metadata": {"name": "my-quota"},
+  "spec": {
+    "hard": {
+      "requests.cpu": "2",
+      "pods": 20,
+      "requests.memory": 1.5e9
+    }
+  }
+}
+`))
+}
+
+func TestApplyChangesToJSON_InsertsKeys(t *testing.T) {
+	cases := []struct {
+		name, hard, want string
+	}{
+		{
+			name: "pretty",
+			hard: "{\n      \"requests.cpu\": \"1\"\n    }",
+			want: "{\n      \"requests.cpu\": \"1\",\n      \"requests.memory\": \"2Gi\"\n    }",
+		},
+		{
+			name: "compact",
+			hard: `{"requests.cpu":"1"}`,
+			want: `{"requests.cpu":"1", "requests.memory":"2Gi"}`,
+		},
+		{
+			name: "empty",
+			hard: `{}`,
+			want: `{"requests.memory": "2Gi"}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			content := `{"kind": "ResourceQuota", "metadata": {"name": "my-quota"}, "spec": {"hard": ` + tc.hard + `}}`
+			limits := map[corev1.ResourceName]resource.Quantity{
+				corev1.ResourceRequestsMemory: resource.MustParse("2Gi"),
+			}
+
+			got, err := applyChangesToJSON(content, "my-quota", limits)
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(`{"kind": "ResourceQuota", "metadata": {"name": "my-quota"}, "spec": {"hard": ` + tc.want + `}}`))
+		})
+	}
+}
+
+func TestApplyChangesToJSON_List(t *testing.T) {
+	g := NewWithT(t)
+	content := `{"apiVersion": "v1", "kind": "List", "items": [
+  {"kind": "LimitRange", "spec": {"hard": {"cpu": "1"}}},
+  {"kind": "ResourceQuota", "metadata": {"name": "my-quota"}, "spec": {"hard": {"cpu": "1"}}}
+]}`
+	limits := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceRequestsCPU: resource.MustParse("4"),
+	}
+
+	got, err := applyChangesToJSON(content, "my-quota", limits)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got).To(ContainSubstring(`{"kind": "LimitRange", "spec": {"hard": {"cpu": "1"}}}`))
+	g.Expect(got).To(ContainSubstring(`"metadata": {"name": "my-quota"}, "spec": {"hard": {"cpu": "4"}}}`))
+	g.Expect(jsonDefinesQuota(content, "my-quota")).To(BeTrue())
+	g.Expect(jsonDefinesQuota(content, "other")).To(BeFalse())
+}
+
+func TestApplyChangesToJSON_Invalid(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := applyChangesToJSON(`{"kind": "ResourceQuota",`, "my-quota", nil)
+
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestApplyChangesToYaml_List(t *testing.T) {
+	g := NewWithT(t)
+	content := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ResourceQuota
+    metadata:
+      name: my-quota
+    spec:
+      hard:
+        requests.cpu: "1"
+`
+	limits := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceRequestsCPU: resource.MustParse("3"),
+	}
+
+	got := applyChangesToYaml(content, "my-quota", limits)
+
+	g.Expect(got).To(ContainSubstring(`requests.cpu: "3"`))
+	g.Expect(got).To(ContainSubstring("kind: List"))
+}
+
+func TestIsJSONManifest(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(isJSONManifest("quota.json", "")).To(BeTrue())
+	g.Expect(isJSONManifest("quota.yaml", "\n  {\"kind\": \"ResourceQuota\"}")).To(BeTrue())
+	g.Expect(isJSONManifest("quota.yaml", "kind: ResourceQuota")).To(BeFalse())
+}
+
+func TestApplyChanges_OnlyTheNamedQuota(t *testing.T) {
+	g := NewWithT(t)
+	limits := map[corev1.ResourceName]resource.Quantity{
+		corev1.ResourceRequestsCPU: resource.MustParse("8"),
+	}
+
+	yamlContent := `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: compute
+spec:
+  hard:
+    requests.cpu: "4"
+---
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: batch
+spec:
+  hard:
+    requests.cpu: "2"
+`
+	got := applyChangesToYaml(yamlContent, "batch", limits)
+	g.Expect(got).To(ContainSubstring(`requests.cpu: "4"`))
+	g.Expect(got).To(ContainSubstring(`requests.cpu: "8"`))
+
+	jsonContent := `{"kind": "List", "items": [
+  {"kind": "ResourceQuota", "metadata": {"name": "compute"}, "spec": {"hard": {"requests.cpu": "4"}}},
+  {"kind": "ResourceQuota", "metadata": {"name": "batch"}, "spec": {"hard": {"requests.cpu": "2"}}}
+]}`
+	gotJSON, err := applyChangesToJSON(jsonContent, "batch", limits)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(gotJSON).To(ContainSubstring(`"compute"}, "spec": {"hard": {"requests.cpu": "4"}}`))
+	g.Expect(gotJSON).To(ContainSubstring(`"batch"}, "spec": {"hard": {"requests.cpu": "8"}}`))
+}
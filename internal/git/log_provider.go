@@ -68,6 +68,7 @@ func (p *LogOnlyProvider) MergePR(ctx context.Context, prID int, method string)
 func (p *LogOnlyProvider) FindOpenPR(
 	ctx context.Context,
 	namespace, quotaName string,
+	annotations map[string]string,
 ) (int, string, error) {
 	return 0, "", nil
 }
@@ -78,13 +79,41 @@ func (p *LogOnlyProvider) ClosePR(ctx context.Context, prID int, comment string)
 	return nil
 }
 
+func (p *LogOnlyProvider) CreateBatchPR(
+	ctx context.Context,
+	namespace, direction string,
+	annotations map[string]string,
+	changes []QuotaChange,
+) (int, error) {
+	log.FromContext(ctx).Info("Would create batched pull request",
+		"namespace", namespace, "quotas", quotaNames(changes),
+		"direction", direction)
+	return rand.Intn(1000) + 1000, nil
+}
+
+func (p *LogOnlyProvider) CreateDigestPR(
+	ctx context.Context,
+	baseBranch string,
+	entries []DigestEntry,
+) (int, []DigestEntry, error) {
+	log.FromContext(ctx).Info("Would create shrink digest pull request",
+		"quotas", len(entries), "namespaces", countNamespaces(entries))
+	return rand.Intn(1000) + 1000, entries, nil
+}
+
 // StatefulLogProvider allows simulating state changes for the demo
 type PRDetails struct {
 	Namespace string
 	QuotaName string
 	Direction string
 	NewLimits map[corev1.ResourceName]resource.Quantity
-	Status    *PRStatus
+	// Batch holds every quota of a batched PR; QuotaName and NewLimits are
+	// then empty.
+	Batch []QuotaChange
+	// Digest holds every quota of a shrink digest; Namespace is then empty
+	// too.
+	Digest []DigestEntry
+	Status *PRStatus
 }
 
 type StatefulLogProvider struct {
@@ -164,14 +193,30 @@ func (p *StatefulLogProvider) UpdatePR(ctx context.Context, prID int, quotaName,
 func (p *StatefulLogProvider) FindOpenPR(
 	ctx context.Context,
 	namespace, quotaName string,
+	annotations map[string]string,
 ) (int, string, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	for id, details := range p.prs {
-		if details.Namespace == namespace && details.QuotaName == quotaName &&
-			details.Status != nil && details.Status.IsOpen {
+		if details.Status == nil || !details.Status.IsOpen {
+			continue
+		}
+		for _, entry := range details.Digest {
+			if entry.Namespace == namespace && entry.Quota == quotaName {
+				return id, details.Direction, nil
+			}
+		}
+		if details.Namespace != namespace {
+			continue
+		}
+		if details.QuotaName == quotaName {
 			return id, details.Direction, nil
 		}
+		for _, change := range details.Batch {
+			if change.Quota == quotaName {
+				return id, details.Direction, nil
+			}
+		}
 	}
 	return 0, "", nil
 }
@@ -224,3 +269,57 @@ func (p *StatefulLogProvider) ClosePR(ctx context.Context, prID int, comment str
 	}
 	return nil
 }
+
+// CreateBatchPR stores a batched PR so FindOpenPR finds it for each quota.
+func (p *StatefulLogProvider) CreateBatchPR(
+	ctx context.Context,
+	namespace, direction string,
+	annotations map[string]string,
+	changes []QuotaChange,
+) (int, error) {
+	logger := log.FromContext(ctx)
+	id := rand.Intn(1000) + 1000
+	logger.Info("GitOps Simulation: Creating batched PR", "namespace", namespace,
+		"quotas", quotaNames(changes), "direction", direction, "prID", id)
+
+	p.mu.Lock()
+	p.prs[id] = &PRDetails{
+		Namespace: namespace,
+		Direction: direction,
+		Batch:     changes,
+		Status: &PRStatus{
+			IsOpen:         true,
+			IsMerged:       false,
+			Mergeable:      true,
+			MergeableState: MergeableStateClean,
+		},
+	}
+	p.mu.Unlock()
+	return id, nil
+}
+
+// CreateDigestPR stores a shrink digest so FindOpenPR finds it for each quota.
+func (p *StatefulLogProvider) CreateDigestPR(
+	ctx context.Context,
+	baseBranch string,
+	entries []DigestEntry,
+) (int, []DigestEntry, error) {
+	logger := log.FromContext(ctx)
+	id := rand.Intn(1000) + 1000
+	logger.Info("GitOps Simulation: Creating shrink digest PR",
+		"quotas", len(entries), "namespaces", countNamespaces(entries), "prID", id)
+
+	p.mu.Lock()
+	p.prs[id] = &PRDetails{
+		Direction: DirectionShrink,
+		Digest:    entries,
+		Status: &PRStatus{
+			IsOpen:         true,
+			IsMerged:       false,
+			Mergeable:      true,
+			MergeableState: MergeableStateClean,
+		},
+	}
+	p.mu.Unlock()
+	return id, entries, nil
+}
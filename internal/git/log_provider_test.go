@@ -25,7 +25,7 @@ func TestStatefulLogProvider_ConcurrentAccess(t *testing.T) {
 				return
 			}
 			_, _ = p.GetPRStatus(ctx, id)
-			_, _, _ = p.FindOpenPR(ctx, "ns", "quota")
+			_, _, _ = p.FindOpenPR(ctx, "ns", "quota", nil)
 			_ = p.MergePR(ctx, id, "squash")
 			_, _ = p.GetPRStatus(ctx, id)
 		}()
@@ -33,7 +33,7 @@ func TestStatefulLogProvider_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 
 	// After merging, no open PR should remain for the ns/quota.
-	openID, _, err := p.FindOpenPR(ctx, "ns", "quota")
+	openID, _, err := p.FindOpenPR(ctx, "ns", "quota", nil)
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(openID).To(Equal(0))
 }
@@ -0,0 +1,235 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Reviewers are asked to review a new pull request.
+type Reviewers struct {
+	Users []string
+	// Teams are team slugs of the organisation owning the repository;
+	// "@org/slug" is accepted as well.
+	Teams     []string
+	Assignees []string
+	// CodeOwners also requests the owners of the changed manifests, as the
+	// repository's CODEOWNERS file on the base branch names them.
+	CodeOwners bool
+}
+
+// IsZero reports whether r asks nobody.
+func (r Reviewers) IsZero() bool {
+	return len(r.Users) == 0 && len(r.Teams) == 0 && len(r.Assignees) == 0 && !r.CodeOwners
+}
+
+// Merge returns the reviewers of r and other, without duplicates.
+func (r Reviewers) Merge(other Reviewers) Reviewers {
+	return Reviewers{
+		Users:      union(r.Users, other.Users),
+		Teams:      union(r.Teams, other.Teams),
+		Assignees:  union(r.Assignees, other.Assignees),
+		CodeOwners: r.CodeOwners || other.CodeOwners,
+	}
+}
+
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, list := range [][]string{a, b} {
+		for _, item := range list {
+			if item != "" && !seen[item] {
+				seen[item] = true
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// ReviewerAssigner is implemented by providers that can ask for reviews on
+// the pull requests they open.
+type ReviewerAssigner interface {
+	// WithReviewers returns a provider whose new pull requests request
+	// reviewers.
+	WithReviewers(reviewers Reviewers) Provider
+}
+
+// WithReviewers implements ReviewerAssigner.
+func (g *GitHubProvider) WithReviewers(reviewers Reviewers) Provider {
+	scoped := *g
+	scoped.reviewers = reviewers
+	return &scoped
+}
+
+// requestReview asks the configured reviewers, and the code owners of the
+// edited files, to review the pull request. Failures are logged only: the
+// pull request is open and useful without them, and a reviewer who is not a
+// collaborator must not stop every proposal of the namespace.
+func (g *GitHubProvider) requestReview(ctx context.Context, number int, baseBranch string, paths []string) {
+	logger := log.FromContext(ctx)
+	reviewers := g.reviewers
+	if reviewers.CodeOwners {
+		owners, err := g.codeOwners(ctx, baseBranch, paths)
+		if err != nil {
+			logger.Error(err, "failed to read CODEOWNERS", "pr", number)
+		}
+		reviewers = reviewers.Merge(owners)
+	}
+
+	teams := make([]string, 0, len(reviewers.Teams))
+	for _, team := range reviewers.Teams {
+		teams = append(teams, teamSlug(team))
+	}
+	if len(reviewers.Users) > 0 || len(teams) > 0 {
+		request := github.ReviewersRequest{Reviewers: reviewers.Users, TeamReviewers: teams}
+		if _, _, err := g.client.PullRequests.RequestReviewers(ctx, g.owner, g.repo, number, request); err != nil {
+			logger.Error(err, "failed to request reviewers", "pr", number,
+				"users", reviewers.Users, "teams", teams)
+		}
+	}
+	if len(reviewers.Assignees) > 0 {
+		if _, _, err := g.client.Issues.AddAssignees(ctx, g.owner, g.repo, number, reviewers.Assignees); err != nil {
+			logger.Error(err, "failed to assign pull request", "pr", number, "assignees", reviewers.Assignees)
+		}
+	}
+}
+
+// requestDraftReview requests the reviews of a new pull request, unless it
+// is a draft: those are requested when it is marked ready.
+func (g *GitHubProvider) requestDraftReview(ctx context.Context, number int, baseBranch string, paths []string) {
+	if g.draft {
+		return
+	}
+	g.requestReview(ctx, number, baseBranch, paths)
+}
+
+// teamSlug strips "@org/" from a team reference; the API takes slugs only.
+func teamSlug(team string) string {
+	if i := strings.LastIndex(team, "/"); i >= 0 {
+		return team[i+1:]
+	}
+	return strings.TrimPrefix(team, "@")
+}
+
+// codeOwnersLocations are where GitHub looks for CODEOWNERS, in its order.
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwners returns the owners of paths. A repository without CODEOWNERS
+// has none.
+func (g *GitHubProvider) codeOwners(ctx context.Context, baseBranch string, paths []string) (Reviewers, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: baseBranch}
+	for _, location := range codeOwnersLocations {
+		file, _, _, err := g.client.Repositories.GetContents(ctx, g.owner, g.repo, location, opts)
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return Reviewers{}, fmt.Errorf("failed to get %s: %w", location, err)
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return Reviewers{}, fmt.Errorf("failed to decode %s: %w", location, err)
+		}
+		rules := parseCodeOwners(content)
+		var owners Reviewers
+		for _, path := range paths {
+			owners = owners.Merge(ownersOf(rules, path))
+		}
+		return owners, nil
+	}
+	return Reviewers{}, nil
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// parseCodeOwners reads a CODEOWNERS file. Lines whose pattern cannot be
+// understood are skipped, as GitHub does.
+func parseCodeOwners(content string) []codeOwnersRule {
+	var rules []codeOwnersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+		rules = append(rules, codeOwnersRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules
+}
+
+// ownersOf returns the owners of the last rule matching path. Owners given
+// by e-mail address cannot be requested through the API and are dropped.
+func ownersOf(rules []codeOwnersRule, path string) Reviewers {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if !rules[i].pattern.MatchString(path) {
+			continue
+		}
+		var owners Reviewers
+		for _, owner := range rules[i].owners {
+			if !strings.HasPrefix(owner, "@") {
+				continue
+			}
+			if strings.Contains(owner, "/") {
+				owners.Teams = append(owners.Teams, owner)
+			} else {
+				owners.Users = append(owners.Users, strings.TrimPrefix(owner, "@"))
+			}
+		}
+		return owners
+	}
+	return Reviewers{}
+}
+
+// codeOwnersPattern translates a CODEOWNERS pattern, which follows
+// .gitignore rules, into a regular expression over repository paths. A
+// pattern naming a directory also matches everything below it.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			// "**/" spans any number of directories, a trailing "**" all
+			// of them.
+			if i+2 < len(pattern) && pattern[i+2] == '/' {
+				sb.WriteString("(?:.*/)?")
+				i += 2
+			} else {
+				sb.WriteString(".*")
+				i++
+			}
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(?:/.*)?$")
+	return regexp.Compile(sb.String())
+}
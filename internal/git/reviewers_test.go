@@ -0,0 +1,122 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCodeOwnersPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*", "managed-resources/prod/team-a/quota.yaml", true},
+		{"*.yaml", "managed-resources/prod/team-a/quota.yaml", true},
+		{"*.json", "managed-resources/prod/team-a/quota.yaml", false},
+		{"team-a/", "managed-resources/prod/team-a/quota.yaml", true},
+		{"/team-a/", "managed-resources/prod/team-a/quota.yaml", false},
+		{"/managed-resources/prod/", "managed-resources/prod/team-a/quota.yaml", true},
+		{"managed-resources/*/team-a", "managed-resources/prod/team-a/quota.yaml", true},
+		{"managed-resources/*/team-a", "managed-resources/prod/x/team-a/quota.yaml", false},
+		{"**/team-a/quota.yaml", "managed-resources/prod/team-a/quota.yaml", true},
+		{"managed-resources/**/quota.yaml", "managed-resources/prod/team-a/quota.yaml", true},
+		{"team-?/", "managed-resources/prod/team-a/quota.yaml", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			g := NewWithT(t)
+			pattern, err := codeOwnersPattern(tc.pattern)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pattern.MatchString(tc.path)).To(Equal(tc.match), pattern.String())
+		})
+	}
+}
+
+func TestOwnersOf_LastMatchWins(t *testing.T) {
+	g := NewWithT(t)
+	rules := parseCodeOwners(`
+# Platform owns everything by default.
+*                     @org/platform
+managed-resources/*/default/  @org/payments @alice alice@example.com  # trailing comment
+`)
+
+	owners := ownersOf(rules, "managed-resources/prod/default/quota.yaml")
+	g.Expect(owners.Teams).To(Equal([]string{"@org/payments"}))
+	g.Expect(owners.Users).To(Equal([]string{"alice"}), "e-mail owners cannot be requested")
+
+	owners = ownersOf(rules, "README.md")
+	g.Expect(owners.Teams).To(Equal([]string{"@org/platform"}))
+}
+
+func TestCreatePR_RequestsReviewers(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	setupCreatePRRoutes(g, mux, 101)
+	mux.HandleFunc("/repos/o/r/issues/101/labels", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	// .github/CODEOWNERS does not exist, the root one does.
+	mux.HandleFunc("/repos/o/r/contents/CODEOWNERS", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.URL.Query().Get("ref")).To(Equal("main"))
+		content := base64.StdEncoding.EncodeToString([]byte("managed-resources/ @org/quota-owners\n"))
+		_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64"}`, content)
+	})
+	var request struct {
+		Reviewers     []string `json:"reviewers"`
+		TeamReviewers []string `json:"team_reviewers"`
+	}
+	mux.HandleFunc("/repos/o/r/pulls/101/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+		_, _ = fmt.Fprint(w, `{"number": 101}`)
+	})
+	var assignees struct {
+		Assignees []string `json:"assignees"`
+	}
+	mux.HandleFunc("/repos/o/r/issues/101/assignees", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(json.NewDecoder(r.Body).Decode(&assignees)).To(Succeed())
+		_, _ = fmt.Fprint(w, `{"number": 101}`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	reviewed := provider.WithReviewers(Reviewers{
+		Users:      []string{"alice"},
+		Teams:      []string{"@org/payments"},
+		Assignees:  []string{"bob"},
+		CodeOwners: true,
+	})
+	prID, err := reviewed.CreatePR(context.Background(), "my-quota", "default", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(101))
+	g.Expect(request.Reviewers).To(Equal([]string{"alice"}))
+	g.Expect(request.TeamReviewers).To(Equal([]string{"payments", "quota-owners"}))
+	g.Expect(assignees.Assignees).To(Equal([]string{"bob"}))
+}
+
+func TestCreatePR_ReviewRequestFailureKeepsThePR(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	setupCreatePRRoutes(g, mux, 101)
+	mux.HandleFunc("/repos/o/r/issues/101/labels", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls/101/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Reviews may only be requested from collaborators."}`, http.StatusUnprocessableEntity)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	reviewed := provider.WithReviewers(Reviewers{Users: []string{"outsider"}})
+	prID, err := reviewed.CreatePR(context.Background(), "my-quota", "default", DirectionShrink, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(101))
+}
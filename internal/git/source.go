@@ -0,0 +1,129 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Source pins where a quota's manifests live when the controller discovered
+// it from the GitOps tool that deploys the quota, rather than from the path
+// template.
+type Source struct {
+	// RepoURL is the clone URL as the GitOps tool records it, in HTTPS
+	// (https://github.com/org/repo.git) or SCP-like SSH
+	// (git@github.com:org/repo.git) form.
+	RepoURL string
+	// Revision is the branch the pull request targets. Empty or "HEAD"
+	// means the repository's default branch.
+	Revision string
+	// Path is the directory holding the manifests, relative to the
+	// repository root.
+	Path string
+}
+
+// SourceScoper is implemented by providers that can be pointed at a
+// repository discovered at runtime.
+type SourceScoper interface {
+	// WithSource returns a provider that opens pull requests against src
+	// instead of the configured repository and path template. It fails when
+	// src is not a repository this provider can reach.
+	WithSource(src Source) (Provider, error)
+}
+
+// RepositoryOf returns the "owner/repo" a provider opens pull requests in, or
+// "" for providers that are not bound to a repository (the dry-run ones).
+// Lock state records it so a pull request number is never looked up in a
+// repository it does not belong to.
+func RepositoryOf(p Provider) string {
+	if bound, ok := p.(interface{ Repository() string }); ok {
+		return bound.Repository()
+	}
+	return ""
+}
+
+// Repository returns the "owner/repo" pull requests are opened in.
+func (g *GitHubProvider) Repository() string {
+	return g.owner + "/" + g.repo
+}
+
+// WithBaseBranch returns a copy of the provider whose pull requests target
+// branch instead of the repository's default branch. branch may use the
+// fields of the path template, e.g. "env/{{ .Cluster }}".
+func (g *GitHubProvider) WithBaseBranch(branch string) *GitHubProvider {
+	scoped := *g
+	scoped.baseBranch = branch
+	scoped.baseBranchDiscovered = false
+	return &scoped
+}
+
+// parseRepoURL splits a clone URL into host, owner and repository name.
+func parseRepoURL(raw string) (host, owner, repo string, err error) {
+	raw = strings.TrimSpace(raw)
+	var repoPath string
+	switch {
+	case strings.Contains(raw, "://"):
+		u, perr := url.Parse(raw)
+		if perr != nil {
+			return "", "", "", fmt.Errorf("invalid repository URL %q: %w", raw, perr)
+		}
+		host, repoPath = u.Hostname(), u.Path
+	case strings.Contains(raw, ":"):
+		// SCP-like syntax: [user@]host:owner/repo.git
+		hostPart, pathPart, _ := strings.Cut(raw, ":")
+		if at := strings.LastIndex(hostPart, "@"); at >= 0 {
+			hostPart = hostPart[at+1:]
+		}
+		host, repoPath = hostPart, pathPart
+	default:
+		return "", "", "", fmt.Errorf("invalid repository URL %q", raw)
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	owner, repo, ok := strings.Cut(repoPath, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", "", fmt.Errorf("repository URL %q does not name an owner/repository pair", raw)
+	}
+	return strings.ToLower(host), owner, repo, nil
+}
+
+// WithSource scopes the provider to a discovered repository. The host has to
+// be the one the client talks to: a repository on another forge that happens
+// to share the owner/repo pair must not receive the pull request.
+func (g *GitHubProvider) WithSource(src Source) (Provider, error) {
+	host, owner, repo, err := parseRepoURL(src.RepoURL)
+	if err != nil {
+		return nil, err
+	}
+	if want := g.webHost(); host != want {
+		return nil, fmt.Errorf("repository %s is hosted on %s, not on %s", src.RepoURL, host, want)
+	}
+
+	scoped := *g
+	scoped.owner = owner
+	scoped.repo = repo
+	scoped.sourceScoped = true
+	// GitOps tools write paths as "./clusters/prod", "apps/team-a/" or "."
+	// for the root; the contents API wants "clusters/prod" and "".
+	scoped.sourcePath = strings.TrimPrefix(path.Clean("/"+src.Path), "/")
+	// The discovered revision replaces the configured branch even when it is
+	// the repository's default: a template written for the global repository
+	// names a branch the discovered one need not have.
+	scoped.baseBranch = src.Revision
+	if src.Revision == "HEAD" {
+		scoped.baseBranch = ""
+	}
+	scoped.baseBranchDiscovered = true
+	return &scoped, nil
+}
+
+// webHost is the host repository URLs carry for the API the client talks to:
+// github.com for api.github.com, the server itself for GitHub Enterprise.
+func (g *GitHubProvider) webHost() string {
+	host := strings.ToLower(g.client.BaseURL.Hostname())
+	if host == "api.github.com" {
+		return "github.com"
+	}
+	return host
+}
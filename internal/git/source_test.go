@@ -0,0 +1,280 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseRepoURL(t *testing.T) {
+	cases := []struct {
+		raw               string
+		host, owner, repo string
+		wantErr           bool
+	}{
+		{raw: "https://github.com/org/tenants.git", host: "github.com", owner: "org", repo: "tenants"},
+		{raw: "https://github.com/org/tenants", host: "github.com", owner: "org", repo: "tenants"},
+		{raw: "git@github.com:org/tenants.git", host: "github.com", owner: "org", repo: "tenants"},
+		{raw: "ssh://git@GitHub.com/org/tenants.git", host: "github.com", owner: "org", repo: "tenants"},
+		{raw: "https://github.com/org", wantErr: true},
+		{raw: "https://gitlab.com/group/sub/project.git", wantErr: true},
+		{raw: "tenants", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.raw, func(t *testing.T) {
+			g := NewWithT(t)
+			host, owner, repo, err := parseRepoURL(tc.raw)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect([]string{host, owner, repo}).To(Equal([]string{tc.host, tc.owner, tc.repo}))
+		})
+	}
+}
+
+func TestWithSource_RejectsOtherHosts(t *testing.T) {
+	g := NewWithT(t)
+	provider, teardown := newTestProvider(t, http.NewServeMux())
+	defer teardown()
+
+	_, err := provider.WithSource(Source{RepoURL: "https://gitlab.com/org/tenants.git", Path: "team-a"})
+
+	g.Expect(err).To(MatchError(ContainSubstring("gitlab.com")))
+}
+
+// TestWithSource_CreatePR checks that a scoped provider opens the pull request
+// in the discovered repository, against the discovered revision, and looks
+// for the quota in the discovered path instead of the template.
+func TestWithSource_CreatePR(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+	host := provider.client.BaseURL.Host
+
+	mux.HandleFunc("/repos/org/tenants", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the default branch must not be looked up when the revision is known")
+		_, _ = fmt.Fprint(w, `{"default_branch": "main"}`)
+	})
+	mux.HandleFunc("/repos/org/tenants/git/ref/heads/release", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/org/tenants/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"ref": "refs/heads/new-branch"}`)
+	})
+	mux.HandleFunc("/repos/org/tenants/contents/apps/team-a", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{"name": "quota.yaml", "path": "apps/team-a/quota.yaml", "type": "file"}]`)
+	})
+	mux.HandleFunc("/repos/org/tenants/contents/apps/team-a/quota.yaml", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprint(w, `{"content": "a2luZDogUmVzb3VyY2VRdW90YQptZXRhZGF0YToKICBuYW1lOiBteS1xdW90YQpzcGVjOgogIGhhcmQ6CiAgICByZXF1ZXN0cy5jcHU6IDE=", "encoding": "base64", "sha": "file-sha"}`)
+		case http.MethodPut:
+			_, _ = fmt.Fprint(w, `{"commit": {"sha": "new-sha"}}`)
+		}
+	})
+	var base string
+	mux.HandleFunc("/repos/org/tenants/pulls", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Base string `json:"base"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		base = body.Base
+		_, _ = fmt.Fprint(w, `{"number": 5, "state": "open"}`)
+	})
+	mux.HandleFunc("/repos/org/tenants/issues/5/labels", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+
+	scoped, err := provider.WithSource(Source{
+		RepoURL:  "https://" + host + "/org/tenants.git",
+		Revision: "release",
+		Path:     "apps/team-a/",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	prID, err := scoped.CreatePR(context.Background(), "my-quota", "team-a", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(5))
+	g.Expect(base).To(Equal("release"))
+	g.Expect(provider.repo).To(Equal("r"), "scoping must not modify the global provider")
+}
+
+func TestWithSource_NormalisesPath(t *testing.T) {
+	provider, teardown := newTestProvider(t, http.NewServeMux())
+	defer teardown()
+	repoURL := "https://" + provider.client.BaseURL.Host + "/org/tenants.git"
+
+	for raw, want := range map[string]string{
+		"./clusters/prod": "clusters/prod",
+		"apps/team-a/":    "apps/team-a",
+		"./":              "",
+		".":               "",
+	} {
+		g := NewWithT(t)
+		scoped, err := provider.WithSource(Source{RepoURL: repoURL, Path: raw})
+		g.Expect(err).NotTo(HaveOccurred())
+		got, err := scoped.(*GitHubProvider).resolvePath("team-a", nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(got).To(Equal(want), raw)
+	}
+}
+
+func TestResolveBaseBranch(t *testing.T) {
+	provider, teardown := newTestProvider(t, http.NewServeMux())
+	defer teardown()
+	templated := provider.WithBaseBranch("env/{{ .Cluster }}")
+	discovered, err := templated.WithSource(Source{
+		RepoURL:  "http://" + provider.client.BaseURL.Host + "/o/r.git",
+		Revision: "env/{{ .Cluster }}",
+		Path:     "team-a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := templated.WithSource(Source{
+		RepoURL:  "http://" + provider.client.BaseURL.Host + "/o/r.git",
+		Revision: "HEAD",
+		Path:     "team-a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		provider    *GitHubProvider
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{name: "repository default", provider: provider, want: ""},
+		{name: "provider template", provider: templated, want: "env/cluster"},
+		{
+			name:     "discovered revision is verbatim",
+			provider: discovered.(*GitHubProvider),
+			want:     "env/{{ .Cluster }}",
+		},
+		{
+			name:     "discovered HEAD is the repository default",
+			provider: head.(*GitHubProvider),
+			want:     "",
+		},
+		{
+			name:        "annotation wins over discovered revision",
+			provider:    discovered.(*GitHubProvider),
+			annotations: map[string]string{AnnotationBaseBranch: "hotfix/{{ .Namespace }}"},
+			want:        "hotfix/team-a",
+		},
+		{
+			name:        "annotation wins",
+			provider:    templated,
+			annotations: map[string]string{AnnotationBaseBranch: "hotfix/{{ .Namespace }}"},
+			want:        "hotfix/team-a",
+		},
+		{
+			name:        "invalid annotation",
+			provider:    provider,
+			annotations: map[string]string{AnnotationBaseBranch: "env/{{ .Cluster"},
+			wantErr:     true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := tc.provider.resolveBaseBranch("team-a", tc.annotations)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tc.want))
+		})
+	}
+}
+
+// TestFindOpenPR_ClosesPullRequestsAgainstAPreviousBase verifies that orphan
+// recovery sees a pull request opened before the base branch changed, and
+// closes it instead of adopting it or leaving a duplicate next to it.
+func TestFindOpenPR_ClosesPullRequestsAgainstAPreviousBase(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.URL.Query().Get("base")).To(BeEmpty())
+		_, _ = fmt.Fprint(w, `[
+			{"number": 1, "head": {"ref": "resize/cluster/grow/team-a/compute/1700000000"}, "base": {"ref": "main"}},
+			{"number": 2, "head": {"ref": "resize/cluster/grow/team-a/compute/1700000001"}, "base": {"ref": "env/cluster"}}
+		]`)
+	})
+	var closed []string
+	mux.HandleFunc("/repos/o/r/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		g.Expect(string(body)).To(ContainSubstring("env/cluster"))
+		_, _ = fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/repos/o/r/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		closed = append(closed, r.Method)
+		_, _ = fmt.Fprint(w, `{}`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	id, direction, err := provider.WithBaseBranch("env/{{ .Cluster }}").FindOpenPR(context.Background(), "team-a", "compute", nil)
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(id).To(Equal(2))
+	g.Expect(direction).To(Equal(DirectionGrow))
+	g.Expect(closed).To(Equal([]string{http.MethodPatch}))
+}
+
+// TestFindQuotaFile_SearchesBelowADiscoveredPath checks that a quota kept in
+// an overlay below the discovered path is found, while a templated path is
+// still only searched one directory deep.
+func TestFindQuotaFile_SearchesBelowADiscoveredPath(t *testing.T) {
+	g := NewWithT(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/contents/apps", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"name": "kustomization.yaml", "path": "apps/kustomization.yaml", "type": "file"},
+			{"name": ".github", "path": "apps/.github", "type": "dir"},
+			{"name": "overlays", "path": "apps/overlays", "type": "dir"}
+		]`)
+	})
+	mux.HandleFunc("/repos/o/r/contents/apps/kustomization.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64"}`,
+			base64.StdEncoding.EncodeToString([]byte("resources:\n- overlays\n")))
+	})
+	mux.HandleFunc("/repos/o/r/contents/apps/.github", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("hidden directories are not searched")
+	})
+	mux.HandleFunc("/repos/o/r/contents/apps/overlays", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{"name": "quota.yaml", "path": "apps/overlays/quota.yaml", "type": "file"}]`)
+	})
+	mux.HandleFunc("/repos/o/r/contents/apps/overlays/quota.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64"}`,
+			base64.StdEncoding.EncodeToString([]byte("kind: ResourceQuota\nmetadata:\n  name: compute\n")))
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	_, _, err := provider.findQuotaFile(context.Background(), "apps", "main", "team-a", "compute")
+	g.Expect(err).To(MatchError(ErrFileNotFound), "the template names the quota's own directory")
+
+	discovered, err := provider.WithSource(Source{
+		RepoURL: "http://" + provider.client.BaseURL.Host + "/o/r.git",
+		Path:    "apps",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	file, _, err := discovered.(*GitHubProvider).findQuotaFile(context.Background(), "apps", "main", "team-a", "compute")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(file).To(Equal("apps/overlays/quota.yaml"))
+}
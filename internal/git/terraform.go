@@ -0,0 +1,565 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Terraform resource types that manage a ResourceQuota through the
+// kubernetes provider. The _v1 spelling is the one the provider recommends
+// since 2.x; the unversioned one is still accepted and widely used.
+var terraformQuotaTypes = map[string]bool{
+	"kubernetes_resource_quota":    true,
+	"kubernetes_resource_quota_v1": true,
+}
+
+// maxTerraformIndirections bounds how many local/var hops are followed from a
+// spec.hard entry. Terraform itself rejects reference cycles; this only keeps
+// a malformed module from looping here.
+const maxTerraformIndirections = 8
+
+var errTerraformUnsupported = errors.New("unsupported terraform expression")
+
+// terraformFile is one .tf or .tfvars file of the module a quota is defined in.
+type terraformFile struct {
+	path    string
+	sha     string
+	content string
+}
+
+// fileEdit is the new content of one repository file touched by a quota
+// change. sha is the blob the change was computed against.
+type fileEdit struct {
+	path    string
+	sha     string
+	content string
+}
+
+func isTerraformFile(name string) bool {
+	return strings.HasSuffix(name, ".tf")
+}
+
+func isTerraformVarsFile(name string) bool {
+	return strings.HasSuffix(name, ".tfvars")
+}
+
+// terraformDefinesQuota reports whether a .tf file declares the quota. Only
+// literal metadata values are matched: a name computed from a variable cannot
+// be compared without evaluating the whole module, and guessing would risk
+// editing another namespace's quota.
+func terraformDefinesQuota(content, namespace, quotaName string) bool {
+	file, diags := hclsyntax.ParseConfig([]byte(content), "quota.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		return false
+	}
+	return findTerraformQuota(file.Body.(*hclsyntax.Body), namespace, quotaName) != nil
+}
+
+// findTerraformQuota returns the spec block of the matching quota resource.
+// A resource without metadata.namespace lives in "default", as it does for
+// the provider.
+func findTerraformQuota(body *hclsyntax.Body, namespace, quotaName string) *hclsyntax.Block {
+	for _, block := range body.Blocks {
+		if block.Type != "resource" || len(block.Labels) != 2 ||
+			!terraformQuotaTypes[block.Labels[0]] {
+			continue
+		}
+		var spec, metadata *hclsyntax.Block
+		for _, inner := range block.Body.Blocks {
+			switch inner.Type {
+			case "metadata":
+				metadata = inner
+			case "spec":
+				spec = inner
+			}
+		}
+		if metadata == nil || spec == nil {
+			continue
+		}
+		name, ok := literalAttribute(metadata.Body, "name")
+		if !ok || name != quotaName {
+			continue
+		}
+		ns, ok := literalAttribute(metadata.Body, "namespace")
+		if !ok {
+			ns = "default"
+		}
+		if ns == namespace {
+			return spec
+		}
+	}
+	return nil
+}
+
+func literalAttribute(body *hclsyntax.Body, name string) (string, bool) {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return "", false
+	}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || val.Type() != cty.String {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// byteReplacement swaps the bytes in [start, end) of a file.
+type byteReplacement struct {
+	start, end int
+	text       string
+}
+
+type parsedTerraformFile struct {
+	terraformFile
+	src  []byte
+	body *hclsyntax.Body
+}
+
+// terraformEditor collects byte-range replacements across the files of one
+// module. Editing the source bytes in place, rather than re-rendering the
+// syntax tree, is what keeps comments, alignment and every untouched
+// expression exactly as the author wrote them.
+type terraformEditor struct {
+	files map[string]*parsedTerraformFile
+	edits map[string][]byteReplacement
+	// seen guards against applying two different values to the same
+	// expression, which happens when two spec.hard keys share one local.
+	seen map[string]string
+}
+
+// applyChangesToTerraform rewrites the spec.hard entries of the quota declared
+// in target. Literal values are replaced in place; a value taken from a local
+// or a variable is changed where it is defined — the locals block, the tfvars
+// entry, or failing that the variable's default — so the module keeps its
+// indirection. Only files whose content changed are returned.
+func applyChangesToTerraform(
+	files []terraformFile,
+	target, namespace, quotaName string,
+	limits map[corev1.ResourceName]resource.Quantity,
+) ([]fileEdit, error) {
+	editor := &terraformEditor{
+		files: map[string]*parsedTerraformFile{},
+		edits: map[string][]byteReplacement{},
+		seen:  map[string]string{},
+	}
+	for _, f := range files {
+		src := []byte(f.content)
+		parsed, diags := hclsyntax.ParseConfig(src, f.path, hcl.InitialPos)
+		if diags.HasErrors() {
+			if f.path == target {
+				return nil, fmt.Errorf("failed to parse %s: %s", f.path, diags.Error())
+			}
+			// A broken sibling only matters if the quota refers into it,
+			// in which case resolve reports the missing definition.
+			continue
+		}
+		editor.files[f.path] = &parsedTerraformFile{
+			terraformFile: f,
+			src:           src,
+			body:          parsed.Body.(*hclsyntax.Body),
+		}
+	}
+
+	file, ok := editor.files[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not part of the loaded module", ErrFileNotFound, target)
+	}
+	spec := findTerraformQuota(file.body, namespace, quotaName)
+	if spec == nil {
+		return nil, fmt.Errorf("%w: quota %s/%s not declared in %s",
+			ErrFileNotFound, namespace, quotaName, target)
+	}
+	hard, ok := spec.Body.Attributes["hard"]
+	if !ok {
+		return nil, fmt.Errorf("quota %s/%s in %s has no spec.hard", namespace, quotaName, target)
+	}
+	if err := editor.setObject(file, hard.Expr, limits, 0); err != nil {
+		return nil, fmt.Errorf("failed to edit spec.hard of %s/%s in %s: %w",
+			namespace, quotaName, target, err)
+	}
+	return editor.render(), nil
+}
+
+// setObject applies limits to an expression that has to evaluate to the
+// spec.hard map.
+func (e *terraformEditor) setObject(
+	file *parsedTerraformFile,
+	expr hclsyntax.Expression,
+	limits map[corev1.ResourceName]resource.Quantity,
+	depth int,
+) error {
+	defFile, defExpr, err := e.resolve(file, expr, depth)
+	if err != nil {
+		return err
+	}
+	obj, ok := defExpr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return fmt.Errorf("%w: spec.hard in %s is not a map literal", errTerraformUnsupported, defFile.path)
+	}
+
+	resources := make([]string, 0, len(limits))
+	for res := range limits {
+		resources = append(resources, string(res))
+	}
+	sort.Strings(resources)
+
+	var missing []string
+	for _, name := range resources {
+		res := corev1.ResourceName(name)
+		found := false
+		for _, item := range obj.Items {
+			key, ok := objectKey(item.KeyExpr)
+			if !ok || !matchesResourceKey(key, res) {
+				continue
+			}
+			found = true
+			if err := e.setValue(defFile, item.ValueExpr, limits[res], depth); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		e.insertEntries(defFile, obj, missing, limits)
+	}
+	return nil
+}
+
+// setValue replaces a single quantity, following local/var references to the
+// place the value is actually written down.
+func (e *terraformEditor) setValue(
+	file *parsedTerraformFile,
+	expr hclsyntax.Expression,
+	qty resource.Quantity,
+	depth int,
+) error {
+	defFile, defExpr, err := e.resolve(file, expr, depth)
+	if err != nil {
+		return err
+	}
+
+	text := strconv.Quote(qty.String())
+	switch v := defExpr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		// `pods = 10` stays a number as long as the new value is one.
+		if v.Val.Type() == cty.Number {
+			if _, err := strconv.ParseInt(qty.String(), 10, 64); err == nil {
+				text = qty.String()
+			}
+		}
+	case *hclsyntax.TemplateExpr:
+		if !v.IsStringLiteral() {
+			return fmt.Errorf("%w: interpolated string in %s", errTerraformUnsupported, defFile.path)
+		}
+	default:
+		return fmt.Errorf("%w: %T in %s", errTerraformUnsupported, defExpr, defFile.path)
+	}
+
+	rng := defExpr.Range()
+	key := fmt.Sprintf("%s:%d", defFile.path, rng.Start.Byte)
+	if previous, ok := e.seen[key]; ok {
+		if previous != text {
+			return fmt.Errorf("%s:%d is shared by resources that need different values (%s and %s)",
+				defFile.path, rng.Start.Line, previous, text)
+		}
+		return nil
+	}
+	e.seen[key] = text
+	e.edits[defFile.path] = append(e.edits[defFile.path], byteReplacement{
+		start: rng.Start.Byte,
+		end:   rng.End.Byte,
+		text:  text,
+	})
+	return nil
+}
+
+// resolve follows local.* and var.* references until it reaches an
+// expression that is written down literally, and returns it together with the
+// file it lives in.
+func (e *terraformEditor) resolve(
+	file *parsedTerraformFile,
+	expr hclsyntax.Expression,
+	depth int,
+) (*parsedTerraformFile, hclsyntax.Expression, error) {
+	// A parenthesised or otherwise wrapped expression is unwrapped first.
+	if wrapped, ok := expr.(*hclsyntax.ParenthesesExpr); ok {
+		return e.resolve(file, wrapped.Expression, depth)
+	}
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok {
+		return file, expr, nil
+	}
+	if depth >= maxTerraformIndirections {
+		return nil, nil, fmt.Errorf("%w: more than %d indirections", errTerraformUnsupported, maxTerraformIndirections)
+	}
+	if len(traversal.Traversal) < 2 {
+		return nil, nil, fmt.Errorf("%w: reference %s", errTerraformUnsupported, traversalString(traversal.Traversal))
+	}
+	name, ok := traversal.Traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: reference %s", errTerraformUnsupported, traversalString(traversal.Traversal))
+	}
+
+	var (
+		defFile *parsedTerraformFile
+		defExpr hclsyntax.Expression
+		err     error
+	)
+	switch traversal.Traversal.RootName() {
+	case "local":
+		defFile, defExpr, err = e.findLocal(name.Name)
+	case "var":
+		defFile, defExpr, err = e.findVariable(name.Name)
+	default:
+		return nil, nil, fmt.Errorf("%w: reference %s", errTerraformUnsupported, traversalString(traversal.Traversal))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Steps past the name index into a map: var.quota["requests.cpu"] or
+	// local.quota.cpu.
+	for _, step := range traversal.Traversal[2:] {
+		defFile, defExpr, err = e.resolve(defFile, defExpr, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := traverserKey(step)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: reference %s", errTerraformUnsupported, traversalString(traversal.Traversal))
+		}
+		obj, ok := defExpr.(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s does not index a map literal", errTerraformUnsupported, traversalString(traversal.Traversal))
+		}
+		var next hclsyntax.Expression
+		for _, item := range obj.Items {
+			if k, ok := objectKey(item.KeyExpr); ok && k == key {
+				next = item.ValueExpr
+				break
+			}
+		}
+		if next == nil {
+			return nil, nil, fmt.Errorf("key %q of %s is not defined", key, traversalString(traversal.Traversal))
+		}
+		defExpr = next
+	}
+	return e.resolve(defFile, defExpr, depth+1)
+}
+
+func (e *terraformEditor) findLocal(name string) (*parsedTerraformFile, hclsyntax.Expression, error) {
+	for _, p := range e.sortedPaths() {
+		file := e.files[p]
+		if !isTerraformFile(file.path) {
+			continue
+		}
+		for _, block := range file.body.Blocks {
+			if block.Type != "locals" {
+				continue
+			}
+			if attr, ok := block.Body.Attributes[name]; ok {
+				return file, attr.Expr, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("local.%s is not defined in the module", name)
+}
+
+// findVariable returns the tfvars entry for a variable. Only one tfvars file
+// in the module may set it: with several (dev.tfvars next to prod.tfvars) the
+// one passed to terraform apply cannot be known here, and editing all of them
+// would change environments nobody asked about. Without any tfvars entry the
+// variable's default is edited instead.
+func (e *terraformEditor) findVariable(name string) (*parsedTerraformFile, hclsyntax.Expression, error) {
+	var (
+		found     []*parsedTerraformFile
+		foundExpr hclsyntax.Expression
+	)
+	for _, p := range e.sortedPaths() {
+		file := e.files[p]
+		if !isTerraformVarsFile(file.path) {
+			continue
+		}
+		if attr, ok := file.body.Attributes[name]; ok {
+			found = append(found, file)
+			foundExpr = attr.Expr
+		}
+	}
+	switch len(found) {
+	case 1:
+		return found[0], foundExpr, nil
+	case 0:
+	default:
+		paths := make([]string, 0, len(found))
+		for _, f := range found {
+			paths = append(paths, f.path)
+		}
+		return nil, nil, fmt.Errorf("var.%s is set in several tfvars files (%s)",
+			name, strings.Join(paths, ", "))
+	}
+
+	for _, p := range e.sortedPaths() {
+		file := e.files[p]
+		if !isTerraformFile(file.path) {
+			continue
+		}
+		for _, block := range file.body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 || block.Labels[0] != name {
+				continue
+			}
+			if attr, ok := block.Body.Attributes["default"]; ok {
+				return file, attr.Expr, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("var.%s has neither a tfvars entry nor a default", name)
+}
+
+// insertEntries appends keys the map does not carry yet, one per line, on the
+// indentation of the existing last entry.
+func (e *terraformEditor) insertEntries(
+	file *parsedTerraformFile,
+	obj *hclsyntax.ObjectConsExpr,
+	names []string,
+	limits map[corev1.ResourceName]resource.Quantity,
+) {
+	closing := obj.SrcRange.End.Byte - 1
+	indent := "  "
+	if n := len(obj.Items); n > 0 {
+		indent = lineIndent(file.src, obj.Items[n-1].KeyExpr.Range().Start.Byte)
+	}
+
+	var sb strings.Builder
+	// Insert after the last non-blank character before the closing brace so
+	// the brace keeps its own line and indentation.
+	at := closing
+	for at > obj.SrcRange.Start.Byte+1 && isBlank(file.src[at-1]) {
+		at--
+	}
+	for _, name := range names {
+		qty := limits[corev1.ResourceName(name)]
+		_, _ = fmt.Fprintf(&sb, "\n%s%q = %q", indent, name, qty.String())
+	}
+	e.edits[file.path] = append(e.edits[file.path], byteReplacement{
+		start: at,
+		end:   at,
+		text:  sb.String(),
+	})
+}
+
+// render applies the collected replacements. A file that was canonically
+// formatted before is formatted again afterwards, so an inserted key keeps
+// `terraform fmt` happy; any other file is left exactly as edited.
+func (e *terraformEditor) render() []fileEdit {
+	var out []fileEdit
+	for _, p := range e.sortedPaths() {
+		reps := e.edits[p]
+		if len(reps) == 0 {
+			continue
+		}
+		file := e.files[p]
+		sort.Slice(reps, func(i, j int) bool { return reps[i].start > reps[j].start })
+
+		src := append([]byte(nil), file.src...)
+		for _, rep := range reps {
+			src = append(src[:rep.start], append([]byte(rep.text), src[rep.end:]...)...)
+		}
+		if bytes.Equal(hclwrite.Format(file.src), file.src) {
+			src = hclwrite.Format(src)
+		}
+		if string(src) == file.content {
+			continue
+		}
+		out = append(out, fileEdit{path: file.path, sha: file.sha, content: string(src)})
+	}
+	return out
+}
+
+func (e *terraformEditor) sortedPaths() []string {
+	paths := make([]string, 0, len(e.files))
+	for p := range e.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// objectKey reads a map key written either as an identifier or as a string.
+func objectKey(expr hclsyntax.Expression) (string, bool) {
+	if keyExpr, ok := expr.(*hclsyntax.ObjectConsKeyExpr); ok {
+		if name := hcl.ExprAsKeyword(keyExpr.Wrapped); name != "" && !keyExpr.ForceNonLiteral {
+			return name, true
+		}
+		expr = keyExpr.Wrapped
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || val.Type() != cty.String {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+func traverserKey(step hcl.Traverser) (string, bool) {
+	switch s := step.(type) {
+	case hcl.TraverseAttr:
+		return s.Name, true
+	case hcl.TraverseIndex:
+		if s.Key.Type() == cty.String && s.Key.IsKnown() && !s.Key.IsNull() {
+			return s.Key.AsString(), true
+		}
+	}
+	return "", false
+}
+
+func traversalString(t hcl.Traversal) string {
+	var sb strings.Builder
+	for i, step := range t {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			sb.WriteString(s.Name)
+		case hcl.TraverseAttr:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(s.Name)
+		case hcl.TraverseIndex:
+			if key, ok := traverserKey(s); ok {
+				_, _ = fmt.Fprintf(&sb, "[%q]", key)
+			} else {
+				sb.WriteString("[...]")
+			}
+		default:
+			sb.WriteString("...")
+		}
+	}
+	return sb.String()
+}
+
+func lineIndent(src []byte, offset int) string {
+	start := offset
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
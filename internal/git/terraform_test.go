@@ -0,0 +1,295 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const quotaTF = `resource "kubernetes_resource_quota_v1" "team" {
+  metadata {
+    name      = "my-quota"
+    namespace = "team-a"
+  }
+
+  spec {
+    hard = %s
+  }
+}
+`
+
+func tfLimits(kv ...string) map[corev1.ResourceName]resource.Quantity {
+	limits := map[corev1.ResourceName]resource.Quantity{}
+	for i := 0; i < len(kv); i += 2 {
+		limits[corev1.ResourceName(kv[i])] = resource.MustParse(kv[i+1])
+	}
+	return limits
+}
+
+func editsByPath(edits []fileEdit) map[string]string {
+	out := map[string]string{}
+	for _, e := range edits {
+		out[e.path] = e.content
+	}
+	return out
+}
+
+func TestTerraformDefinesQuota(t *testing.T) {
+	g := NewWithT(t)
+	content := fmt.Sprintf(quotaTF, `{ "requests.cpu" = "1" }`)
+
+	g.Expect(terraformDefinesQuota(content, "team-a", "my-quota")).To(BeTrue())
+	g.Expect(terraformDefinesQuota(content, "team-b", "my-quota")).To(BeFalse())
+	g.Expect(terraformDefinesQuota(content, "team-a", "other")).To(BeFalse())
+	g.Expect(terraformDefinesQuota("not { valid", "team-a", "my-quota")).To(BeFalse())
+}
+
+func TestApplyChangesToTerraform_Literal(t *testing.T) {
+	g := NewWithT(t)
+	src := fmt.Sprintf(quotaTF, `{
+      # CPU is reviewed every quarter
+      "requests.cpu"    = "1"
+      "requests.memory" = "1Gi"
+      pods              = 10
+    }`)
+	files := []terraformFile{{path: "tf/main.tf", sha: "s1", content: src}}
+
+	edits, err := applyChangesToTerraform(files, "tf/main.tf", "team-a", "my-quota",
+		tfLimits("requests.cpu", "2", "pods", "20"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(edits).To(HaveLen(1))
+	g.Expect(edits[0].sha).To(Equal("s1"))
+	g.Expect(edits[0].content).To(ContainSubstring(`"requests.cpu"    = "2"`))
+	g.Expect(edits[0].content).To(ContainSubstring(`pods              = 20`), "numbers stay unquoted")
+	g.Expect(edits[0].content).To(ContainSubstring(`"requests.memory" = "1Gi"`))
+	g.Expect(edits[0].content).To(ContainSubstring("# CPU is reviewed every quarter"))
+}
+
+func TestApplyChangesToTerraform_ShortNameMatchesRequests(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{{path: "main.tf", content: fmt.Sprintf(quotaTF, `{ cpu = "1" }`)}}
+
+	edits, err := applyChangesToTerraform(files, "main.tf", "team-a", "my-quota", tfLimits("requests.cpu", "3"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(edits[0].content).To(ContainSubstring(`cpu = "3"`))
+}
+
+func TestApplyChangesToTerraform_Local(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{
+		{path: "m/main.tf", content: fmt.Sprintf(quotaTF, `local.team_quota`)},
+		{path: "m/locals.tf", sha: "l1", content: `locals {
+  team_quota = {
+    "requests.cpu" = "1"
+  }
+}
+`},
+	}
+
+	edits, err := applyChangesToTerraform(files, "m/main.tf", "team-a", "my-quota", tfLimits("requests.cpu", "4"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(edits).To(HaveLen(1))
+	g.Expect(edits[0].path).To(Equal("m/locals.tf"))
+	g.Expect(edits[0].content).To(ContainSubstring(`"requests.cpu" = "4"`))
+}
+
+func TestApplyChangesToTerraform_VariableFromTfvars(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{
+		{path: "m/main.tf", content: fmt.Sprintf(quotaTF, `{
+      "requests.cpu"    = var.cpu
+      "requests.memory" = "1Gi"
+    }`)},
+		{path: "m/variables.tf", content: `variable "cpu" {
+  type    = string
+  default = "1"
+}
+`},
+		{path: "m/terraform.tfvars", content: `cpu = "2"
+`},
+	}
+
+	edits, err := applyChangesToTerraform(files, "m/main.tf", "team-a", "my-quota",
+		tfLimits("requests.cpu", "5", "requests.memory", "2Gi"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	byPath := editsByPath(edits)
+	g.Expect(byPath).To(HaveLen(2))
+	g.Expect(byPath["m/terraform.tfvars"]).To(Equal("cpu = \"5\"\n"))
+	g.Expect(byPath["m/main.tf"]).To(ContainSubstring(`"requests.cpu"    = var.cpu`), "the reference is kept")
+	g.Expect(byPath["m/main.tf"]).To(ContainSubstring(`"requests.memory" = "2Gi"`))
+	g.Expect(byPath).NotTo(HaveKey("m/variables.tf"), "the default is only a fallback")
+}
+
+func TestApplyChangesToTerraform_VariableDefault(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{
+		{path: "main.tf", content: fmt.Sprintf(quotaTF, `var.quota`)},
+		{path: "variables.tf", content: `variable "quota" {
+  type = map(string)
+  default = {
+    "requests.cpu" = "1"
+  }
+}
+`},
+	}
+
+	edits, err := applyChangesToTerraform(files, "main.tf", "team-a", "my-quota", tfLimits("requests.cpu", "6"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(edits).To(HaveLen(1))
+	g.Expect(edits[0].path).To(Equal("variables.tf"))
+	g.Expect(edits[0].content).To(ContainSubstring(`"requests.cpu" = "6"`))
+}
+
+func TestApplyChangesToTerraform_AmbiguousTfvars(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{
+		{path: "main.tf", content: fmt.Sprintf(quotaTF, `{ "requests.cpu" = var.cpu }`)},
+		{path: "dev.tfvars", content: `cpu = "1"`},
+		{path: "prod.tfvars", content: `cpu = "8"`},
+	}
+
+	_, err := applyChangesToTerraform(files, "main.tf", "team-a", "my-quota", tfLimits("requests.cpu", "2"))
+
+	g.Expect(err).To(MatchError(ContainSubstring("several tfvars files")))
+}
+
+func TestApplyChangesToTerraform_InsertsMissingKey(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{{path: "main.tf", content: fmt.Sprintf(quotaTF, `{
+      "requests.cpu" = "1"
+    }`)}}
+
+	edits, err := applyChangesToTerraform(files, "main.tf", "team-a", "my-quota",
+		tfLimits("requests.cpu", "1", "requests.memory", "4Gi"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(edits).To(HaveLen(1))
+	g.Expect(edits[0].content).To(ContainSubstring("\"requests.cpu\"    = \"1\"\n      \"requests.memory\" = \"4Gi\"\n    }"))
+}
+
+func TestApplyChangesToTerraform_Unsupported(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{{path: "main.tf", content: fmt.Sprintf(quotaTF, `{ "requests.cpu" = "${var.n}000m" }`)}}
+
+	_, err := applyChangesToTerraform(files, "main.tf", "team-a", "my-quota", tfLimits("requests.cpu", "2"))
+
+	g.Expect(err).To(MatchError(errTerraformUnsupported))
+}
+
+func TestApplyChangesToTerraform_Unchanged(t *testing.T) {
+	g := NewWithT(t)
+	files := []terraformFile{{path: "main.tf", content: fmt.Sprintf(quotaTF, `{ "requests.cpu" = "2" }`)}}
+
+	edits, err := applyChangesToTerraform(files, "main.tf", "team-a", "my-quota", tfLimits("requests.cpu", "2"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(edits).To(BeEmpty())
+}
+
+// TestCreatePR_TerraformCommitsModuleAtomically covers the path where the
+// quota's value lives in a tfvars file: both files have to land in a single
+// commit made through the git data API.
+func TestCreatePR_TerraformCommitsModuleAtomically(t *testing.T) {
+	g := NewWithT(t)
+	dir := "/repos/o/r/contents/managed-resources/cluster/team-a"
+	files := map[string]string{
+		"main.tf": fmt.Sprintf(quotaTF, `{
+      "requests.cpu"    = var.cpu
+      "requests.memory" = "1Gi"
+    }`),
+		"terraform.tfvars": "cpu = \"1\"\n",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"default_branch": "main"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "base-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"ref": "refs/heads/new-branch"}`)
+	})
+	var updatedRef string
+	mux.HandleFunc("/repos/o/r/git/refs/heads/resize/", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodPatch))
+		var body struct {
+			SHA string `json:"sha"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		updatedRef = body.SHA
+		_, _ = fmt.Fprint(w, `{"object": {"sha": "commit-sha"}}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/commits/base-sha", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"sha": "base-sha", "tree": {"sha": "base-tree"}}`)
+	})
+	var treeBody struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"tree"`
+	}
+	mux.HandleFunc("/repos/o/r/git/trees", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(json.NewDecoder(r.Body).Decode(&treeBody)).To(Succeed())
+		_, _ = fmt.Fprint(w, `{"sha": "new-tree"}`)
+	})
+	mux.HandleFunc("/repos/o/r/git/commits", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"sha": "commit-sha"}`)
+	})
+	mux.HandleFunc(dir, func(w http.ResponseWriter, r *http.Request) {
+		var entries []string
+		for name := range files {
+			entries = append(entries, fmt.Sprintf(
+				`{"name": %q, "path": "managed-resources/cluster/team-a/%s", "type": "file"}`, name, name))
+		}
+		_, _ = fmt.Fprintf(w, "[%s]", strings.Join(entries, ","))
+	})
+	for name, content := range files {
+		mux.HandleFunc(dir+"/"+name, func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(r.Method).To(Equal(http.MethodGet), "multi-file edits must not use the contents API")
+			_, _ = fmt.Fprintf(w, `{"content": %q, "encoding": "base64", "sha": "sha-%s"}`,
+				base64.StdEncoding.EncodeToString([]byte(content)), name)
+		})
+	}
+	var prBody string
+	mux.HandleFunc("/repos/o/r/pulls", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Body string `json:"body"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		prBody = body.Body
+		_, _ = fmt.Fprint(w, `{"number": 9, "state": "open"}`)
+	})
+	mux.HandleFunc("/repos/o/r/issues/9/labels", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[]`)
+	})
+	provider, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	prID, err := provider.CreatePR(context.Background(), "my-quota", "team-a", DirectionGrow, nil,
+		tfLimits("requests.cpu", "2", "requests.memory", "2Gi"))
+
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(prID).To(Equal(9))
+	g.Expect(treeBody.BaseTree).To(Equal("base-tree"))
+	g.Expect(treeBody.Tree).To(HaveLen(2))
+	g.Expect(updatedRef).To(Equal("commit-sha"))
+	g.Expect(prBody).To(ContainSubstring("terraform apply"))
+}
@@ -0,0 +1,124 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// UnmappedError is returned when no manifest in the repository declares a
+// quota. It matches ErrFileNotFound.
+type UnmappedError struct {
+	Quota string
+	// Paths are the directories searched and the files read in them.
+	Paths []string
+	// Err is the failure to list the directory, if it does not exist.
+	Err error
+}
+
+func (e *UnmappedError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v: %v", ErrFileNotFound, e.Err)
+	}
+	return fmt.Sprintf("%v: quota %s not found in %s", ErrFileNotFound, e.Quota, strings.Join(e.Paths, ", "))
+}
+
+// Is makes errors.Is(err, ErrFileNotFound) hold.
+func (e *UnmappedError) Is(target error) bool { return target == ErrFileNotFound }
+
+// UnmappedReporter is implemented by providers that can file an issue about
+// a quota whose manifest cannot be found.
+type UnmappedReporter interface {
+	// ReportUnmapped opens an issue listing the paths searched for the
+	// quota's manifest, or returns the one already open.
+	ReportUnmapped(ctx context.Context, namespace, quotaName string, paths []string) (int, error)
+	// ResolveUnmapped closes the issue once the manifest was found.
+	ResolveUnmapped(ctx context.Context, issue int) error
+	// WithdrawUnmapped closes the issue once the quota no longer needs a
+	// change: it was deleted, its namespace opted out, or its proposal was
+	// dropped.
+	WithdrawUnmapped(ctx context.Context, issue int) error
+}
+
+// labelUnmapped marks the issues about quotas without a manifest.
+const labelUnmapped = "resizer/unmapped"
+
+const (
+	unmappedResolvedComment  = "The quota's manifest was found. Closing this issue."
+	unmappedWithdrawnComment = "The quota no longer needs a change. Closing this issue; " +
+		"a new one is opened if it needs one again and its manifest is still missing."
+)
+
+func unmappedMarker(namespace, quotaName string) string {
+	return "<!-- resizer:unmapped " + digestKey(namespace, quotaName) + " -->"
+}
+
+// ReportUnmapped implements UnmappedReporter.
+func (g *GitHubProvider) ReportUnmapped(ctx context.Context, namespace, quotaName string, paths []string) (int, error) {
+	marker := unmappedMarker(namespace, quotaName)
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{labelManaged, labelUnmapped},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := g.client.Issues.ListByRepo(ctx, g.owner, g.repo, opts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list issues: %w", err)
+		}
+		for _, issue := range issues {
+			if !issue.IsPullRequest() && strings.Contains(issue.GetBody(), marker) &&
+				clusterFromLabels(issue.Labels) == g.clusterName {
+				return issue.GetNumber(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.ListOptions.Page = resp.NextPage
+	}
+
+	labels := []string{labelManaged, labelUnmapped}
+	if g.clusterName != "" {
+		labels = append(labels, labelNamespacePrefix+g.clusterName+"/"+namespace, labelClusterPrefix+g.clusterName)
+	} else {
+		labels = append(labels, labelNamespacePrefix+namespace)
+	}
+	request := &github.IssueRequest{
+		Title:  github.Ptr(fmt.Sprintf("Manifest of quota %s in %s not found", quotaName, namespace)),
+		Body:   github.Ptr(generateUnmappedBody(namespace, quotaName, paths) + "\n" + marker),
+		Labels: &labels,
+	}
+	issue, _, err := g.client.Issues.Create(ctx, g.owner, g.repo, request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open issue: %w", err)
+	}
+	return issue.GetNumber(), nil
+}
+
+// ResolveUnmapped implements UnmappedReporter.
+func (g *GitHubProvider) ResolveUnmapped(ctx context.Context, issue int) error {
+	return g.closeIssue(ctx, issue, unmappedResolvedComment, "completed")
+}
+
+// WithdrawUnmapped implements UnmappedReporter.
+func (g *GitHubProvider) WithdrawUnmapped(ctx context.Context, issue int) error {
+	return g.closeIssue(ctx, issue, unmappedWithdrawnComment, "not_planned")
+}
+
+func generateUnmappedBody(namespace, quotaName string, paths []string) string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "### No manifest found for `%s` in `%s`\n\n", quotaName, namespace)
+	sb.WriteString("The Namespace Resizer Controller has a recommendation for this quota, ")
+	sb.WriteString("but cannot find the manifest declaring it. Searched:\n\n")
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(&sb, "* `%s`\n", path)
+	}
+	sb.WriteString("\nAdd the quota's manifest there, or point the `resizer.io/git-path` annotation ")
+	sb.WriteString("of the namespace at the directory that holds it. ")
+	sb.WriteString("This issue is closed automatically once the manifest is found.\n")
+	sb.WriteString("\n*Generated automatically by Namespace Resizer*")
+	return sb.String()
+}
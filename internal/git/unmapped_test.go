@@ -0,0 +1,96 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCreatePR_UnmappedListsSearchedPaths(t *testing.T) {
+	g := NewWithT(t)
+
+	mux := http.NewServeMux()
+	setupCreatePRRoutes(g, mux, 42)
+	gh, teardown := newTestProvider(t, mux)
+	defer teardown()
+
+	_, err := gh.CreatePR(context.Background(), "other-quota", "default", DirectionGrow, nil, createPRLimits())
+
+	g.Expect(errors.Is(err, ErrFileNotFound)).To(BeTrue())
+	var unmapped *UnmappedError
+	g.Expect(errors.As(err, &unmapped)).To(BeTrue())
+	g.Expect(unmapped.Quota).To(Equal("other-quota"))
+	g.Expect(unmapped.Paths).To(Equal([]string{
+		"managed-resources/cluster/default",
+		"managed-resources/cluster/default/quota.yaml",
+	}))
+}
+
+func TestReportUnmapped(t *testing.T) {
+	cases := map[string]struct {
+		open    string
+		created bool
+		want    int
+	}{
+		"opens an issue": {open: `[]`, created: true, want: 8},
+		"reuses the open issue": {
+			open: fmt.Sprintf(`[{"number": 5, "body": "x\n%s", "labels": [{"name": %q}]}]`,
+				jsonEscape(unmappedMarker("team-a", "compute")), labelClusterPrefix+"cluster"),
+			want: 5,
+		},
+		"ignores other clusters": {
+			open: fmt.Sprintf(`[{"number": 5, "body": "x\n%s", "labels": [{"name": %q}]}]`,
+				jsonEscape(unmappedMarker("team-a", "compute")), labelClusterPrefix+"elsewhere"),
+			created: true, want: 8,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var request struct {
+				Title  string   `json:"title"`
+				Body   string   `json:"body"`
+				Labels []string `json:"labels"`
+			}
+			created := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/o/r/issues", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					g.Expect(r.URL.Query().Get("labels")).To(Equal(labelManaged + "," + labelUnmapped))
+					_, _ = fmt.Fprint(w, tc.open)
+					return
+				}
+				created = true
+				g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+				_, _ = fmt.Fprint(w, `{"number": 8}`)
+			})
+			gh, teardown := newTestProvider(t, mux)
+			defer teardown()
+
+			issue, err := gh.ReportUnmapped(context.Background(), "team-a", "compute",
+				[]string{"managed-resources/cluster/team-a"})
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(issue).To(Equal(tc.want))
+			g.Expect(created).To(Equal(tc.created))
+			if tc.created {
+				g.Expect(request.Title).To(Equal("Manifest of quota compute in team-a not found"))
+				g.Expect(request.Labels).To(ConsistOf(labelManaged, labelUnmapped,
+					labelNamespacePrefix+"cluster/team-a", labelClusterPrefix+"cluster"))
+				g.Expect(request.Body).To(ContainSubstring("* `managed-resources/cluster/team-a`"))
+				g.Expect(request.Body).To(HaveSuffix(unmappedMarker("team-a", "compute")))
+			}
+		})
+	}
+}
+
+func jsonEscape(s string) string {
+	out, _ := json.Marshal(s)
+	return string(out[1 : len(out)-1])
+}
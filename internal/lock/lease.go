@@ -24,6 +24,40 @@ const (
 	AnnotationLastShrink = "resizer.io/last-shrink"
 	// AnnotationPRDirection records whether the open PR grows or shrinks.
 	AnnotationPRDirection = "resizer.io/pr-direction"
+	// AnnotationPRRepository records the "owner/repo" the open PR lives in.
+	AnnotationPRRepository = "resizer.io/pr-repository"
+	// AnnotationGrowIdleSince records since when an open grow PR has not been
+	// needed by any sizing decision.
+	AnnotationGrowIdleSince = "resizer.io/grow-idle-since"
+	// AnnotationPRBatch marks a PR shared by several quotas of the namespace.
+	AnnotationPRBatch = "resizer.io/pr-batch"
+	// AnnotationPRDigest marks a PR shared by quotas of several namespaces.
+	AnnotationPRDigest = "resizer.io/pr-digest"
+	// AnnotationPRLimits records the limits an open issue or draft proposes.
+	AnnotationPRLimits = "resizer.io/pr-limits"
+	// AnnotationPRDraft marks a draft PR still soaking; AnnotationPRReady
+	// stores when it was marked ready for review.
+	AnnotationPRDraft = "resizer.io/pr-draft"
+	AnnotationPRReady = "resizer.io/pr-ready"
+	// AnnotationDigestLimits and AnnotationDigestApplied track a shrink
+	// digest's limits for the quota until a revert can no longer be told
+	// apart from ordinary growth.
+	AnnotationDigestLimits  = "resizer.io/digest-limits"
+	AnnotationDigestApplied = "resizer.io/digest-applied"
+	// AnnotationCreateKey records the idempotency key of a PR being created.
+	AnnotationCreateKey = "resizer.io/create-key"
+	// AnnotationUnmappedSince records since when the quota's manifest cannot
+	// be found; AnnotationUnmappedIssue the issue tracking it.
+	AnnotationUnmappedSince = "resizer.io/unmapped-since"
+	AnnotationUnmappedIssue = "resizer.io/unmapped-issue"
+	// AnnotationPendingDirection, AnnotationPendingSince and
+	// AnnotationPendingLimits hold a proposal waiting for the batch debounce;
+	// AnnotationPendingReason and AnnotationPendingExclusions explain it.
+	AnnotationPendingDirection  = "resizer.io/pending-direction"
+	AnnotationPendingSince      = "resizer.io/pending-since"
+	AnnotationPendingLimits     = "resizer.io/pending-limits"
+	AnnotationPendingReason     = "resizer.io/pending-reason"
+	AnnotationPendingExclusions = "resizer.io/pending-exclusions"
 	// AnnotationWindow stores the JSON-encoded observation window.
 	AnnotationWindow = "resizer.io/observation-window"
 
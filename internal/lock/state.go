@@ -3,10 +3,12 @@ package lock
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	coordinationv1 "k8s.io/api/coordination/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -19,6 +21,58 @@ type State struct {
 	PRID int
 	// PRDirection is "grow", "shrink", or empty when no PR is open.
 	PRDirection string
+	// PRRepo is the "owner/repo" the open PR lives in. It is empty for a PR
+	// opened before repositories could be routed per namespace, and for
+	// providers that are not bound to a repository.
+	PRRepo string
+	// GrowIdleSince is when sizing stopped asking for the open grow PR. It is
+	// zero while the PR is still needed and when no grow PR is open.
+	GrowIdleSince time.Time
+	// PRBatch is true when the open PR also carries other quotas of the
+	// namespace, whose Leases point at the same PRID.
+	PRBatch bool
+	// PRDigest is true when the open PR is a shrink digest, shared with
+	// quotas of other namespaces too.
+	PRDigest bool
+	// PRLimits is the raw JSON of the limits the open PR proposes. It is only
+	// recorded in issue mode, where the controller resolves the issue itself
+	// once the quota carries them, and for drafts, whose soak compares later
+	// decisions against them.
+	PRLimits string
+	// PRDraft is true while the open PR is a draft soaking before review.
+	// PRReady is when it was marked ready for review; it is zero for PRs
+	// that were never drafts.
+	PRDraft bool
+	PRReady time.Time
+
+	// DigestLimits is the raw JSON of the limits a shrink digest proposed for
+	// the quota. It is kept after the digest is merged, and DigestApplied
+	// records when the cluster first carried those limits, so that a later
+	// revert of the quota's manifest can be told from the merge not having
+	// synced yet.
+	DigestLimits  string
+	DigestApplied time.Time
+
+	// PendingDirection, PendingSince and PendingLimits describe a proposal
+	// waiting to be batched with the namespace's other quotas. PendingLimits
+	// is raw JSON the lock package does not interpret, like Window.
+	PendingDirection string
+	PendingSince     time.Time
+	PendingLimits    string
+	// PendingReason and PendingExclusions carry the decision's explanation
+	// to the batched PR, one line per entry.
+	PendingReason     string
+	PendingExclusions string
+	// CreateKey is the idempotency key of a PR being created. It is recorded
+	// before the first side effect and names the PR's branch, so that a
+	// creation interrupted by a crash or a leader change is resumed instead
+	// of duplicated. It is cleared once the PR is recorded.
+	CreateKey string
+	// UnmappedSince is when the quota's manifest was first not found in the
+	// repository, zero while it is found. UnmappedIssue is the issue opened
+	// about it, 0 if none.
+	UnmappedSince time.Time
+	UnmappedIssue int
 
 	LastModified time.Time
 	LastGrow     time.Time
@@ -29,6 +83,36 @@ type State struct {
 	Window string
 }
 
+// ReleasePR frees the lock and forgets everything recorded about the pull
+// request that held it. The timestamps that outlive a pull request (cooldowns,
+// the window) are the caller's to set.
+func (s *State) ReleasePR() {
+	s.PRID = 0
+	s.PRDirection = ""
+	s.PRRepo = ""
+	s.GrowIdleSince = time.Time{}
+	s.PRBatch = false
+	s.PRDigest = false
+	s.PRLimits = ""
+	s.PRDraft = false
+	s.PRReady = time.Time{}
+}
+
+// ForgetDigest stops watching a shrink digest's limits.
+func (s *State) ForgetDigest() {
+	s.DigestLimits = ""
+	s.DigestApplied = time.Time{}
+}
+
+// ClearPending drops a proposal waiting for the batch debounce.
+func (s *State) ClearPending() {
+	s.PendingDirection = ""
+	s.PendingSince = time.Time{}
+	s.PendingLimits = ""
+	s.PendingReason = ""
+	s.PendingExclusions = ""
+}
+
 // GetState reads the full state in a single API call. A missing Lease yields
 // the zero State, which is the correct starting point for a new quota.
 func (l *LeaseLocker) GetState(ctx context.Context, targetNS, quotaName string) (State, error) {
@@ -45,6 +129,42 @@ func (l *LeaseLocker) GetState(ctx context.Context, targetNS, quotaName string)
 	return stateFromLease(&lease), nil
 }
 
+// ListStates reads the state of every quota of targetNS that has a Lease,
+// keyed by quota name.
+func (l *LeaseLocker) ListStates(ctx context.Context, targetNS string) (map[string]State, error) {
+	all, err := l.listStates(ctx, client.MatchingLabels{labelManagedBy: managedByValue, labelTargetNS: targetNS})
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]State, len(all))
+	for key, state := range all {
+		states[key.Name] = state
+	}
+	return states, nil
+}
+
+// ListAllStates reads the state of every quota in the cluster that has a
+// Lease, keyed by namespace and quota name.
+func (l *LeaseLocker) ListAllStates(ctx context.Context) (map[types.NamespacedName]State, error) {
+	return l.listStates(ctx, client.MatchingLabels{labelManagedBy: managedByValue})
+}
+
+func (l *LeaseLocker) listStates(ctx context.Context, labels client.MatchingLabels) (map[types.NamespacedName]State, error) {
+	var leases coordinationv1.LeaseList
+	if err := l.client.List(ctx, &leases, client.InNamespace(ControllerNamespace), labels); err != nil {
+		return nil, err
+	}
+	states := make(map[types.NamespacedName]State, len(leases.Items))
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		key := types.NamespacedName{Namespace: lease.Labels[labelTargetNS], Name: lease.Labels[labelQuota]}
+		if key.Namespace != "" && key.Name != "" {
+			states[key] = stateFromLease(lease)
+		}
+	}
+	return states, nil
+}
+
 // MutateState applies fn to the current state and writes the result back,
 // retrying on optimistic-concurrency conflicts. The Lease is created if it does
 // not exist yet, so callers need no separate bootstrap step.
@@ -77,10 +197,30 @@ func (l *LeaseLocker) MutateState(
 func stateFromLease(lease *coordinationv1.Lease) State {
 	state := State{
 		PRDirection:  lease.Annotations[AnnotationPRDirection],
+		PRRepo:       lease.Annotations[AnnotationPRRepository],
 		Window:       lease.Annotations[AnnotationWindow],
 		LastModified: parseStamp(lease.Annotations[AnnotationLastModified]),
 		LastGrow:     parseStamp(lease.Annotations[AnnotationLastGrow]),
 		LastShrink:   parseStamp(lease.Annotations[AnnotationLastShrink]),
+
+		GrowIdleSince: parseStamp(lease.Annotations[AnnotationGrowIdleSince]),
+		PRBatch:       lease.Annotations[AnnotationPRBatch] == "true",
+		PRDigest:      lease.Annotations[AnnotationPRDigest] == "true",
+		PRLimits:      lease.Annotations[AnnotationPRLimits],
+		PRDraft:       lease.Annotations[AnnotationPRDraft] == "true",
+		PRReady:       parseStamp(lease.Annotations[AnnotationPRReady]),
+		DigestLimits:  lease.Annotations[AnnotationDigestLimits],
+		DigestApplied: parseStamp(lease.Annotations[AnnotationDigestApplied]),
+
+		PendingDirection:  lease.Annotations[AnnotationPendingDirection],
+		PendingSince:      parseStamp(lease.Annotations[AnnotationPendingSince]),
+		PendingLimits:     lease.Annotations[AnnotationPendingLimits],
+		PendingReason:     lease.Annotations[AnnotationPendingReason],
+		PendingExclusions: lease.Annotations[AnnotationPendingExclusions],
+		CreateKey:         lease.Annotations[AnnotationCreateKey],
+
+		UnmappedSince: parseStamp(lease.Annotations[AnnotationUnmappedSince]),
+		UnmappedIssue: parseInt(lease.Annotations[AnnotationUnmappedIssue]),
 	}
 	if lease.Spec.HolderIdentity != nil {
 		var id int
@@ -98,8 +238,25 @@ func applyStateToLease(state *State, lease *coordinationv1.Lease) {
 	setStamp(lease.Annotations, AnnotationLastModified, state.LastModified)
 	setStamp(lease.Annotations, AnnotationLastGrow, state.LastGrow)
 	setStamp(lease.Annotations, AnnotationLastShrink, state.LastShrink)
+	setStamp(lease.Annotations, AnnotationGrowIdleSince, state.GrowIdleSince)
 	setString(lease.Annotations, AnnotationPRDirection, state.PRDirection)
+	setString(lease.Annotations, AnnotationPRRepository, state.PRRepo)
 	setString(lease.Annotations, AnnotationWindow, state.Window)
+	setStamp(lease.Annotations, AnnotationPendingSince, state.PendingSince)
+	setString(lease.Annotations, AnnotationPendingDirection, state.PendingDirection)
+	setString(lease.Annotations, AnnotationPendingLimits, state.PendingLimits)
+	setString(lease.Annotations, AnnotationPendingReason, state.PendingReason)
+	setString(lease.Annotations, AnnotationPendingExclusions, state.PendingExclusions)
+	setString(lease.Annotations, AnnotationCreateKey, state.CreateKey)
+	setStamp(lease.Annotations, AnnotationUnmappedSince, state.UnmappedSince)
+	setInt(lease.Annotations, AnnotationUnmappedIssue, state.UnmappedIssue)
+	setFlag(lease.Annotations, AnnotationPRBatch, state.PRBatch)
+	setFlag(lease.Annotations, AnnotationPRDigest, state.PRDigest)
+	setString(lease.Annotations, AnnotationPRLimits, state.PRLimits)
+	setFlag(lease.Annotations, AnnotationPRDraft, state.PRDraft)
+	setStamp(lease.Annotations, AnnotationPRReady, state.PRReady)
+	setString(lease.Annotations, AnnotationDigestLimits, state.DigestLimits)
+	setStamp(lease.Annotations, AnnotationDigestApplied, state.DigestApplied)
 
 	if state.PRID == 0 {
 		lease.Spec.HolderIdentity = nil
@@ -120,6 +277,14 @@ func parseStamp(raw string) time.Time {
 	return stamp
 }
 
+func parseInt(raw string) int {
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
 func setStamp(annotations map[string]string, key string, value time.Time) {
 	if value.IsZero() {
 		delete(annotations, key)
@@ -128,6 +293,14 @@ func setStamp(annotations map[string]string, key string, value time.Time) {
 	annotations[key] = value.UTC().Format(time.RFC3339)
 }
 
+func setFlag(annotations map[string]string, key string, value bool) {
+	if !value {
+		delete(annotations, key)
+		return
+	}
+	annotations[key] = "true"
+}
+
 func setString(annotations map[string]string, key, value string) {
 	if value == "" {
 		delete(annotations, key)
@@ -135,3 +308,11 @@ func setString(annotations map[string]string, key, value string) {
 	}
 	annotations[key] = value
 }
+
+func setInt(annotations map[string]string, key string, value int) {
+	if value == 0 {
+		delete(annotations, key)
+		return
+	}
+	annotations[key] = strconv.Itoa(value)
+}
@@ -9,6 +9,7 @@ import (
 	coordinationv1 "k8s.io/api/coordination/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -44,6 +45,22 @@ func TestMutateState_RoundTrip(t *testing.T) {
 	err := locker.MutateState(ctx, testNamespace, testQuotaName, func(s *State) {
 		s.PRID = 42
 		s.PRDirection = "shrink"
+		s.PRRepo = "org/tenants"
+		s.GrowIdleSince = shrunkAt
+		s.PRBatch = true
+		s.PRDigest = true
+		s.PRLimits = `{"requests.cpu":"4"}`
+		s.PRDraft = true
+		s.PRReady = grownAt
+		s.DigestLimits = `{"requests.cpu":"2"}`
+		s.DigestApplied = modifiedAt
+		s.PendingDirection = "grow"
+		s.PendingSince = grownAt
+		s.PendingLimits = `{"requests.cpu":"8"}`
+		s.PendingReason = "- `requests.cpu`: 6 -> 8 (driven by projected demand)"
+		s.CreateKey = "0f3a9c1d2e4b5a68"
+		s.UnmappedSince = modifiedAt
+		s.UnmappedIssue = 12
 		s.LastModified = modifiedAt
 		s.LastGrow = grownAt
 		s.LastShrink = shrunkAt
@@ -55,6 +72,22 @@ func TestMutateState_RoundTrip(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(state.PRID).To(Equal(42))
 	g.Expect(state.PRDirection).To(Equal("shrink"))
+	g.Expect(state.PRRepo).To(Equal("org/tenants"))
+	g.Expect(state.GrowIdleSince.Equal(shrunkAt)).To(BeTrue())
+	g.Expect(state.PRBatch).To(BeTrue())
+	g.Expect(state.PRDigest).To(BeTrue())
+	g.Expect(state.PRLimits).To(Equal(`{"requests.cpu":"4"}`))
+	g.Expect(state.PRDraft).To(BeTrue())
+	g.Expect(state.PRReady.Equal(grownAt)).To(BeTrue())
+	g.Expect(state.DigestLimits).To(Equal(`{"requests.cpu":"2"}`))
+	g.Expect(state.DigestApplied.Equal(modifiedAt)).To(BeTrue())
+	g.Expect(state.PendingDirection).To(Equal("grow"))
+	g.Expect(state.PendingSince.Equal(grownAt)).To(BeTrue())
+	g.Expect(state.PendingLimits).To(Equal(`{"requests.cpu":"8"}`))
+	g.Expect(state.PendingReason).To(Equal("- `requests.cpu`: 6 -> 8 (driven by projected demand)"))
+	g.Expect(state.CreateKey).To(Equal("0f3a9c1d2e4b5a68"))
+	g.Expect(state.UnmappedSince.Equal(modifiedAt)).To(BeTrue())
+	g.Expect(state.UnmappedIssue).To(Equal(12))
 	g.Expect(state.LastModified.Equal(modifiedAt)).To(BeTrue())
 	g.Expect(state.LastGrow.Equal(grownAt)).To(BeTrue())
 	g.Expect(state.LastShrink.Equal(shrunkAt)).To(BeTrue())
@@ -69,11 +102,11 @@ func TestMutateState_ClearingPRIDReleasesTheLock(t *testing.T) {
 	g.Expect(locker.MutateState(ctx, testNamespace, testQuotaName, func(s *State) {
 		s.PRID = 7
 		s.PRDirection = "grow"
+		s.GrowIdleSince = time.Date(2026, 8, 7, 12, 0, 0, 0, time.UTC)
 	})).To(Succeed())
 
 	g.Expect(locker.MutateState(ctx, testNamespace, testQuotaName, func(s *State) {
-		s.PRID = 0
-		s.PRDirection = ""
+		s.ReleasePR()
 		s.LastShrink = time.Date(2026, 8, 8, 12, 0, 0, 0, time.UTC)
 	})).To(Succeed())
 
@@ -91,6 +124,33 @@ func TestMutateState_ClearingPRIDReleasesTheLock(t *testing.T) {
 	}
 	g.Expect(c.Get(ctx, key, &lease)).To(Succeed())
 	g.Expect(lease.Annotations).NotTo(HaveKey(AnnotationPRDirection))
+	g.Expect(lease.Annotations).NotTo(HaveKey(AnnotationGrowIdleSince))
+}
+
+func TestListStates(t *testing.T) {
+	g := NewWithT(t)
+	locker, _ := newStateLocker()
+	ctx := context.Background()
+
+	for quota, prID := range map[string]int{"compute": 5, "storage": 5} {
+		g.Expect(locker.MutateState(ctx, testNamespace, quota, func(s *State) {
+			s.PRID = prID
+		})).To(Succeed())
+	}
+	g.Expect(locker.MutateState(ctx, "other-ns", "compute", func(s *State) {
+		s.PRID = 9
+	})).To(Succeed())
+
+	states, err := locker.ListStates(ctx, testNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(states).To(HaveLen(2))
+	g.Expect(states["compute"].PRID).To(Equal(5))
+	g.Expect(states["storage"].PRID).To(Equal(5))
+
+	all, err := locker.ListAllStates(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(all).To(HaveLen(3))
+	g.Expect(all[types.NamespacedName{Namespace: "other-ns", Name: "compute"}].PRID).To(Equal(9))
 }
 
 func TestMutateState_PreservesExistingLastModified(t *testing.T) {
@@ -0,0 +1,233 @@
+// Package ownership maps namespaces to the people who review their quota
+// proposals. The rules are read from a ConfigMap in the controller namespace
+// and may derive reviewers from the namespace's labels and annotations, so
+// one rule can serve every team that labels its namespaces the same way.
+package ownership
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// RulesKey is the ConfigMap data key holding the reviewer rules.
+const RulesKey = "reviewers.yaml"
+
+// Set is one set of reviewers. Every entry is a template over .Namespace,
+// .Labels and .Annotations of the namespace; entries rendering empty, such
+// as a label the namespace does not carry, are dropped.
+type Set struct {
+	Users []string `json:"users,omitempty"`
+	// Teams are team slugs, or "@org/slug".
+	Teams     []string `json:"teams,omitempty"`
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+// Rule gives the namespaces matching NamespaceSelector their reviewers.
+type Rule struct {
+	Name string `json:"name"`
+	// NamespaceSelector matches the labels of the namespace. An empty
+	// selector matches every namespace.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	// Reviewers review both grow and shrink pull requests, unless Grow or
+	// Shrink replaces them for that direction.
+	Reviewers *Set `json:"reviewers,omitempty"`
+	Grow      *Set `json:"grow,omitempty"`
+	Shrink    *Set `json:"shrink,omitempty"`
+	// CodeOwners also requests the owners of the changed manifests from the
+	// repository's CODEOWNERS file.
+	CodeOwners bool `json:"codeOwners,omitempty"`
+
+	selector labels.Selector
+	compiled map[*Set]compiledSet
+}
+
+type compiledSet struct {
+	users, teams, assignees []*template.Template
+}
+
+type ruleTable struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Parse reads and validates a rule table, templates included.
+func Parse(data string) ([]Rule, error) {
+	var table ruleTable
+	if err := yaml.UnmarshalStrict([]byte(data), &table); err != nil {
+		return nil, fmt.Errorf("invalid reviewer rules: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range table.Rules {
+		rule := &table.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		selector, err := metav1.LabelSelectorAsSelector(&rule.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: invalid namespaceSelector: %w", rule.Name, err)
+		}
+		rule.selector = selector
+
+		rule.compiled = map[*Set]compiledSet{}
+		for _, set := range []*Set{rule.Reviewers, rule.Grow, rule.Shrink} {
+			if set == nil {
+				continue
+			}
+			compiled, err := compile(set)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			rule.compiled[set] = compiled
+		}
+	}
+	return table.Rules, nil
+}
+
+func compile(set *Set) (compiledSet, error) {
+	var compiled compiledSet
+	for _, field := range []struct {
+		entries []string
+		into    *[]*template.Template
+	}{
+		{set.Users, &compiled.users},
+		{set.Teams, &compiled.teams},
+		{set.Assignees, &compiled.assignees},
+	} {
+		for _, entry := range field.entries {
+			tmpl, err := template.New("reviewer").Option("missingkey=zero").Parse(entry)
+			if err != nil {
+				return compiledSet{}, fmt.Errorf("invalid reviewer %q: %w", entry, err)
+			}
+			*field.into = append(*field.into, tmpl)
+		}
+	}
+	return compiled, nil
+}
+
+// templateData is what reviewer templates see.
+type templateData struct {
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// reviewers renders the rule's reviewers of direction for ns.
+func (rule *Rule) reviewers(ns *corev1.Namespace, direction string) (git.Reviewers, error) {
+	set := rule.Reviewers
+	switch {
+	case direction == git.DirectionGrow && rule.Grow != nil:
+		set = rule.Grow
+	case direction == git.DirectionShrink && rule.Shrink != nil:
+		set = rule.Shrink
+	}
+	reviewers := git.Reviewers{CodeOwners: rule.CodeOwners}
+	if set == nil {
+		return reviewers, nil
+	}
+
+	data := templateData{Namespace: ns.Name, Labels: ns.Labels, Annotations: ns.Annotations}
+	compiled := rule.compiled[set]
+	var err error
+	if reviewers.Users, err = render(compiled.users, data); err != nil {
+		return git.Reviewers{}, err
+	}
+	if reviewers.Teams, err = render(compiled.teams, data); err != nil {
+		return git.Reviewers{}, err
+	}
+	if reviewers.Assignees, err = render(compiled.assignees, data); err != nil {
+		return git.Reviewers{}, err
+	}
+	return reviewers, nil
+}
+
+func render(templates []*template.Template, data templateData) ([]string, error) {
+	var out []string
+	for _, tmpl := range templates {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render reviewer: %w", err)
+		}
+		if value := strings.TrimSpace(buf.String()); value != "" {
+			out = append(out, value)
+		}
+	}
+	return out, nil
+}
+
+// Resolver resolves the reviewers of a namespace from the rule table. It is
+// safe for concurrent use.
+type Resolver struct {
+	client    client.Reader
+	configMap string
+
+	mu       sync.Mutex
+	rulesRV  string
+	rules    []Rule
+	rulesErr error
+}
+
+// NewResolver returns a Resolver reading the ConfigMap configMap in the
+// controller namespace.
+func NewResolver(c client.Reader, configMap string) *Resolver {
+	return &Resolver{client: c, configMap: configMap}
+}
+
+// ReviewersFor returns the reviewers of the first rule matching ns, for a
+// pull request of direction. No matching rule, or no ConfigMap, means no
+// reviewers.
+func (r *Resolver) ReviewersFor(ctx context.Context, ns *corev1.Namespace, direction string) (git.Reviewers, error) {
+	var cm corev1.ConfigMap
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: lock.ControllerNamespace, Name: r.configMap}, &cm)
+	if errors.IsNotFound(err) {
+		return git.Reviewers{}, nil
+	}
+	if err != nil {
+		return git.Reviewers{}, fmt.Errorf("failed to read reviewers ConfigMap %s: %w", r.configMap, err)
+	}
+
+	rules, err := r.parse(&cm)
+	if err != nil {
+		return git.Reviewers{}, fmt.Errorf("reviewers ConfigMap %s: %w", r.configMap, err)
+	}
+	for i := range rules {
+		if rules[i].selector.Matches(labels.Set(ns.Labels)) {
+			reviewers, err := rules[i].reviewers(ns, direction)
+			if err != nil {
+				return git.Reviewers{}, fmt.Errorf("rule %q: %w", rules[i].Name, err)
+			}
+			return reviewers, nil
+		}
+	}
+	return git.Reviewers{}, nil
+}
+
+// parse returns the rules of cm, reusing the previous result while the
+// ConfigMap is unchanged.
+func (r *Resolver) parse(cm *corev1.ConfigMap) ([]Rule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cm.ResourceVersion == "" || cm.ResourceVersion != r.rulesRV {
+		r.rulesRV = cm.ResourceVersion
+		r.rules, r.rulesErr = Parse(cm.Data[RulesKey])
+	}
+	return r.rules, r.rulesErr
+}
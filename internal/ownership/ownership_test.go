@@ -0,0 +1,108 @@
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+const testRules = `
+rules:
+- name: payments
+  namespaceSelector:
+    matchLabels:
+      team: payments
+  reviewers:
+    teams: ["@org/payments"]
+    assignees: ['{{ index .Annotations "owner" }}']
+  shrink:
+    teams: ["@org/payments", finops]
+  codeOwners: true
+- name: by-team-label
+  namespaceSelector:
+    matchExpressions:
+    - {key: team, operator: Exists}
+  reviewers:
+    teams: ["{{ .Labels.team }}"]
+    users: ["{{ .Labels.lead }}"]
+`
+
+func newTestResolver(objs ...client.Object) *Resolver {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return NewResolver(c, "reviewers")
+}
+
+func rulesConfigMap(data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviewers", Namespace: lock.ControllerNamespace},
+		Data:       map[string]string{RulesKey: data},
+	}
+}
+
+func namespace(labels, annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "ns", Labels: labels, Annotations: annotations,
+	}}
+}
+
+func TestReviewersFor(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	resolver := newTestResolver(rulesConfigMap(testRules))
+	payments := namespace(map[string]string{"team": "payments"}, map[string]string{"owner": "alice"})
+
+	grow, err := resolver.ReviewersFor(ctx, payments, git.DirectionGrow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(grow).To(Equal(git.Reviewers{
+		Teams: []string{"@org/payments"}, Assignees: []string{"alice"}, CodeOwners: true,
+	}))
+
+	shrink, err := resolver.ReviewersFor(ctx, payments, git.DirectionShrink)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(shrink.Teams).To(Equal([]string{"@org/payments", "finops"}))
+	g.Expect(shrink.Assignees).To(BeEmpty(), "the shrink set replaces the default one")
+
+	// A label the namespace does not carry renders nothing.
+	search, err := resolver.ReviewersFor(ctx, namespace(map[string]string{"team": "search"}, nil), git.DirectionGrow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(search).To(Equal(git.Reviewers{Teams: []string{"search"}}))
+
+	none, err := resolver.ReviewersFor(ctx, namespace(nil, nil), git.DirectionGrow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(none.IsZero()).To(BeTrue())
+}
+
+func TestReviewersFor_NoConfigMap(t *testing.T) {
+	g := NewWithT(t)
+	reviewers, err := newTestResolver().ReviewersFor(context.Background(), namespace(nil, nil), git.DirectionGrow)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(reviewers.IsZero()).To(BeTrue())
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":  "rules:\n- name: a\n  reviewer: {users: [x]}\n",
+		"missing name":   "rules:\n- reviewers: {users: [x]}\n",
+		"duplicate name": "rules:\n- {name: a}\n- {name: a}\n",
+		"bad selector":   "rules:\n- name: a\n  namespaceSelector:\n    matchExpressions:\n    - {key: env, operator: Maybe}\n",
+		"bad template":   "rules:\n- name: a\n  shrink: {teams: ['{{ .Labels.team']}\n",
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := Parse(data)
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+// Credential keys of a route's Secret. They mirror the environment variables
+// of the global provider so a Secret can be shared with the deployment.
+const (
+	KeyToken          = "GITHUB_TOKEN"
+	KeyAppID          = "GITHUB_APP_ID"
+	KeyInstallationID = "GITHUB_INSTALLATION_ID"
+	KeyPrivateKey     = "GITHUB_PRIVATE_KEY"
+)
+
+// providerFactory builds the provider of one route, whose PathTemplate and
+// BaseBranch already fall back to the global ones. Tests replace it to avoid
+// real GitHub clients.
+type providerFactory func(route Route, clusterName string, creds map[string][]byte) (git.Provider, error)
+
+type cachedProvider struct {
+	// fingerprint is derived from the settings the provider is built from
+	// and the resource version of the credentials Secret, so rotating a token
+	// rebuilds the client while editing another route, or only the selector
+	// of this one, keeps it along with any state it holds.
+	fingerprint string
+	provider    git.Provider
+}
+
+// Router resolves the provider for a namespace from the route table. It is
+// safe for concurrent use.
+type Router struct {
+	client              client.Reader
+	configMap           string
+	clusterName         string
+	defaultPathTemplate string
+	defaultBaseBranch   string
+	fallback            git.Provider
+	newProvider         providerFactory
+
+	mu          sync.Mutex
+	routesRV    string
+	routes      []Route
+	routesErr   error
+	byRouteName map[string]cachedProvider
+}
+
+// NewRouter returns a Router reading the ConfigMap configMap in the controller
+// namespace. Namespaces no route matches, and every namespace while the
+// ConfigMap does not exist, use fallback. Routes without a path template or
+// base branch use defaultPathTemplate and defaultBaseBranch.
+func NewRouter(
+	c client.Reader,
+	configMap, clusterName, defaultPathTemplate, defaultBaseBranch string,
+	fallback git.Provider,
+) *Router {
+	return &Router{
+		client:              c,
+		configMap:           configMap,
+		clusterName:         clusterName,
+		defaultPathTemplate: defaultPathTemplate,
+		defaultBaseBranch:   defaultBaseBranch,
+		fallback:            fallback,
+		newProvider:         newProvider,
+		byRouteName:         map[string]cachedProvider{},
+	}
+}
+
+// ProviderFor returns the provider of the first route matching the namespace.
+// An invalid route table or missing credentials are errors rather than a
+// silent fallback: proposing a tenant's quota change in the shared
+// repository would put it in front of the wrong reviewers.
+func (r *Router) ProviderFor(ctx context.Context, ns *corev1.Namespace) (git.Provider, error) {
+	var cm corev1.ConfigMap
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: lock.ControllerNamespace, Name: r.configMap}, &cm)
+	if errors.IsNotFound(err) {
+		return r.fallback, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing ConfigMap %s: %w", r.configMap, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	routes, err := r.parse(&cm)
+	if err != nil {
+		return nil, fmt.Errorf("routing ConfigMap %s: %w", r.configMap, err)
+	}
+	route := match(routes, ns.Labels)
+	if route == nil {
+		return r.fallback, nil
+	}
+
+	resolved := *route
+	if resolved.PathTemplate == "" {
+		resolved.PathTemplate = r.defaultPathTemplate
+	}
+	if resolved.BaseBranch == "" {
+		resolved.BaseBranch = r.defaultBaseBranch
+	}
+
+	fingerprint := fmt.Sprintf("%s|%s|%s|%s|%s", resolved.Provider, resolved.Repository,
+		resolved.BaseBranch, resolved.PathTemplate, resolved.CredentialsSecret)
+	var creds map[string][]byte
+	if route.CredentialsSecret != "" {
+		var secret corev1.Secret
+		key := client.ObjectKey{Namespace: lock.ControllerNamespace, Name: route.CredentialsSecret}
+		if err := r.client.Get(ctx, key, &secret); err != nil {
+			return nil, fmt.Errorf("route %q: failed to read credentials Secret %s: %w", route.Name, route.CredentialsSecret, err)
+		}
+		fingerprint += "/" + secret.ResourceVersion
+		creds = secret.Data
+	}
+
+	if cached, ok := r.byRouteName[route.Name]; ok && cached.fingerprint == fingerprint {
+		return cached.provider, nil
+	}
+
+	provider, err := r.newProvider(resolved, r.clusterName, creds)
+	if err != nil {
+		return nil, fmt.Errorf("route %q: %w", route.Name, err)
+	}
+	r.byRouteName[route.Name] = cachedProvider{fingerprint: fingerprint, provider: provider}
+	return provider, nil
+}
+
+// parse returns the routes of cm, reusing the previous result while the
+// ConfigMap is unchanged. Providers of routes that no longer exist are
+// dropped. The caller holds r.mu.
+func (r *Router) parse(cm *corev1.ConfigMap) ([]Route, error) {
+	if cm.ResourceVersion != "" && cm.ResourceVersion == r.routesRV {
+		return r.routes, r.routesErr
+	}
+	r.routesRV = cm.ResourceVersion
+	r.routes, r.routesErr = Parse(cm.Data[RoutesKey])
+
+	names := map[string]bool{}
+	for _, route := range r.routes {
+		names[route.Name] = true
+	}
+	for name := range r.byRouteName {
+		if !names[name] {
+			delete(r.byRouteName, name)
+		}
+	}
+	return r.routes, r.routesErr
+}
+
+// newProvider builds a provider for route the same way cmd/main.go builds the
+// global one: a GitHub App takes precedence over a token.
+func newProvider(route Route, clusterName string, creds map[string][]byte) (git.Provider, error) {
+	if route.Provider == ProviderLog {
+		return git.NewStatefulLogProvider(), nil
+	}
+
+	owner, repo, _ := cutRepository(route.Repository)
+	var provider *git.GitHubProvider
+	switch {
+	case len(creds[KeyAppID]) > 0 && len(creds[KeyInstallationID]) > 0 && len(creds[KeyPrivateKey]) > 0:
+		appID, err := strconv.ParseInt(string(creds[KeyAppID]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", KeyAppID, err)
+		}
+		installID, err := strconv.ParseInt(string(creds[KeyInstallationID]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", KeyInstallationID, err)
+		}
+		provider, err = git.NewGitHubAppProvider(appID, installID, creds[KeyPrivateKey], owner, repo, clusterName, route.PathTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub App provider: %w", err)
+		}
+	case len(creds[KeyToken]) > 0:
+		provider = git.NewGitHubProvider(string(creds[KeyToken]), owner, repo, clusterName, route.PathTemplate)
+	default:
+		return nil, fmt.Errorf("credentials Secret %s has neither %s nor %s/%s/%s",
+			route.CredentialsSecret, KeyToken, KeyAppID, KeyInstallationID, KeyPrivateKey)
+	}
+
+	if route.BaseBranch != "" {
+		provider = provider.WithBaseBranch(route.BaseBranch)
+	}
+	if route.Provider == ProviderGitHubIssues {
+		return provider.Issues(), nil
+	}
+	return provider, nil
+}
@@ -0,0 +1,226 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/payback159/namespace-resizer/internal/git"
+	"github.com/payback159/namespace-resizer/internal/lock"
+)
+
+const testRoutes = `
+routes:
+- name: payments
+  namespaceSelector:
+    matchLabels:
+      team: payments
+  repository: payments/k8s-config
+  baseBranch: production
+  credentialsSecret: payments-github
+- name: sandbox
+  namespaceSelector:
+    matchExpressions:
+    - {key: env, operator: In, values: [sandbox]}
+  provider: log
+`
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":             "routes:\n- name: a\n  repo: org/x\n",
+		"missing name":              "routes:\n- repository: org/x\n  credentialsSecret: s\n",
+		"duplicate name":            "routes:\n- {name: a, provider: log}\n- {name: a, provider: log}\n",
+		"bad repository":            "routes:\n- {name: a, repository: org, credentialsSecret: s}\n",
+		"no credentials":            "routes:\n- {name: a, repository: org/x}\n",
+		"issues without repository": "routes:\n- {name: a, provider: github-issues, credentialsSecret: s}\n",
+		"unknown provider":          "routes:\n- {name: a, provider: gitlab}\n",
+		"bad selector":              "routes:\n- name: a\n  provider: log\n  namespaceSelector:\n    matchExpressions:\n    - {key: env, operator: Maybe}\n",
+		"bad path template":         "routes:\n- {name: a, provider: log, pathTemplate: '{{ .Namespace'}\n",
+		"bad base branch":           "routes:\n- {name: a, provider: log, baseBranch: 'env/{{ .Cluster'}\n",
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := Parse(data)
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
+
+type builtProvider struct {
+	git.StatefulLogProvider
+	route Route
+	creds map[string][]byte
+}
+
+func newTestRouter(objs ...client.Object) (*Router, *int) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+	router := NewRouter(c, "routes", "prod", "clusters/{{ .Cluster }}/{{ .Namespace }}", "env/{{ .Cluster }}",
+		git.NewLogOnlyProvider())
+	builds := 0
+	router.newProvider = func(route Route, _ string, creds map[string][]byte) (git.Provider, error) {
+		builds++
+		return &builtProvider{route: route, creds: creds}, nil
+	}
+	return router, &builds
+}
+
+func namespace(labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: labels}}
+}
+
+func TestProviderFor(t *testing.T) {
+	g := NewWithT(t)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "routes", Namespace: lock.ControllerNamespace},
+		Data:       map[string]string{RoutesKey: testRoutes},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments-github", Namespace: lock.ControllerNamespace},
+		Data:       map[string][]byte{KeyToken: []byte("token")},
+	}
+	router, builds := newTestRouter(cm, secret)
+	ctx := context.Background()
+
+	provider, err := router.ProviderFor(ctx, namespace(map[string]string{"team": "payments"}))
+	g.Expect(err).NotTo(HaveOccurred())
+	built := provider.(*builtProvider)
+	g.Expect(built.route.Repository).To(Equal("payments/k8s-config"))
+	g.Expect(built.route.PathTemplate).To(Equal("clusters/{{ .Cluster }}/{{ .Namespace }}"))
+	g.Expect(built.route.BaseBranch).To(Equal("production"), "the route's base branch wins over the global one")
+	g.Expect(built.creds).To(HaveKeyWithValue(KeyToken, []byte("token")))
+
+	again, err := router.ProviderFor(ctx, namespace(map[string]string{"team": "payments"}))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again).To(BeIdenticalTo(provider), "the provider is cached")
+	g.Expect(*builds).To(Equal(1))
+
+	provider, err = router.ProviderFor(ctx, namespace(map[string]string{"env": "sandbox"}))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.(*builtProvider).route.Provider).To(Equal(ProviderLog))
+	g.Expect(provider.(*builtProvider).route.BaseBranch).To(Equal("env/{{ .Cluster }}"))
+
+	provider, err = router.ProviderFor(ctx, namespace(nil))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeIdenticalTo(router.fallback))
+}
+
+func TestProviderFor_RebuildsOnSecretRotation(t *testing.T) {
+	g := NewWithT(t)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "routes", Namespace: lock.ControllerNamespace},
+		Data:       map[string]string{RoutesKey: testRoutes},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments-github", Namespace: lock.ControllerNamespace},
+		Data:       map[string][]byte{KeyToken: []byte("old")},
+	}
+	router, builds := newTestRouter(cm, secret)
+	ctx := context.Background()
+	ns := namespace(map[string]string{"team": "payments"})
+
+	_, err := router.ProviderFor(ctx, ns)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret.Data[KeyToken] = []byte("new")
+	g.Expect(router.client.(client.Client).Update(ctx, secret)).To(Succeed())
+
+	provider, err := router.ProviderFor(ctx, ns)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider.(*builtProvider).creds).To(HaveKeyWithValue(KeyToken, []byte("new")))
+	g.Expect(*builds).To(Equal(2))
+}
+
+func TestProviderFor_KeepsUnchangedRoutesAcrossEdits(t *testing.T) {
+	g := NewWithT(t)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "routes", Namespace: lock.ControllerNamespace},
+		Data:       map[string]string{RoutesKey: testRoutes},
+	}
+	router, builds := newTestRouter(cm)
+	ctx := context.Background()
+	sandbox := namespace(map[string]string{"env": "sandbox"})
+
+	provider, err := router.ProviderFor(ctx, sandbox)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Adding a route leaves the log route, and the pull requests it tracks,
+	// as they were.
+	cm.Data[RoutesKey] = testRoutes + "- {name: other, provider: log}\n"
+	g.Expect(router.client.(client.Client).Update(ctx, cm)).To(Succeed())
+	again, err := router.ProviderFor(ctx, sandbox)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again).To(BeIdenticalTo(provider))
+	g.Expect(*builds).To(Equal(1))
+
+	// Changing the route itself rebuilds its provider.
+	cm.Data[RoutesKey] = testRoutes + "  pathTemplate: sandbox/{{ .Namespace }}\n"
+	g.Expect(router.client.(client.Client).Update(ctx, cm)).To(Succeed())
+	rebuilt, err := router.ProviderFor(ctx, sandbox)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rebuilt).NotTo(BeIdenticalTo(provider))
+	g.Expect(rebuilt.(*builtProvider).route.PathTemplate).To(Equal("sandbox/{{ .Namespace }}"))
+	g.Expect(*builds).To(Equal(2))
+}
+
+func TestProviderFor_Errors(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	// Without the ConfigMap every namespace uses the fallback.
+	router, _ := newTestRouter()
+	provider, err := router.ProviderFor(ctx, namespace(map[string]string{"team": "payments"}))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeIdenticalTo(router.fallback))
+
+	// A matching route whose Secret is missing must not fall back.
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "routes", Namespace: lock.ControllerNamespace},
+		Data:       map[string]string{RoutesKey: testRoutes},
+	}
+	router, _ = newTestRouter(cm)
+	_, err = router.ProviderFor(ctx, namespace(map[string]string{"team": "payments"}))
+	g.Expect(err).To(MatchError(ContainSubstring("payments-github")))
+
+	// Neither may an invalid table.
+	cm.Data[RoutesKey] = "routes: [{name: a}]"
+	router, _ = newTestRouter(cm)
+	_, err = router.ProviderFor(ctx, namespace(nil))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNewProvider(t *testing.T) {
+	g := NewWithT(t)
+	route := Route{
+		Name: "a", Provider: ProviderGitHub, Repository: "org/tenants",
+		PathTemplate: "{{ .Namespace }}", BaseBranch: "release", CredentialsSecret: "s",
+	}
+
+	provider, err := newProvider(route, "prod", map[string][]byte{KeyToken: []byte("t")})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(git.RepositoryOf(provider)).To(Equal("org/tenants"))
+
+	route.Provider = ProviderGitHubIssues
+	provider, err = newProvider(route, "prod", map[string][]byte{KeyToken: []byte("t")})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(provider).To(BeAssignableToTypeOf(&git.GitHubIssueProvider{}))
+	g.Expect(git.RepositoryOf(provider)).To(Equal("org/tenants"))
+	route.Provider = ProviderGitHub
+
+	_, err = newProvider(route, "prod", map[string][]byte{})
+	g.Expect(err).To(MatchError(ContainSubstring(KeyToken)))
+
+	_, err = newProvider(route, "prod", map[string][]byte{
+		KeyAppID: []byte("x"), KeyInstallationID: []byte("1"), KeyPrivateKey: []byte("k"),
+	})
+	g.Expect(err).To(MatchError(ContainSubstring(KeyAppID)))
+}
@@ -0,0 +1,136 @@
+// Package routing maps namespaces to the git repository and credentials their
+// quota proposals go to. Routes are read from a ConfigMap in the controller
+// namespace, so tenants owning their own repositories can be added without
+// restarting the controller.
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// RoutesKey is the ConfigMap data key holding the route table.
+const RoutesKey = "routes.yaml"
+
+// Provider types a route can select.
+const (
+	ProviderGitHub = "github"
+	// ProviderGitHubIssues files an issue per quota in the repository instead
+	// of opening pull requests, for teams that apply the changes themselves.
+	ProviderGitHubIssues = "github-issues"
+	// ProviderLog only logs what it would do, like DRY_RUN does globally.
+	ProviderLog = "log"
+)
+
+// Route sends the proposals for every namespace matching NamespaceSelector to
+// one repository.
+type Route struct {
+	// Name identifies the route in logs and keys the provider cache.
+	Name string `json:"name"`
+	// NamespaceSelector matches the labels of the namespace. An empty
+	// selector matches every namespace, which makes a catch-all route.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	// Provider is ProviderGitHub (the default), ProviderGitHubIssues or
+	// ProviderLog.
+	Provider string `json:"provider,omitempty"`
+	// Repository is "owner/repo".
+	Repository string `json:"repository,omitempty"`
+	// BaseBranch is the branch pull requests target, templated like
+	// PathTemplate. Empty means GIT_BASE_BRANCH.
+	BaseBranch string `json:"baseBranch,omitempty"`
+	// PathTemplate replaces GIT_PATH_TEMPLATE for this route.
+	PathTemplate string `json:"pathTemplate,omitempty"`
+	// CredentialsSecret names a Secret in the controller namespace with the
+	// same keys as the global configuration: GITHUB_TOKEN, or
+	// GITHUB_APP_ID, GITHUB_INSTALLATION_ID and GITHUB_PRIVATE_KEY.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	selector labels.Selector
+}
+
+type routeTable struct {
+	Routes []Route `json:"routes"`
+}
+
+// Parse reads and validates a route table. Every route is checked up front:
+// a typo in one tenant's route must not be discovered only when that tenant's
+// quota first needs a pull request.
+func Parse(data string) ([]Route, error) {
+	var table routeTable
+	if err := yaml.UnmarshalStrict([]byte(data), &table); err != nil {
+		return nil, fmt.Errorf("invalid route table: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range table.Routes {
+		route := &table.Routes[i]
+		if route.Name == "" {
+			return nil, fmt.Errorf("route %d has no name", i)
+		}
+		if seen[route.Name] {
+			return nil, fmt.Errorf("route %q is defined twice", route.Name)
+		}
+		seen[route.Name] = true
+
+		selector, err := metav1.LabelSelectorAsSelector(&route.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: invalid namespaceSelector: %w", route.Name, err)
+		}
+		route.selector = selector
+
+		if route.PathTemplate != "" {
+			if _, err := template.New("path").Parse(route.PathTemplate); err != nil {
+				return nil, fmt.Errorf("route %q: invalid pathTemplate: %w", route.Name, err)
+			}
+		}
+		if route.BaseBranch != "" {
+			if _, err := template.New("base").Parse(route.BaseBranch); err != nil {
+				return nil, fmt.Errorf("route %q: invalid baseBranch: %w", route.Name, err)
+			}
+		}
+
+		switch route.Provider {
+		case "", ProviderGitHub, ProviderGitHubIssues:
+			if route.Provider == "" {
+				route.Provider = ProviderGitHub
+			}
+			if _, _, ok := cutRepository(route.Repository); !ok {
+				return nil, fmt.Errorf("route %q: repository %q is not owner/repo", route.Name, route.Repository)
+			}
+			if route.CredentialsSecret == "" {
+				return nil, fmt.Errorf("route %q: credentialsSecret is required for the %s provider", route.Name, route.Provider)
+			}
+		case ProviderLog:
+		default:
+			return nil, fmt.Errorf("route %q: unknown provider %q", route.Name, route.Provider)
+		}
+	}
+	return table.Routes, nil
+}
+
+// match returns the first route whose selector matches the namespace labels,
+// or nil. Order matters, as in a firewall rule set: a catch-all route goes
+// last.
+func match(routes []Route, nsLabels map[string]string) *Route {
+	set := labels.Set(nsLabels)
+	for i := range routes {
+		if routes[i].selector.Matches(set) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// cutRepository splits "owner/repo".
+func cutRepository(repository string) (owner, repo string, ok bool) {
+	owner, repo, ok = strings.Cut(repository, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", false
+	}
+	return owner, repo, true
+}
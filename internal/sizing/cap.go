@@ -0,0 +1,45 @@
+package sizing
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// growCeiling returns the highest limit of res a single grow PR may propose,
+// in milli-units, and the cap that sets it. The lowest of the configured
+// maximum, the grow step cap and the per-PR increase cap wins. It reports
+// ok=false when none is configured.
+func growCeiling(in Input, res corev1.ResourceName, hard resource.Quantity) (int64, string, bool) {
+	hardMilli := hard.MilliValue()
+	var (
+		ceiling int64
+		cause   string
+		found   bool
+	)
+	limit := func(milli int64, what string) {
+		if !found || milli < ceiling {
+			ceiling, cause, found = milli, what, true
+		}
+	}
+
+	if q, ok := in.Policy.MaxFor(res); ok && !overflowsMilliValue(q) {
+		limit(q.MilliValue(), "the configured maximum of "+q.String())
+	}
+	if step := in.Policy.MaxGrowStep; step > 0 {
+		stepped := min(float64(hardMilli)*(1+step), maxMilliValue)
+		limit(int64(stepped), fmt.Sprintf("the grow step cap of %g%%", step*100))
+	}
+	if q, ok := in.Policy.MaxIncreaseFor(res); ok && !overflowsMilliValue(q) {
+		limit(hardMilli+q.MilliValue(), "the per-PR increase cap of "+q.String())
+	}
+	return ceiling, cause, found
+}
+
+// shortageRemains reports whether a grow of res to limit still leaves the
+// pods rejected by a pending shortage without room.
+func shortageRemains(in Input, res corev1.ResourceName, usedMilli int64, limit resource.Quantity) bool {
+	deficit, ok := in.Deficits[res]
+	return ok && deficit > 0 && limit.MilliValue() < usedMilli+deficit
+}
@@ -0,0 +1,115 @@
+package sizing
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestDecide_GrowCaps(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*Policy)
+		direction Direction
+		target    string
+		driver    string
+		shortage  bool
+	}{
+		{
+			name:      "uncapped",
+			configure: func(p *Policy) { p.Max[corev1.ResourceCPU] = resource.MustParse("25") },
+			direction: DirectionGrow,
+			target:    "20",
+			driver:    "driven by pending shortage)",
+		},
+		{
+			name:      "configured maximum",
+			configure: func(p *Policy) { p.Max[corev1.ResourceCPU] = resource.MustParse("15") },
+			direction: DirectionGrow,
+			target:    "15",
+			driver:    "capped by the configured maximum of 15",
+			shortage:  true,
+		},
+		{
+			name:      "grow step",
+			configure: func(p *Policy) { p.MaxGrowStep = 0.5 },
+			direction: DirectionGrow,
+			target:    "15",
+			driver:    "capped by the grow step cap of 50%",
+			shortage:  true,
+		},
+		{
+			name: "per-PR increase, leaving room for the shortage",
+			configure: func(p *Policy) {
+				p.MaxIncrease[corev1.ResourceRequestsCPU] = resource.MustParse("8")
+			},
+			direction: DirectionGrow,
+			target:    "18",
+			driver:    "capped by the per-PR increase cap of 8",
+		},
+		{
+			name: "lowest cap wins",
+			configure: func(p *Policy) {
+				p.Max[corev1.ResourceCPU] = resource.MustParse("30")
+				p.MaxGrowStep = 0.5
+				p.MaxIncrease[corev1.ResourceCPU] = resource.MustParse("3")
+			},
+			direction: DirectionGrow,
+			target:    "13",
+			driver:    "capped by the per-PR increase cap of 3",
+			shortage:  true,
+		},
+		{
+			name:      "already at the maximum",
+			configure: func(p *Policy) { p.Max[corev1.ResourceCPU] = resource.MustParse("8") },
+			direction: DirectionNone,
+			shortage:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// used 10 plus a shortage of 6 -> target 16 * 1.25 = 20.
+			in := baseInput("10", "10", "10")
+			in.Deficits = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 6000}
+			tc.configure(&in.Policy)
+
+			got := Decide(in)
+
+			if got.Direction != tc.direction {
+				t.Fatalf("direction = %v, want %v", got.Direction, tc.direction)
+			}
+			if tc.direction == DirectionGrow {
+				if target := targetCPU(t, got); target != tc.target {
+					t.Fatalf("target = %s, want %s", target, tc.target)
+				}
+				if !strings.Contains(got.Reason, tc.driver) {
+					t.Errorf("reason = %q, want it to contain %q", got.Reason, tc.driver)
+				}
+			}
+			if capped := tc.target != "20"; capped != (len(got.Capped) == 1) {
+				t.Errorf("capped = %v, want one entry: %v", got.Capped, capped)
+			}
+			if got.ShortageRemains != tc.shortage {
+				t.Errorf("shortageRemains = %v, want %v", got.ShortageRemains, tc.shortage)
+			}
+		})
+	}
+}
+
+func TestDecide_GrowCapRoundsDown(t *testing.T) {
+	in := baseInput("10", "10", "10")
+	in.Hard = corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("1Gi")}
+	in.Used = corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("1Gi")}
+	in.Policy.MaxGrowStep = 0.1
+
+	got := Decide(in)
+
+	// 1Gi * 1.1 is 1126.4Mi; rounding up would overshoot the cap.
+	qty := got.Targets[corev1.ResourceRequestsMemory]
+	if want := "1126Mi"; qty.String() != want {
+		t.Fatalf("target = %s, want %s", qty.String(), want)
+	}
+}
@@ -0,0 +1,99 @@
+package sizing
+
+import (
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// resourceJobs is the object-count quota key for Jobs.
+const resourceJobs corev1.ResourceName = "count/jobs.batch"
+
+// CronRun is one upcoming run of a CronJob and what it counts against the
+// quota while it runs, in milli-units.
+type CronRun struct {
+	// Name identifies the CronJob, e.g. "CronJob/nightly-report".
+	Name   string
+	At     time.Time
+	Demand map[corev1.ResourceName]int64
+}
+
+// CronJobRuns returns the runs of a CronJob scheduled after from and up to
+// to. active is the number of its Jobs still running. A run starts as many
+// pods as the Job's parallelism, or fewer when it needs fewer completions.
+// With concurrencyPolicy Allow it runs alongside the active Jobs; Forbid and
+// Replace keep one Job at a time. A suspended CronJob has no runs.
+func CronJobRuns(name string, spec batchv1.CronJobSpec, active int, from, to time.Time) ([]CronRun, error) {
+	if spec.Suspend != nil && *spec.Suspend {
+		return nil, nil
+	}
+	timeZone := ""
+	if spec.TimeZone != nil {
+		timeZone = *spec.TimeZone
+	}
+	schedule, err := ParseSchedule(spec.Schedule, timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	job := spec.JobTemplate.Spec
+	pods := int64(1)
+	if job.Parallelism != nil {
+		pods = int64(*job.Parallelism)
+	}
+	if job.Completions != nil {
+		pods = min(pods, int64(*job.Completions))
+	}
+	jobs := int64(1)
+	if spec.ConcurrencyPolicy == batchv1.AllowConcurrent || spec.ConcurrencyPolicy == "" {
+		jobs += int64(active)
+	}
+	demand := WorkloadDemand(job.Template.Spec, nil, pods*jobs)
+	demand[resourceJobs] = jobs * 1000
+
+	var runs []CronRun
+	for _, at := range schedule.Runs(from, to) {
+		runs = append(runs, CronRun{Name: name, At: at, Demand: demand})
+	}
+	return runs, nil
+}
+
+// scheduledPeak returns the highest demand for res at any upcoming CronJob
+// run, in milli-units, and names the run. The demand at a run is what the
+// namespace used in the hour before it, on any day of the window, plus every
+// CronJob starting in the same hour. The hour before leaves out the
+// CronJob's own earlier runs, which would otherwise count twice. Without
+// hourly history, current usage stands in for it.
+func scheduledPeak(in Input, res corev1.ResourceName, usedMilli int64) (int64, string, bool) {
+	var peak int64
+	var driver string
+	found := false
+	for _, run := range in.CronRuns {
+		if _, ok := demandFor(run.Demand, res); !ok {
+			continue
+		}
+		slot := run.At.UTC().Truncate(time.Hour)
+		need, ok := in.Window.HourlyPeak(res, slot.Add(-time.Hour).Hour())
+		if !ok {
+			need = usedMilli
+		}
+		var names []string
+		for _, other := range in.CronRuns {
+			if !other.At.UTC().Truncate(time.Hour).Equal(slot) {
+				continue
+			}
+			if milli, ok := demandFor(other.Demand, res); ok {
+				need += milli
+				names = append(names, other.Name)
+			}
+		}
+		if !found || need > peak {
+			peak, found = need, true
+			driver = "scheduled run of " + strings.Join(names, " and ") +
+				" at " + run.At.Format("Mon 15:04 MST")
+		}
+	}
+	return peak, driver, found
+}
@@ -0,0 +1,169 @@
+package sizing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+)
+
+// nightlyReport returns a CronJob spec running at 02:00 Berlin time whose
+// pods request cpu each.
+func nightlyReport(cpu string) batchv1.CronJobSpec {
+	spec := batchv1.CronJobSpec{
+		Schedule: "0 2 * * *",
+		TimeZone: ptr.To("Europe/Berlin"),
+	}
+	spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "report",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse(cpu),
+		}},
+	}}
+	return spec
+}
+
+func TestCronJobRuns_Demand(t *testing.T) {
+	cases := []struct {
+		name     string
+		mutate   func(*batchv1.CronJobSpec)
+		active   int
+		wantCPU  int64
+		wantJobs int64
+	}{
+		{"one pod", func(*batchv1.CronJobSpec) {}, 0, 2000, 1000},
+		{"parallelism", func(s *batchv1.CronJobSpec) {
+			s.JobTemplate.Spec.Parallelism = ptr.To[int32](4)
+		}, 0, 8000, 1000},
+		{"fewer completions than parallelism", func(s *batchv1.CronJobSpec) {
+			s.JobTemplate.Spec.Parallelism = ptr.To[int32](4)
+			s.JobTemplate.Spec.Completions = ptr.To[int32](3)
+		}, 0, 6000, 1000},
+		{"Allow runs alongside the active Job", func(*batchv1.CronJobSpec) {}, 1, 4000, 2000},
+		{"Forbid keeps one Job", func(s *batchv1.CronJobSpec) {
+			s.ConcurrencyPolicy = batchv1.ForbidConcurrent
+		}, 1, 2000, 1000},
+		{"Replace keeps one Job", func(s *batchv1.CronJobSpec) {
+			s.ConcurrencyPolicy = batchv1.ReplaceConcurrent
+		}, 1, 2000, 1000},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := nightlyReport("2")
+			tc.mutate(&spec)
+
+			runs, err := CronJobRuns("CronJob/report", spec, tc.active, testNow, testNow.Add(24*time.Hour))
+			if err != nil {
+				t.Fatalf("CronJobRuns: %v", err)
+			}
+			if len(runs) != 1 {
+				t.Fatalf("runs = %d, want 1", len(runs))
+			}
+			if got := runs[0].Demand[corev1.ResourceRequestsCPU]; got != tc.wantCPU {
+				t.Errorf("requests.cpu = %d, want %d", got, tc.wantCPU)
+			}
+			if got := runs[0].Demand[resourceJobs]; got != tc.wantJobs {
+				t.Errorf("count/jobs.batch = %d, want %d", got, tc.wantJobs)
+			}
+		})
+	}
+}
+
+func TestCronJobRuns_SuspendedHasNone(t *testing.T) {
+	spec := nightlyReport("2")
+	spec.Suspend = ptr.To(true)
+
+	runs, err := CronJobRuns("CronJob/report", spec, 0, testNow, testNow.Add(24*time.Hour))
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("runs = %v, err = %v, want none", runs, err)
+	}
+}
+
+// cronInput returns a pairInput with the runs of spec over the next day.
+func cronInput(t *testing.T, spec batchv1.CronJobSpec) Input {
+	t.Helper()
+	in := pairInput("16", "32", "4", "4")
+	runs, err := CronJobRuns("CronJob/report", spec, 0, testNow, testNow.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("CronJobRuns: %v", err)
+	}
+	in.CronRuns = runs
+	return in
+}
+
+func TestDecide_ScheduledRunGrowsAheadOfIt(t *testing.T) {
+	// 4 used in the hour before the run plus 14 for the run -> 18, past 16.
+	in := cronInput(t, nightlyReport("14"))
+
+	got := Decide(in)
+
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow", got.Direction)
+	}
+	if targets := targetsOf(got); targets["requests.cpu"] != "22500m" {
+		t.Fatalf("targets = %v, want requests.cpu at 22500m", targets)
+	}
+	if !strings.Contains(got.Reason, "scheduled run of CronJob/report at Sun 02:00 CEST") {
+		t.Errorf("reason = %q, want the run named", got.Reason)
+	}
+}
+
+func TestDecide_ScheduledRunCountsTheHourBefore(t *testing.T) {
+	// 10 fits on top of 4, but not on top of the 8 seen at 01:00 Berlin time
+	// (23:00 UTC) on one day of the window.
+	in := cronInput(t, nightlyReport("10"))
+	if got := Decide(in); got.Direction == DirectionGrow {
+		t.Fatalf("direction = grow, want the run to fit the usual usage")
+	}
+	in.Window.Days[3].Hourly["requests.cpu"][23] = 8000
+
+	got := Decide(in)
+
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow", got.Direction)
+	}
+	if targets := targetsOf(got); targets["requests.cpu"] != "22500m" {
+		t.Fatalf("targets = %v, want requests.cpu at 22500m", targets)
+	}
+}
+
+func TestDecide_ConcurrentRunsAddUp(t *testing.T) {
+	in := cronInput(t, nightlyReport("7"))
+	other := nightlyReport("7")
+	other.Schedule = "30 2 * * *"
+	runs, err := CronJobRuns("CronJob/export", other, 0, testNow, testNow.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("CronJobRuns: %v", err)
+	}
+	in.CronRuns = append(in.CronRuns, runs...)
+
+	got := Decide(in)
+
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow from 4 + 7 + 7", got.Direction)
+	}
+	if !strings.Contains(got.Reason, "CronJob/report and CronJob/export") {
+		t.Errorf("reason = %q, want both runs named", got.Reason)
+	}
+}
+
+func TestWindow_HourlyPeak(t *testing.T) {
+	w := Window{Version: WindowVersion}
+	w.Observe(testNow, testUID, corev1.ResourceList{
+		corev1.ResourceRequestsCPU: resource.MustParse("3"),
+	}, 14)
+	w.Observe(testNow.Add(10*time.Minute), testUID, corev1.ResourceList{
+		corev1.ResourceRequestsCPU: resource.MustParse("5"),
+	}, 14)
+
+	if peak, ok := w.HourlyPeak(corev1.ResourceRequestsCPU, 12); !ok || peak != 5000 {
+		t.Errorf("HourlyPeak(12) = %d/%v, want 5000/true", peak, ok)
+	}
+	if _, ok := w.HourlyPeak(corev1.ResourceRequestsCPU, 13); ok {
+		t.Errorf("HourlyPeak(13) found, want an unsampled hour")
+	}
+}
@@ -91,6 +91,9 @@ const (
 	GateRecentGrow Gate = "recent-grow"
 	// GateCooldown enforces the long shrink cooldown.
 	GateCooldown Gate = "cooldown"
+	// GateSeasonal requires one full seasonal cycle in the coarse history,
+	// when the namespace declares a seasonality.
+	GateSeasonal Gate = "seasonal"
 )
 
 // Input is everything Decide needs. It carries no client and no ambient clock.
@@ -105,6 +108,19 @@ type Input struct {
 	Policy     Policy
 	LastGrow   time.Time
 	LastShrink time.Time
+	// ScaleOut holds, per quota key, the milli-value the namespace's
+	// workloads need at their maximum scale (see WorkloadDemand), or nil
+	// when it was not computed. It only holds a shrink back.
+	ScaleOut map[corev1.ResourceName]int64
+	// Projected holds, per quota key, the milli-value the namespace's
+	// workloads need at their desired scale, or nil when it was not
+	// computed. A key projected past its hard limit grows before any pod is
+	// rejected; below the limit it has no effect.
+	Projected map[corev1.ResourceName]int64
+	// CronRuns are the CronJob runs due within Policy.CronLookahead. A key
+	// whose demand at one of them exceeds its hard limit grows ahead of the
+	// run; below the limit they have no effect.
+	CronRuns []CronRun
 }
 
 // Decision is the result of one evaluation.
@@ -119,11 +135,9 @@ type Decision struct {
 	// stops it from being proposed. It answers "what would this PR look
 	// like" for a namespace stuck behind a gate, which RawTargets cannot:
 	// RawTargets is the uncapped target and does not reflect the step cap a
-	// real PR would be bound by. Nothing in this codebase reads it outside
-	// tests today — recordDecision (controller/metrics.go) moved to
-	// RawTargets for the metrics — but the value it carries is not
-	// derivable from RawTargets, so it stays. Never act on it directly —
-	// only Targets is authoritative for a pull request.
+	// real PR would be bound by. The reconciler only reads whether it is
+	// empty, to tell when a scale-out floor is worth computing. Never act
+	// on it directly — only Targets is authoritative for a pull request.
 	ShrinkPreview map[corev1.ResourceName]resource.Quantity
 	// RawTargets is the uncapped target for every resource Decide evaluated,
 	// independent of the per-PR step cap and the tolerance band. It exists to
@@ -135,6 +149,17 @@ type Decision struct {
 	RawTargets map[corev1.ResourceName]resource.Quantity
 	Reason     string
 	BlockedBy  []Gate
+	// Exclusions explain the usage the targets leave out: peaks of workloads
+	// ignored in sizing and outlier days. They are kept out of Reason so a
+	// proposal can show them next to the limits.
+	Exclusions []string
+	// Capped explains every grow a cap held below its target, one sentence
+	// per resource. It is set whatever the direction: a quota already at its
+	// maximum cannot grow at all.
+	Capped []string
+	// ShortageRemains reports that a cap leaves the pods rejected by a
+	// pending shortage without room. Such a grow is never auto-merged.
+	ShortageRemains bool
 }
 
 // Decide computes the target for every quota key and folds the per-resource
@@ -149,7 +174,11 @@ func Decide(in Input) Decision {
 	growTargets := map[corev1.ResourceName]resource.Quantity{}
 	shrinkTargets := map[corev1.ResourceName]resource.Quantity{}
 	rawTargets := map[corev1.ResourceName]resource.Quantity{}
-	var growReasons, shrinkReasons []string
+	notes := map[corev1.ResourceName][]string{}
+	growDrivers := map[corev1.ResourceName]string{}
+	shrinkDrivers := map[corev1.ResourceName]string{}
+	var caps []string
+	shortage := false
 
 	for res, hard := range in.Hard {
 		if overflowsMilliValue(hard) {
@@ -173,72 +202,147 @@ func Decide(in Input) Decision {
 		usedMilli := used.MilliValue()
 
 		headroom := in.Policy.HeadroomFor(res)
-		targetMilli, driver := targetFor(in, res, usedMilli, headroom)
+		targetMilli, driver, resNotes := targetFor(in, res, hard, usedMilli, headroom)
+		notes[res] = resNotes
 
 		// Recorded before the step cap and the tolerance band below touch
 		// targetMilli, so it stays the uncapped target described on
 		// RawTargets — the metrics need it; PR proposals do not.
 		rawTargets[res] = Quantize(res, targetMilli, hard.Format)
 
+		// The tolerance band is checked on the rounded target: a rounding
+		// that lands back on the current limit must not open a PR.
 		switch {
-		case float64(targetMilli) > float64(hardMilli)*(1+in.Policy.Tolerance):
-			qty := Quantize(res, targetMilli, hard.Format)
+		case targetMilli > hardMilli:
+			qty := in.Policy.roundUp(res, Quantize(res, targetMilli, hard.Format))
+			if float64(qty.MilliValue()) <= float64(hardMilli)*(1+in.Policy.Tolerance) {
+				continue
+			}
+			if projected, trend, ok := forecastFor(in, res); ok {
+				if forecast := int64(float64(projected) * (1 + headroom)); forecast > targetMilli {
+					peak := Quantize(res, projected, hard.Format)
+					rise := Quantize(res, int64(trend.Slope), hard.Format)
+					qty = in.Policy.roundUp(res, Quantize(res, forecast, hard.Format))
+					driver = fmt.Sprintf("%d-day forecast of %s, the daily peak rising by %s per day",
+						in.Policy.ForecastDays, peak.String(), rise.String())
+				}
+			}
+			if ceiling, cause, ok := growCeiling(in, res, hard); ok && qty.MilliValue() > ceiling {
+				limit := in.Policy.roundDown(res, quantizeDown(res, ceiling, hard.Format))
+				if limit.Cmp(hard) < 0 {
+					// A cap below the current limit stops the grow; it does
+					// not turn it into a shrink.
+					limit = hard
+				}
+				caps = append(caps, fmt.Sprintf("%s: the target of %s is capped at %s by %s",
+					res, qty.String(), limit.String(), cause))
+				shortage = shortage || shortageRemains(in, res, usedMilli, limit)
+				qty = limit
+				driver += ", capped by " + cause
+			}
 			if qty.Cmp(hard) <= 0 {
-				// Rounding erased the increase; nothing to propose.
+				// A cap erased the increase; nothing to propose.
 				continue
 			}
 			growTargets[res] = qty
-			growReasons = append(growReasons,
-				describe(res, hard, qty, driver))
+			growDrivers[res] = driver
 
-		case float64(targetMilli) < float64(hardMilli)*(1-in.Policy.Tolerance):
+		case targetMilli < hardMilli:
+			floor, _ := floorFor(in, res, hardMilli, usedMilli, headroom)
 			capped := int64(float64(hardMilli) * (1 - in.Policy.MaxShrinkStep))
 			if capped > targetMilli {
 				targetMilli = capped
 				driver = "step cap"
 			}
-			qty := Quantize(res, targetMilli, hard.Format)
-			if qty.Cmp(hard) >= 0 {
-				// Rounding erased the decrease; nothing to propose.
+			// Rounding down may not undo the step cap or a floor, so the
+			// result is never below the first step above them.
+			qty := in.Policy.roundDown(res, Quantize(res, targetMilli, hard.Format))
+			if least := in.Policy.roundUp(res, Quantize(res, max(floor, capped), hard.Format)); qty.Cmp(least) < 0 {
+				qty = least
+			}
+			if float64(qty.MilliValue()) >= float64(hardMilli)*(1-in.Policy.Tolerance) {
 				continue
 			}
 			shrinkTargets[res] = qty
-			shrinkReasons = append(shrinkReasons,
-				describe(res, hard, qty, driver))
+			shrinkDrivers[res] = driver
 		}
 	}
 
+	grew := len(growTargets) > 0
+	if grew {
+		held, heldShort := keepPairsOnGrow(in, growTargets, growDrivers)
+		caps = append(caps, held...)
+		shortage = shortage || heldShort
+	}
+	sort.Strings(caps)
 	if len(growTargets) > 0 {
-		sort.Strings(growReasons)
+		exclusions := exclusionsOf(growTargets, notes)
+		reasons := describeAll(in.Hard, growTargets, growDrivers)
+		if shortage {
+			reasons = append(reasons, "The caps leave less room than the pods "+
+				"rejected by the quota need, so this change will not be auto-merged.")
+		}
 		return Decision{
-			Direction:  DirectionGrow,
-			Targets:    growTargets,
-			Reason:     strings.Join(growReasons, "\n"),
-			RawTargets: rawTargets,
+			Direction:       DirectionGrow,
+			Targets:         growTargets,
+			Reason:          strings.Join(reasons, "\n"),
+			RawTargets:      rawTargets,
+			Exclusions:      exclusions,
+			Capped:          caps,
+			ShortageRemains: shortage,
 		}
 	}
 
+	if grew {
+		// A pair held every grow back. The demand is still there, so
+		// nothing may shrink meanwhile.
+		clear(shrinkTargets)
+	}
+	keepPairsOnShrink(in, shrinkTargets, shrinkDrivers)
 	if len(shrinkTargets) == 0 {
-		return Decision{Direction: DirectionNone, RawTargets: rawTargets}
+		return Decision{
+			Direction:       DirectionNone,
+			RawTargets:      rawTargets,
+			Capped:          caps,
+			ShortageRemains: shortage,
+		}
 	}
 
 	if blocked := shrinkGates(in, shrinkTargets); len(blocked) > 0 {
 		return Decision{
-			Direction:     DirectionNone,
-			ShrinkPreview: shrinkTargets,
-			BlockedBy:     blocked,
-			RawTargets:    rawTargets,
+			Direction:       DirectionNone,
+			ShrinkPreview:   shrinkTargets,
+			BlockedBy:       blocked,
+			RawTargets:      rawTargets,
+			Capped:          caps,
+			ShortageRemains: shortage,
 		}
 	}
 
-	sort.Strings(shrinkReasons)
+	exclusions := exclusionsOf(shrinkTargets, notes)
 	return Decision{
-		Direction:     DirectionShrink,
-		Targets:       shrinkTargets,
-		ShrinkPreview: shrinkTargets,
-		Reason:        strings.Join(shrinkReasons, "\n"),
-		RawTargets:    rawTargets,
+		Direction:       DirectionShrink,
+		Targets:         shrinkTargets,
+		ShrinkPreview:   shrinkTargets,
+		Reason:          strings.Join(describeAll(in.Hard, shrinkTargets, shrinkDrivers), "\n"),
+		RawTargets:      rawTargets,
+		Exclusions:      exclusions,
+		Capped:          caps,
+		ShortageRemains: shortage,
+	}
+}
+
+// exclusionsOf collects the notes on what was left out of the targets, sorted.
+func exclusionsOf(
+	targets map[corev1.ResourceName]resource.Quantity,
+	notes map[corev1.ResourceName][]string,
+) []string {
+	var exclusions []string
+	for res := range targets {
+		exclusions = append(exclusions, notes[res]...)
 	}
+	sort.Strings(exclusions)
+	return exclusions
 }
 
 // targetFor implements the formula from spec 3 and reports which term decided
@@ -246,15 +350,18 @@ func Decide(in Input) Decision {
 func targetFor(
 	in Input,
 	res corev1.ResourceName,
+	hard resource.Quantity,
 	usedMilli int64,
 	headroom float64,
-) (int64, string) {
+) (int64, string, []string) {
+	hardMilli := hard.MilliValue()
 	peakMilli := usedMilli
 	driver := "current usage"
 
-	if p, ok := in.Window.Peak(res, in.Now, in.Policy.WindowDays); ok && p > peakMilli {
+	p, estimator, notes, ok := windowEstimate(in, res, hard.Format)
+	if ok && p > peakMilli {
 		peakMilli = p
-		driver = fmt.Sprintf("%d-day peak", in.Policy.WindowDays)
+		driver = estimator
 	}
 	if deficit, ok := in.Deficits[res]; ok && deficit > 0 {
 		if need := usedMilli + deficit; need > peakMilli {
@@ -262,19 +369,50 @@ func targetFor(
 			driver = "pending shortage"
 		}
 	}
+	if projected, ok := demandFor(in.Projected, res); ok && projected > hardMilli && projected > peakMilli {
+		peakMilli = projected
+		driver = "projected demand"
+	}
+	if scheduled, scheduledDriver, ok := scheduledPeak(in, res, usedMilli); ok && scheduled > hardMilli && scheduled > peakMilli {
+		peakMilli = scheduled
+		driver = scheduledDriver
+	}
 
 	target := int64(float64(peakMilli) * (1 + headroom))
+	if floor, floorDriver := floorFor(in, res, hardMilli, usedMilli, headroom); floor > target {
+		target = floor
+		driver = floorDriver
+	}
 
-	if lowerBound := int64(float64(usedMilli) * (1 + headroom)); lowerBound > target {
-		target = lowerBound
-		driver = "current usage floor"
+	return target, driver, notes
+}
+
+// floorFor returns the lowest target res may have, in milli-units, and which
+// bound sets it: current usage plus headroom, the seasonal peak, the
+// scale-out demand, or the configured minimum.
+func floorFor(in Input, res corev1.ResourceName, hardMilli, usedMilli int64, headroom float64) (int64, string) {
+	floor := int64(float64(usedMilli) * (1 + headroom))
+	driver := "current usage floor"
+	// The seasonal floor only holds a shrink back, up to the current limit.
+	// A recurring peak above the limit is met by a grow when it recurs.
+	if seasonal, ok := in.Window.SeasonalPeak(res, in.Policy.Seasonality, in.Now); ok {
+		if peak := min(int64(float64(seasonal)*(1+headroom)), hardMilli); peak > floor {
+			floor = peak
+			driver = string(in.Policy.Seasonality) + " seasonal peak"
+		}
+	}
+	// Like the seasonal floor, the scale-out floor never raises a limit.
+	if scaled, ok := demandFor(in.ScaleOut, res); ok && in.Policy.ScaleOutFloor {
+		if need := min(scaled, hardMilli); need > floor {
+			floor = need
+			driver = "scale-out floor"
+		}
 	}
-	if floor, ok := in.Policy.MinFor(res); ok && floor.MilliValue() > target {
-		target = floor.MilliValue()
+	if minimum, ok := in.Policy.MinFor(res); ok && minimum.MilliValue() > floor {
+		floor = minimum.MilliValue()
 		driver = "configured minimum"
 	}
-
-	return target, driver
+	return floor, driver
 }
 
 // shrinkGates returns every gate from spec 3.3 that currently blocks a shrink.
@@ -298,10 +436,44 @@ func shrinkGates(in Input, targets map[corev1.ResourceName]resource.Quantity) []
 	if !in.LastShrink.IsZero() && in.Now.Sub(in.LastShrink) < in.Policy.ShrinkCooldown {
 		blocked = append(blocked, GateCooldown)
 	}
+	if !in.Window.SeasonObserved(in.Policy.Seasonality, in.Now) {
+		blocked = append(blocked, GateSeasonal)
+	}
 
 	return blocked
 }
 
+// FitsWithHeadroom reports whether current usage plus headroom stays within
+// the hard limit for every measurable resource. It is what makes an open grow
+// PR obsolete: once it holds, merging the PR would only add slack.
+func FitsWithHeadroom(hard, used corev1.ResourceList, policy Policy) bool {
+	for res, limit := range hard {
+		usage, ok := used[res]
+		if !ok || overflowsMilliValue(limit) || overflowsMilliValue(usage) {
+			continue
+		}
+		need := float64(usage.MilliValue()) * (1 + policy.HeadroomFor(res))
+		if need > float64(limit.MilliValue()) {
+			return false
+		}
+	}
+	return true
+}
+
+// describeAll describes every target, sorted.
+func describeAll(
+	hard corev1.ResourceList,
+	targets map[corev1.ResourceName]resource.Quantity,
+	drivers map[corev1.ResourceName]string,
+) []string {
+	reasons := make([]string, 0, len(targets))
+	for res, qty := range targets {
+		reasons = append(reasons, describe(res, hard[res], qty, drivers[res]))
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
 func describe(
 	res corev1.ResourceName,
 	from resource.Quantity,
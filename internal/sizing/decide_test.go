@@ -1,6 +1,7 @@
 package sizing
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -525,3 +526,52 @@ func TestDecide_MissingUsedEntryIsSkipped(t *testing.T) {
 		t.Fatalf("BlockedBy = %v, want empty — the key should be skipped, not gated", got.BlockedBy)
 	}
 }
+
+func TestFitsWithHeadroom(t *testing.T) {
+	policy := DefaultPolicy()
+	hard := corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("10")}
+
+	cases := map[string]struct {
+		used string
+		want bool
+	}{
+		"well below":       {"4", true},
+		"exactly at limit": {"8", true},
+		"headroom exceeds": {"8100m", false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			used := corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(tc.used)}
+			if got := FitsWithHeadroom(hard, used, policy); got != tc.want {
+				t.Errorf("FitsWithHeadroom(used=%s) = %v, want %v", tc.used, got, tc.want)
+			}
+		})
+	}
+
+	// A resource without usage is not measurable and does not block.
+	if !FitsWithHeadroom(hard, corev1.ResourceList{}, policy) {
+		t.Error("FitsWithHeadroom without usage = false, want true")
+	}
+}
+
+func TestDecide_PercentileEstimator(t *testing.T) {
+	in := baseInput("10", "1", "1")
+	in.Window = spikyWindow(testNow, in.Policy.WindowDays)
+
+	if got := Decide(in); got.Direction != DirectionNone {
+		t.Fatalf("direction = %v, want none: the peak of 8 plus headroom matches the limit", got.Direction)
+	}
+
+	in.Policy.Percentile = 95
+	in.Policy.MaxShrinkStep = 0.9
+	got := Decide(in)
+	if got.Direction != DirectionShrink {
+		t.Fatalf("direction = %v, want shrink", got.Direction)
+	}
+	if want := "1255m"; targetCPU(t, got) != want {
+		t.Fatalf("target = %s, want %s (p95 of about 1 plus headroom)", targetCPU(t, got), want)
+	}
+	if !strings.Contains(got.Reason, "14-day p95") {
+		t.Fatalf("reason = %q, want it to name the estimator", got.Reason)
+	}
+}
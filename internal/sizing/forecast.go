@@ -0,0 +1,111 @@
+package sizing
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// forecastMinDays is how many covered days a trend needs. Fewer points let a
+// single noisy day tilt the fit.
+const forecastMinDays = 5
+
+// Trend is a straight line through the daily peaks of a window.
+type Trend struct {
+	// Slope is the change per day, in milli-units.
+	Slope float64
+	// Today is the line's value at today's date, in milli-units.
+	Today float64
+}
+
+// At returns the line's value days after today, in milli-units.
+func (t Trend) At(days int) int64 {
+	v := t.Today + t.Slope*float64(days)
+	if v <= 0 {
+		return 0
+	}
+	if v >= maxMilliValue {
+		return maxMilliValue
+	}
+	return int64(v)
+}
+
+// Trend fits a line through the daily peaks of the covered days of the
+// window with the Theil–Sen estimator: the slope is the median of the slopes
+// between every pair of days, the intercept the median of what remains. Up to
+// nearly a third of the days can be outliers without moving it, where a least
+// squares fit would follow a single spike. It reports ok=false with fewer than
+// forecastMinDays covered days.
+func (w Window) Trend(res corev1.ResourceName, now time.Time, windowDays int) (Trend, bool) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	oldest := today.AddDate(0, 0, -windowDays).Format(dateLayout)
+
+	var xs, ys []float64
+	for _, bucket := range w.Days {
+		if bucket.Date >= today.Format(dateLayout) || bucket.Date < oldest || !bucket.covered() {
+			continue
+		}
+		date, err := time.Parse(dateLayout, bucket.Date)
+		if err != nil {
+			continue
+		}
+		raw, ok := bucket.Peaks[string(res)]
+		if !ok {
+			continue
+		}
+		qty, err := resource.ParseQuantity(raw)
+		if err != nil || overflowsMilliValue(qty) {
+			continue
+		}
+		xs = append(xs, date.Sub(today).Hours()/24)
+		ys = append(ys, float64(qty.MilliValue()))
+	}
+	if len(xs) < forecastMinDays {
+		return Trend{}, false
+	}
+
+	slopes := make([]float64, 0, len(xs)*(len(xs)-1)/2)
+	for i := range xs {
+		for j := i + 1; j < len(xs); j++ {
+			if xs[i] != xs[j] {
+				slopes = append(slopes, (ys[j]-ys[i])/(xs[j]-xs[i]))
+			}
+		}
+	}
+	slope := median(slopes)
+	residuals := make([]float64, len(xs))
+	for i := range xs {
+		residuals[i] = ys[i] - slope*xs[i]
+	}
+	return Trend{Slope: slope, Today: median(residuals)}, true
+}
+
+// median sorts values and returns their median.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid]
+	}
+	return (values[mid-1] + values[mid]) / 2
+}
+
+// forecastFor returns the projected peak of res ForecastDays ahead, when the
+// window shows a rising trend. A flat or falling trend projects nothing: a
+// forecast may bring a grow forward, never a shrink.
+func forecastFor(in Input, res corev1.ResourceName) (int64, Trend, bool) {
+	if in.Policy.ForecastDays <= 0 {
+		return 0, Trend{}, false
+	}
+	trend, ok := in.Window.Trend(res, in.Now, in.Policy.WindowDays)
+	if !ok || trend.Slope <= 0 || math.IsNaN(trend.Slope) {
+		return 0, Trend{}, false
+	}
+	return trend.At(in.Policy.ForecastDays), trend, true
+}
@@ -0,0 +1,107 @@
+package sizing
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// trendingWindow samples every 5 minutes across the given number of completed
+// days ending yesterday. The usage of day i, counted from the oldest, is
+// peak(i) milli-CPU.
+func trendingWindow(now time.Time, days int, peak func(day int) int64) Window {
+	w := Window{Version: WindowVersion}
+	end := now.UTC().Truncate(24 * time.Hour)
+	for day := 0; day < days; day++ {
+		start := end.AddDate(0, 0, day-days)
+		cpu := fmt.Sprintf("%dm", peak(day))
+		for t := start; t.Before(start.Add(24 * time.Hour)); t = t.Add(5 * time.Minute) {
+			w.Observe(t, testUID, used(cpu), days)
+		}
+	}
+	return w
+}
+
+func TestWindow_TrendIgnoresASpike(t *testing.T) {
+	w := trendingWindow(testNow, 14, func(day int) int64 {
+		if day == 10 {
+			return 50000
+		}
+		return 4000 + 200*int64(day)
+	})
+
+	trend, ok := w.Trend(corev1.ResourceRequestsCPU, testNow, 14)
+	if !ok {
+		t.Fatal("Trend reported no data")
+	}
+	if trend.Slope < 199 || trend.Slope > 201 {
+		t.Errorf("slope = %v, want 200 per day", trend.Slope)
+	}
+	// Yesterday, day 13, peaked at 6600.
+	if got := trend.At(0); got < 6799 || got > 6801 {
+		t.Errorf("today = %d, want 6800", got)
+	}
+	if got := trend.At(30); got < 12799 || got > 12801 {
+		t.Errorf("in 30 days = %d, want 12800", got)
+	}
+}
+
+func TestWindow_TrendNeedsCoveredDays(t *testing.T) {
+	w := trendingWindow(testNow, forecastMinDays-1, func(day int) int64 { return 1000 * int64(day+1) })
+
+	if _, ok := w.Trend(corev1.ResourceRequestsCPU, testNow, 14); ok {
+		t.Fatal("Trend fitted a line through too few days")
+	}
+}
+
+func TestDecide_ForecastRaisesAGrow(t *testing.T) {
+	in := baseInput("6", "6600m", "1")
+	in.Window = trendingWindow(testNow, 14, func(day int) int64 { return 4000 + 200*int64(day) })
+
+	got := Decide(in)
+	if want := "8250m"; targetCPU(t, got) != want {
+		t.Fatalf("target without forecast = %s, want %s", targetCPU(t, got), want)
+	}
+
+	in.Policy.ForecastDays = 30
+	got = Decide(in)
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow", got.Direction)
+	}
+	if want := "16"; targetCPU(t, got) != want {
+		t.Fatalf("target = %s, want %s (12.8 projected plus headroom)", targetCPU(t, got), want)
+	}
+	if !strings.Contains(got.Reason, "30-day forecast of 12800m, the daily peak rising by 200m per day") {
+		t.Fatalf("reason = %q, want it to explain the projection", got.Reason)
+	}
+}
+
+func TestDecide_ForecastNeverTriggersOrShapesAShrink(t *testing.T) {
+	cases := map[string]struct {
+		hard  string
+		trend func(day int) int64
+	}{
+		// Falling demand: the shrink follows the observed peak only.
+		"falling": {"20", func(day int) int64 { return 8000 - 200*int64(day) }},
+		// Rising demand inside the band: the forecast alone does not grow.
+		"rising in band": {"10", func(day int) int64 { return 6000 + 100*int64(day) }},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			in := baseInput(tc.hard, "1", "1")
+			in.Window = trendingWindow(testNow, 14, tc.trend)
+			want := Decide(in)
+
+			in.Policy.ForecastDays = 30
+			got := Decide(in)
+
+			if got.Direction != want.Direction || got.Reason != want.Reason {
+				t.Fatalf("decision = %v %q, want %v %q as without a forecast",
+					got.Direction, got.Reason, want.Direction, want.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package sizing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// pair is a requests key of a quota and the limits key of the same family.
+// A quota whose limits fall below its requests admits no pod that sets both.
+type pair struct {
+	requests corev1.ResourceName
+	limits   corev1.ResourceName
+}
+
+// pairsOf returns the requests/limits pairs among the measurable keys of
+// hard: limits.X with requests.X, or with the bare X that means the same.
+func pairsOf(hard corev1.ResourceList) []pair {
+	measurable := func(res corev1.ResourceName) bool {
+		qty, ok := hard[res]
+		return ok && !overflowsMilliValue(qty) && !qty.IsZero()
+	}
+	var pairs []pair
+	for res := range hard {
+		name, ok := strings.CutPrefix(string(res), "limits.")
+		if !ok || !measurable(res) {
+			continue
+		}
+		for _, requests := range []corev1.ResourceName{corev1.ResourceName("requests." + name), corev1.ResourceName(name)} {
+			if measurable(requests) {
+				pairs = append(pairs, pair{requests: requests, limits: res})
+				break
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].limits < pairs[j].limits })
+	return pairs
+}
+
+// proposed returns the limit a decision leaves res at: its target, or the
+// current limit when it does not move.
+func proposed(in Input, targets map[corev1.ResourceName]resource.Quantity, res corev1.ResourceName) resource.Quantity {
+	if qty, ok := targets[res]; ok {
+		return qty
+	}
+	return in.Hard[res]
+}
+
+// setTarget records qty as the target of res, or drops the target when qty
+// no longer moves it in the decision's direction.
+func setTarget(
+	in Input,
+	targets map[corev1.ResourceName]resource.Quantity,
+	drivers map[corev1.ResourceName]string,
+	res corev1.ResourceName,
+	qty resource.Quantity,
+	driver string,
+	grow bool,
+) {
+	hard := in.Hard[res]
+	if cmp := qty.Cmp(hard); (grow && cmp <= 0) || (!grow && cmp >= 0) {
+		delete(targets, res)
+		delete(drivers, res)
+		return
+	}
+	targets[res] = qty
+	drivers[res] = driver
+}
+
+// ratioCeil returns milli divided by ratio, rounded up.
+func ratioCeil(milli int64, ratio float64) int64 {
+	return int64(math.Ceil(float64(milli) / ratio))
+}
+
+// keepPairsOnGrow pulls the partner of a grown key along: limits are raised
+// to at least the requests, and requests to at least the limits divided by
+// the configured ratio. When a cap stops the partner, the grown key is held
+// back to what the partner reached instead, which it explains like a cap, and
+// it reports whether that leaves a pending shortage without room.
+func keepPairsOnGrow(
+	in Input,
+	targets map[corev1.ResourceName]resource.Quantity,
+	drivers map[corev1.ResourceName]string,
+) ([]string, bool) {
+	var held []string
+	shortage := false
+	// raise lifts res to at least want, as far as its caps allow, and
+	// returns the limit it ends up at.
+	raise := func(res corev1.ResourceName, want resource.Quantity, driver string) resource.Quantity {
+		current := proposed(in, targets, res)
+		if want.Cmp(current) <= 0 {
+			return current
+		}
+		hard := in.Hard[res]
+		if ceiling, cause, ok := growCeiling(in, res, hard); ok && want.MilliValue() > ceiling {
+			limit := in.Policy.roundDown(res, quantizeDown(res, ceiling, hard.Format))
+			if limit.Cmp(current) < 0 {
+				limit = current
+			}
+			held = append(held, fmt.Sprintf("%s: the target of %s is capped at %s by %s",
+				res, want.String(), limit.String(), cause))
+			if limit.Cmp(current) == 0 {
+				return current
+			}
+			want = limit
+			driver += ", capped by " + cause
+		}
+		setTarget(in, targets, drivers, res, want, driver, true)
+		return want
+	}
+	// hold lowers the target of res to bound, never below its current limit.
+	hold := func(res corev1.ResourceName, bound resource.Quantity, partner corev1.ResourceName) resource.Quantity {
+		hard := in.Hard[res]
+		if bound.Cmp(hard) < 0 {
+			bound = hard
+		}
+		if target, ok := targets[res]; ok {
+			held = append(held, fmt.Sprintf("%s: the target of %s is held at %s because %s cannot follow it",
+				res, target.String(), bound.String(), partner))
+		}
+		if used, ok := in.Used[res]; ok && !overflowsMilliValue(used) {
+			shortage = shortage || shortageRemains(in, res, used.MilliValue(), bound)
+		}
+		setTarget(in, targets, drivers, res, bound,
+			fmt.Sprintf("%s, held back by %s", drivers[res], partner), true)
+		return bound
+	}
+
+	for _, p := range pairsOf(in.Hard) {
+		requests := proposed(in, targets, p.requests)
+		limits := proposed(in, targets, p.limits)
+		format := in.Hard[p.limits].Format
+
+		if limits.Cmp(requests) < 0 {
+			want := in.Policy.roundUp(p.limits, Quantize(p.limits, requests.MilliValue(), format))
+			limits = raise(p.limits, want, "pulled along by "+string(p.requests))
+			if limits.Cmp(requests) < 0 {
+				requests = hold(p.requests, limits, p.limits)
+			}
+		}
+
+		ratio := in.Policy.MaxLimitRequestRatioFor(p.limits)
+		if ratio >= 1 && float64(limits.MilliValue()) > ratio*float64(requests.MilliValue()) {
+			format := in.Hard[p.requests].Format
+			want := in.Policy.roundUp(p.requests,
+				Quantize(p.requests, ratioCeil(limits.MilliValue(), ratio), format))
+			requests = raise(p.requests, want, fmt.Sprintf("pulled along by %s at a limit/request ratio of at most %g",
+				p.limits, ratio))
+			if float64(limits.MilliValue()) > ratio*float64(requests.MilliValue()) {
+				bound := int64(ratio * float64(requests.MilliValue()))
+				hold(p.limits, in.Policy.roundDown(p.limits, quantizeDown(p.limits, bound, format)), p.requests)
+			}
+		}
+	}
+	return held, shortage
+}
+
+// keepPairsOnShrink caps a shrink that would break a pair: limits stay at or
+// above the requests, and requests at or above the limits divided by the
+// configured ratio. A shrink capped back to the current limit is dropped.
+func keepPairsOnShrink(
+	in Input,
+	targets map[corev1.ResourceName]resource.Quantity,
+	drivers map[corev1.ResourceName]string,
+) {
+	for _, p := range pairsOf(in.Hard) {
+		requests := proposed(in, targets, p.requests)
+		limits := proposed(in, targets, p.limits)
+
+		if _, shrinking := targets[p.limits]; shrinking && limits.Cmp(requests) < 0 {
+			limits = in.Policy.roundUp(p.limits,
+				Quantize(p.limits, requests.MilliValue(), in.Hard[p.limits].Format))
+			setTarget(in, targets, drivers, p.limits, limits, "kept at or above "+string(p.requests), false)
+			limits = proposed(in, targets, p.limits)
+		}
+
+		ratio := in.Policy.MaxLimitRequestRatioFor(p.limits)
+		_, shrinking := targets[p.requests]
+		if shrinking && ratio >= 1 && float64(limits.MilliValue()) > ratio*float64(requests.MilliValue()) {
+			requests = in.Policy.roundUp(p.requests,
+				Quantize(p.requests, ratioCeil(limits.MilliValue(), ratio), in.Hard[p.requests].Format))
+			setTarget(in, targets, drivers, p.requests, requests,
+				fmt.Sprintf("kept at or above %s divided by %g", p.limits, ratio), false)
+		}
+	}
+}
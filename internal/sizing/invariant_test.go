@@ -0,0 +1,126 @@
+package sizing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// pairInput builds an Input for requests.cpu and limits.cpu whose window is
+// fully covered at the current usage of both.
+func pairInput(hardRequests, hardLimits, usedRequests, usedLimits string) Input {
+	policy := DefaultPolicy()
+	usedList := corev1.ResourceList{
+		corev1.ResourceRequestsCPU: resource.MustParse(usedRequests),
+		corev1.ResourceLimitsCPU:   resource.MustParse(usedLimits),
+	}
+	w := Window{Version: WindowVersion}
+	start := testNow.UTC().AddDate(0, 0, -policy.WindowDays).Truncate(24 * time.Hour)
+	for t := start; t.Before(testNow.UTC().Truncate(24 * time.Hour)); t = t.Add(5 * time.Minute) {
+		w.Observe(t, testUID, usedList, policy.WindowDays)
+	}
+	return Input{
+		Now: testNow,
+		Hard: corev1.ResourceList{
+			corev1.ResourceRequestsCPU: resource.MustParse(hardRequests),
+			corev1.ResourceLimitsCPU:   resource.MustParse(hardLimits),
+		},
+		Used:   usedList,
+		Window: w,
+		Policy: policy,
+	}
+}
+
+func targetsOf(d Decision) map[string]string {
+	out := map[string]string{}
+	for res, qty := range d.Targets {
+		out[string(res)] = qty.String()
+	}
+	return out
+}
+
+func TestDecide_GrowOfRequestsPullsLimitsAlong(t *testing.T) {
+	// requests: used 10 plus a shortage of 6 -> 20, above the limit of 12.
+	in := pairInput("10", "12", "10", "10")
+	in.Deficits = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 6000}
+
+	got := Decide(in)
+
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow", got.Direction)
+	}
+	targets := targetsOf(got)
+	if targets["requests.cpu"] != "20" || targets["limits.cpu"] != "20" {
+		t.Fatalf("targets = %v, want requests.cpu and limits.cpu at 20", targets)
+	}
+	if !strings.Contains(got.Reason, "pulled along by requests.cpu") {
+		t.Errorf("reason = %q, want the pull explained", got.Reason)
+	}
+}
+
+func TestDecide_GrowOfLimitsPullsRequestsWithinTheRatio(t *testing.T) {
+	// limits: used 8 plus a shortage of 8 -> 20, ten times the requests.
+	in := pairInput("2", "8", "2", "8")
+	in.Deficits = map[corev1.ResourceName]int64{corev1.ResourceLimitsCPU: 8000}
+	in.Policy.MaxLimitRequestRatio[corev1.ResourceCPU] = 4
+
+	got := Decide(in)
+
+	targets := targetsOf(got)
+	if targets["limits.cpu"] != "20" || targets["requests.cpu"] != "5" {
+		t.Fatalf("targets = %v, want limits.cpu 20 and requests.cpu 5", targets)
+	}
+}
+
+func TestDecide_CappedLimitsHoldRequestsBack(t *testing.T) {
+	in := pairInput("10", "12", "10", "10")
+	in.Deficits = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 6000}
+	in.Policy.Max[corev1.ResourceLimitsCPU] = resource.MustParse("15")
+
+	got := Decide(in)
+
+	targets := targetsOf(got)
+	if targets["requests.cpu"] != "15" || targets["limits.cpu"] != "15" {
+		t.Fatalf("targets = %v, want both held at 15", targets)
+	}
+	if len(got.Capped) != 2 || !got.ShortageRemains {
+		t.Errorf("capped = %v, shortageRemains = %v, want both caps and a remaining shortage",
+			got.Capped, got.ShortageRemains)
+	}
+}
+
+func TestDecide_ShrinkOfLimitsStopsAtTheRequests(t *testing.T) {
+	// limits: used 4 -> 5, below the unchanged requests of 8.
+	in := pairInput("8", "16", "7", "4")
+	in.Policy.MaxShrinkStep = 0.9
+
+	got := Decide(in)
+
+	if got.Direction != DirectionShrink {
+		t.Fatalf("direction = %v, want shrink", got.Direction)
+	}
+	targets := targetsOf(got)
+	if len(targets) != 1 || targets["limits.cpu"] != "8" {
+		t.Fatalf("targets = %v, want only limits.cpu at 8", targets)
+	}
+	if !strings.Contains(got.Reason, "kept at or above requests.cpu") {
+		t.Errorf("reason = %q, want the cap explained", got.Reason)
+	}
+}
+
+func TestDecide_ShrinkOfRequestsKeepsTheRatio(t *testing.T) {
+	// requests: used 2 -> 2.5, but limits of 16 allow no less than 8.
+	in := pairInput("10", "16", "2", "14")
+	in.Policy.MaxShrinkStep = 0.9
+	in.Policy.MaxLimitRequestRatio[DefaultKey] = 2
+
+	got := Decide(in)
+
+	targets := targetsOf(got)
+	if targets["requests.cpu"] != "8" {
+		t.Fatalf("targets = %v, want requests.cpu at 8", targets)
+	}
+}
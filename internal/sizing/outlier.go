@@ -0,0 +1,142 @@
+package sizing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// outlierMinDays is how many daily peaks the median needs before a day
+	// can stand out from it.
+	outlierMinDays = 5
+	// outlierSpread is how many scaled median absolute deviations a daily
+	// peak has to sit above the median of the window to be an outlier.
+	outlierSpread = 5
+	// outlierRatio is how far above the median it has to sit as well, so a
+	// window of near-identical days does not turn a small rise into one.
+	outlierRatio = 1.5
+	// madScale makes the median absolute deviation estimate the standard
+	// deviation of normally distributed peaks.
+	madScale = 1.4826
+)
+
+// Outliers returns the days of the window whose peak of res sits far above
+// the rest, with their peaks in milli-units, and the median daily peak. A day
+// is an outlier when its peak exceeds both the median plus outlierSpread
+// scaled median absolute deviations and outlierRatio times the median. The
+// median and its deviation hardly move for a few extreme days, where a mean
+// and standard deviation would be dragged up by the very spike in question.
+func (w Window) Outliers(res corev1.ResourceName, now time.Time, windowDays int) (map[string]int64, int64) {
+	today := now.UTC().Format(dateLayout)
+	oldest := now.UTC().AddDate(0, 0, -windowDays).Format(dateLayout)
+
+	peaks := map[string]int64{}
+	var values []float64
+	for _, bucket := range w.Days {
+		if bucket.Date >= today || bucket.Date < oldest {
+			continue
+		}
+		raw, ok := bucket.Peaks[string(res)]
+		if !ok {
+			continue
+		}
+		qty, err := resource.ParseQuantity(raw)
+		if err != nil || overflowsMilliValue(qty) {
+			continue
+		}
+		peaks[bucket.Date] = qty.MilliValue()
+		values = append(values, float64(qty.MilliValue()))
+	}
+	if len(values) < outlierMinDays {
+		return nil, 0
+	}
+
+	mid := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - mid)
+	}
+	limit := math.Max(mid+outlierSpread*madScale*median(deviations), mid*outlierRatio)
+
+	outliers := map[string]int64{}
+	for date, peak := range peaks {
+		if float64(peak) > limit {
+			outliers[date] = peak
+		}
+	}
+	return outliers, int64(mid)
+}
+
+// windowEstimate returns the demand the window suggests for res, its
+// peak or the configured percentile of its samples, and what drove it. notes
+// explain what was left out of it, for the PR body.
+func windowEstimate(in Input, res corev1.ResourceName, format resource.Format) (int64, string, []string, bool) {
+	notes := ignoredNotes(in, res, format)
+	if in.Policy.Percentile > 0 {
+		estimate, ok := in.Window.Percentile(res, in.Now, in.Policy.WindowDays, in.Policy.Percentile)
+		return estimate, fmt.Sprintf("%d-day p%g", in.Policy.WindowDays, in.Policy.Percentile), notes, ok
+	}
+
+	var skip map[string]bool
+	if in.Policy.ExcludeOutliers {
+		outliers, typical := in.Window.Outliers(res, in.Now, in.Policy.WindowDays)
+		dates := make([]string, 0, len(outliers))
+		for date := range outliers {
+			dates = append(dates, date)
+		}
+		sort.Strings(dates)
+		skip = make(map[string]bool, len(dates))
+		for _, date := range dates {
+			skip[date] = true
+			notes = append(notes, fmt.Sprintf("- `%s`: left out the peak of %s on %s, far above the "+
+				"median daily peak of %s", res, display(res, outliers[date], format), date,
+				display(res, typical, format)))
+		}
+	}
+	peak, day, ok := in.Window.peakExcept(res, in.Now, in.Policy.WindowDays, skip)
+	driver := fmt.Sprintf("%d-day peak", in.Policy.WindowDays)
+	if workload := day.Drivers[string(res)]; ok && workload != "" {
+		driver += fmt.Sprintf(" on %s, mostly %s", day.Date, workload)
+	}
+	return peak, driver, notes, ok
+}
+
+// ignoredNotes reports the highest usage of res the window left out because
+// its workloads are ignored in sizing.
+func ignoredNotes(in Input, res corev1.ResourceName, format resource.Format) []string {
+	today := in.Now.UTC().Format(dateLayout)
+	oldest := in.Now.UTC().AddDate(0, 0, -in.Policy.WindowDays).Format(dateLayout)
+
+	var (
+		most int64
+		date string
+	)
+	for _, bucket := range in.Window.Days {
+		if bucket.Date >= today || bucket.Date < oldest {
+			continue
+		}
+		qty, err := resource.ParseQuantity(bucket.Excluded[string(res)])
+		if err != nil || overflowsMilliValue(qty) {
+			continue
+		}
+		if milli := qty.MilliValue(); milli > most {
+			most, date = milli, bucket.Date
+		}
+	}
+	if most == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("- `%s`: left out up to %s used by workloads ignored in sizing, on %s",
+		res, display(res, most, format), date)}
+}
+
+// display renders a milli-value of res the way a target would be.
+func display(res corev1.ResourceName, milli int64, format resource.Format) string {
+	qty := Quantize(res, milli, format)
+	return qty.String()
+}
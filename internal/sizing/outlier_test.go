@@ -0,0 +1,107 @@
+package sizing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// attributedWindow samples 1 CPU every 5 minutes across 14 completed days,
+// except for one hour on the third day before now, when a migration adds
+// extra CPU. ignored reports that usage as excluded.
+func attributedWindow(now time.Time, extra string, ignored bool) Window {
+	w := Window{Version: WindowVersion}
+	end := now.UTC().Truncate(24 * time.Hour)
+	spike := end.AddDate(0, 0, -3).Add(12 * time.Hour)
+	for t := end.AddDate(0, 0, -14); t.Before(end); t = t.Add(5 * time.Minute) {
+		sample := Sample{Used: used("1"), Drivers: map[corev1.ResourceName]string{
+			corev1.ResourceRequestsCPU: "Deployment/api",
+		}}
+		if !t.Before(spike) && t.Before(spike.Add(time.Hour)) {
+			total := resource.MustParse("1")
+			total.Add(resource.MustParse(extra))
+			sample.Used = corev1.ResourceList{corev1.ResourceRequestsCPU: total}
+			sample.Drivers[corev1.ResourceRequestsCPU] = "Job/migrate"
+			if ignored {
+				sample.Excluded = used(extra)
+			}
+		}
+		w.ObserveSample(t, testUID, sample, 14)
+	}
+	return w
+}
+
+func TestWindow_ObserveSampleLeavesExcludedUsageOut(t *testing.T) {
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.UTC)
+	w := attributedWindow(now, "7", true)
+
+	if peak, _ := w.Peak(corev1.ResourceRequestsCPU, now, 14); peak != 1000 {
+		t.Fatalf("peak = %d, want 1000 without the ignored migration", peak)
+	}
+	day := w.Days[len(w.Days)-3]
+	if day.Excluded["requests.cpu"] != "7" {
+		t.Errorf("excluded = %v, want 7 recorded on the day", day.Excluded)
+	}
+	if day.Drivers["requests.cpu"] != "Deployment/api" {
+		t.Errorf("driver = %v, want the workload of the first peak sample", day.Drivers)
+	}
+}
+
+func TestWindow_Outliers(t *testing.T) {
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.UTC)
+
+	outliers, typical := attributedWindow(now, "7", false).Outliers(corev1.ResourceRequestsCPU, now, 14)
+	if typical != 1000 {
+		t.Errorf("median = %d, want 1000", typical)
+	}
+	if len(outliers) != 1 || outliers["2026-08-05"] != 8000 {
+		t.Errorf("outliers = %v, want the migration day at 8000", outliers)
+	}
+
+	// Twice the usual peak is a busy day, not an outlier: it must reach
+	// outlierRatio times the median as well.
+	if outliers, _ := attributedWindow(now, "400m", false).Outliers(corev1.ResourceRequestsCPU, now, 14); len(outliers) != 0 {
+		t.Errorf("outliers = %v, want none for a peak 40 %% above the median", outliers)
+	}
+}
+
+func TestDecide_ExclusionsAreExplained(t *testing.T) {
+	in := baseInput("10", "500m", "1")
+	in.Policy.MaxShrinkStep = 0.9
+
+	in.Window = attributedWindow(testNow, "7", false)
+	got := Decide(in)
+	if got.Direction != DirectionNone {
+		t.Fatalf("direction = %v, want none: the migration day holds the peak at 8", got.Direction)
+	}
+	if len(got.Exclusions) != 0 {
+		t.Fatalf("exclusions = %v, want none", got.Exclusions)
+	}
+
+	in.Policy.ExcludeOutliers = true
+	got = Decide(in)
+	if got.Direction != DirectionShrink || targetCPU(t, got) != "1250m" {
+		t.Fatalf("decision = %v %v, want a shrink to 1250m", got.Direction, got.Targets)
+	}
+	want := "- `requests.cpu`: left out the peak of 8 on 2026-08-05, far above the median daily peak of 1"
+	if len(got.Exclusions) != 1 || got.Exclusions[0] != want {
+		t.Fatalf("exclusions = %q, want %q", got.Exclusions, want)
+	}
+	if strings.Contains(got.Reason, want) {
+		t.Fatalf("reason = %q, want the exclusion left to Exclusions", got.Reason)
+	}
+	if !strings.Contains(got.Reason, "14-day peak on ") || !strings.Contains(got.Reason, "mostly Deployment/api") {
+		t.Fatalf("reason = %q, want it to attribute the peak", got.Reason)
+	}
+
+	in.Policy.ExcludeOutliers = false
+	in.Window = attributedWindow(testNow, "7", true)
+	got = Decide(in)
+	want = "- `requests.cpu`: left out up to 7 used by workloads ignored in sizing, on 2026-08-05"
+	if got.Direction != DirectionShrink || len(got.Exclusions) != 1 || got.Exclusions[0] != want {
+		t.Fatalf("decision = %v %q, want a shrink explaining %q", got.Direction, got.Exclusions, want)
+	}
+}
@@ -48,13 +48,55 @@ type PolicyWarning struct {
 type Policy struct {
 	Headroom map[corev1.ResourceName]float64
 	Min      map[corev1.ResourceName]resource.Quantity
+	// Max caps the limit a grow may propose, whatever the demand. MaxIncrease
+	// caps how far a single grow PR may raise it.
+	Max         map[corev1.ResourceName]resource.Quantity
+	MaxIncrease map[corev1.ResourceName]resource.Quantity
+	// Step rounds targets to multiples of a readable unit: grows up, shrinks
+	// down, but never below their floor.
+	Step map[corev1.ResourceName]resource.Quantity
+	// MaxLimitRequestRatio bounds limits.<family> to that multiple of its
+	// requests, as a LimitRange does for containers. Unset leaves only the
+	// bound that limits stay at or above requests.
+	MaxLimitRequestRatio map[corev1.ResourceName]float64
+
+	Tolerance  float64
+	WindowDays int
+	// Percentile sizes the target from that percentile of the window's
+	// samples instead of its peak, which lets a single abnormal day pass
+	// without setting the target for the whole window. Zero means the peak.
+	Percentile float64
+	// ForecastDays projects a rising trend of the daily peaks that many days
+	// ahead when a quota grows, so it grows once for the month instead of
+	// every cooldown. Zero switches the forecast off.
+	ForecastDays int
+	// Seasonality keeps a shrink above the peak that recurred in every
+	// observed week or month, and holds shrinks back until one full cycle
+	// has been observed.
+	Seasonality Seasonality
+	// ExcludeOutliers leaves daily peaks far above the rest of the window
+	// out of the peak estimator.
+	ExcludeOutliers bool
+	// ScaleOutFloor keeps a shrink above what the namespace's workloads need
+	// at their HPA maxReplicas, or their replicas without an HPA.
+	ScaleOutFloor bool
+	// CronLookahead is how far ahead CronJob runs are projected, so a quota
+	// grows before a run that would not fit. Zero switches it off.
+	CronLookahead time.Duration
+
+	MaxShrinkStep float64
+	// MaxGrowStep caps a single grow PR at that share above the current
+	// limit. Zero leaves grows uncapped.
+	MaxGrowStep float64
 
-	Tolerance      float64
-	WindowDays     int
-	MaxShrinkStep  float64
 	ShrinkCooldown time.Duration
 	ShrinkPRTTL    time.Duration
 	GrowCooldown   time.Duration
+	// GrowPRTTL closes a grow PR nobody merged. GrowPRObsoleteAfter closes
+	// one earlier once no decision has asked for it for that long and usage
+	// fits the current limits.
+	GrowPRTTL           time.Duration
+	GrowPRObsoleteAfter time.Duration
 
 	Enabled       bool
 	ShrinkEnabled bool
@@ -63,16 +105,25 @@ type Policy struct {
 // DefaultPolicy returns the built-in defaults from spec 7.2.
 func DefaultPolicy() Policy {
 	return Policy{
-		Headroom:       map[corev1.ResourceName]float64{DefaultKey: 0.25},
-		Min:            map[corev1.ResourceName]resource.Quantity{},
-		Tolerance:      0.15,
-		WindowDays:     14,
-		MaxShrinkStep:  0.25,
-		ShrinkCooldown: 7 * 24 * time.Hour,
-		ShrinkPRTTL:    7 * 24 * time.Hour,
-		GrowCooldown:   60 * time.Minute,
-		Enabled:        true,
-		ShrinkEnabled:  true,
+		Headroom:             map[corev1.ResourceName]float64{DefaultKey: 0.25},
+		Min:                  map[corev1.ResourceName]resource.Quantity{},
+		Max:                  map[corev1.ResourceName]resource.Quantity{},
+		MaxIncrease:          map[corev1.ResourceName]resource.Quantity{},
+		Step:                 map[corev1.ResourceName]resource.Quantity{},
+		MaxLimitRequestRatio: map[corev1.ResourceName]float64{},
+		Tolerance:            0.15,
+		WindowDays:           14,
+		MaxShrinkStep:        0.25,
+		ShrinkCooldown:       7 * 24 * time.Hour,
+		ShrinkPRTTL:          7 * 24 * time.Hour,
+		GrowCooldown:         60 * time.Minute,
+		Enabled:              true,
+		ShrinkEnabled:        true,
+		ScaleOutFloor:        true,
+		CronLookahead:        24 * time.Hour,
+
+		GrowPRTTL:           14 * 24 * time.Hour,
+		GrowPRObsoleteAfter: 24 * time.Hour,
 	}
 }
 
@@ -100,11 +151,42 @@ func (p Policy) HeadroomFor(res corev1.ResourceName) float64 {
 // name the docs give as the example, and it never matches a quota key
 // exactly.
 func (p Policy) MinFor(res corev1.ResourceName) (resource.Quantity, bool) {
-	if q, ok := p.Min[res]; ok {
+	return quantityFor(p.Min, res)
+}
+
+// MaxFor returns the configured absolute upper bound for a quota key, resolved
+// the same way as MinFor.
+func (p Policy) MaxFor(res corev1.ResourceName) (resource.Quantity, bool) {
+	return quantityFor(p.Max, res)
+}
+
+// MaxIncreaseFor returns how far a single grow PR may raise a quota key,
+// resolved the same way as MinFor.
+func (p Policy) MaxIncreaseFor(res corev1.ResourceName) (resource.Quantity, bool) {
+	return quantityFor(p.MaxIncrease, res)
+}
+
+// MaxLimitRequestRatioFor resolves the ratio for a quota key like HeadroomFor:
+// exact match, then family, then the namespace default. Zero means unset.
+func (p Policy) MaxLimitRequestRatioFor(res corev1.ResourceName) float64 {
+	if v, ok := p.MaxLimitRequestRatio[res]; ok {
+		return v
+	}
+	if family, ok := resourceFamily(res); ok {
+		if v, ok := p.MaxLimitRequestRatio[family]; ok {
+			return v
+		}
+	}
+	return p.MaxLimitRequestRatio[DefaultKey]
+}
+
+// quantityFor looks a quota key up by exact match, then by resource family.
+func quantityFor(values map[corev1.ResourceName]resource.Quantity, res corev1.ResourceName) (resource.Quantity, bool) {
+	if q, ok := values[res]; ok {
 		return q, true
 	}
 	if family, ok := resourceFamily(res); ok {
-		if q, ok := p.Min[family]; ok {
+		if q, ok := values[family]; ok {
 			return q, true
 		}
 	}
@@ -146,6 +228,18 @@ func parseScalar(name, value string, out *Policy) PolicyWarning {
 			return PolicyWarning{}
 		}
 		return rejectionWarning(name, value, "must be a valid resource quantity")
+	case strings.HasSuffix(name, "-max"):
+		if q, err := resource.ParseQuantity(value); err == nil && q.Sign() > 0 {
+			out.Max[corev1.ResourceName(strings.TrimSuffix(name, "-max"))] = q
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a positive resource quantity")
+	case strings.HasSuffix(name, "-max-increase"):
+		if q, err := resource.ParseQuantity(value); err == nil && q.Sign() > 0 {
+			out.MaxIncrease[corev1.ResourceName(strings.TrimSuffix(name, "-max-increase"))] = q
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a positive resource quantity")
 	case name == "tolerance":
 		if v, ok := parseFraction(value); ok && v >= 0 && v < 1 {
 			out.Tolerance = v
@@ -158,12 +252,40 @@ func parseScalar(name, value string, out *Policy) PolicyWarning {
 			return PolicyWarning{}
 		}
 		return rejectionWarning(name, value, "must be a fraction in (0, 1), e.g. \"0.25\" or \"25%\"")
+	case name == "max-grow-step":
+		if v, ok := parseFraction(value); ok && v > 0 {
+			out.MaxGrowStep = v
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a fraction > 0, e.g. \"0.5\" or \"50%\"")
+	case name == "estimator":
+		if v, ok := parseEstimator(value); ok {
+			out.Percentile = v
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be \"peak\" or a percentile such as \"p95\"")
+	case name == "forecast-days":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			out.ForecastDays = v
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a non-negative integer")
+	case name == "seasonality":
+		switch Seasonality(value) {
+		case SeasonalityWeekly, SeasonalityMonthly:
+			out.Seasonality = Seasonality(value)
+			return PolicyWarning{}
+		case "off":
+			out.Seasonality = SeasonalityOff
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be \"weekly\", \"monthly\" or \"off\"")
 	case name == "window-days":
-		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 && v <= maxWindowDays {
 			out.WindowDays = v
 			return PolicyWarning{}
 		}
-		return rejectionWarning(name, value, "must be a positive integer")
+		return rejectionWarning(name, value, fmt.Sprintf("must be an integer from 1 to %d", maxWindowDays))
 	case name == "shrink-cooldown-days":
 		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
 			out.ShrinkCooldown = time.Duration(v) * 24 * time.Hour
@@ -176,12 +298,55 @@ func parseScalar(name, value string, out *Policy) PolicyWarning {
 			return PolicyWarning{}
 		}
 		return rejectionWarning(name, value, "must be a positive integer")
+	case name == "grow-pr-ttl-days":
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			out.GrowPRTTL = time.Duration(v) * 24 * time.Hour
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a positive integer")
+	case name == "grow-pr-obsolete-hours":
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			out.GrowPRObsoleteAfter = time.Duration(v) * time.Hour
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a positive integer")
 	case name == "cooldown-minutes":
 		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
 			out.GrowCooldown = time.Duration(v) * time.Minute
 			return PolicyWarning{}
 		}
 		return rejectionWarning(name, value, "must be a non-negative integer")
+	case name == "scale-out-floor":
+		if v, err := strconv.ParseBool(value); err == nil {
+			out.ScaleOutFloor = v
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be \"true\" or \"false\"")
+	case name == "cronjob-lookahead-hours":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			out.CronLookahead = time.Duration(v) * time.Hour
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a non-negative integer")
+	case name == "exclude-outliers":
+		if v, err := strconv.ParseBool(value); err == nil {
+			out.ExcludeOutliers = v
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be \"true\" or \"false\"")
+	case strings.HasSuffix(name, "max-limit-request-ratio"):
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 1 {
+			out.MaxLimitRequestRatio[suffixKey(name, "max-limit-request-ratio")] = v
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a number >= 1, e.g. \"2\"")
+	case strings.HasSuffix(name, "-step"):
+		// After the step caps above, which end in "-step" as well.
+		if q, err := resource.ParseQuantity(value); err == nil && q.Sign() > 0 && !overflowsMilliValue(q) {
+			out.Step[corev1.ResourceName(strings.TrimSuffix(name, "-step"))] = q
+			return PolicyWarning{}
+		}
+		return rejectionWarning(name, value, "must be a positive resource quantity")
 	case name == "enabled":
 		out.Enabled = value != falseValue
 	case name == "shrink-enabled":
@@ -240,6 +405,10 @@ func ParsePolicy(annotations map[string]string, base Policy) (Policy, []PolicyWa
 	out := base
 	out.Headroom = copyFloatMap(base.Headroom)
 	out.Min = copyQuantityMap(base.Min)
+	out.Max = copyQuantityMap(base.Max)
+	out.MaxIncrease = copyQuantityMap(base.MaxIncrease)
+	out.Step = copyQuantityMap(base.Step)
+	out.MaxLimitRequestRatio = copyFloatMap(base.MaxLimitRequestRatio)
 
 	// Collected separately so precedence is applied deterministically,
 	// independent of Go's random map iteration order.
@@ -319,6 +488,22 @@ func suffixKey(name, suffix string) corev1.ResourceName {
 	return corev1.ResourceName(trimmed)
 }
 
+// parseEstimator accepts "peak", which yields 0, and "pNN" for a percentile
+// strictly between 0 and 100, such as "p95" or "p99.5".
+func parseEstimator(value string) (float64, bool) {
+	if strings.EqualFold(value, "peak") {
+		return 0, true
+	}
+	if !strings.HasPrefix(value, "p") && !strings.HasPrefix(value, "P") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(value[1:], 64)
+	if err != nil || v <= 0 || v >= 100 {
+		return 0, false
+	}
+	return v, true
+}
+
 // parseFraction accepts "0.25" and "25%" and returns a fraction.
 func parseFraction(value string) (float64, bool) {
 	if strings.HasSuffix(value, "%") {
@@ -140,15 +140,29 @@ func TestParsePolicy_MigrationChain(t *testing.T) {
 
 func TestParsePolicy_ScalarsAndMin(t *testing.T) {
 	p, _ := ParsePolicy(map[string]string{
-		"resizer.io/tolerance":            "0.1",
-		"resizer.io/window-days":          "30",
-		"resizer.io/shrink-cooldown-days": "14",
-		"resizer.io/max-shrink-step":      "15%",
-		"resizer.io/shrink-pr-ttl-days":   "3",
-		"resizer.io/cooldown-minutes":     "120",
-		"resizer.io/enabled":              "false",
-		"resizer.io/shrink-enabled":       "false",
-		"resizer.io/requests.cpu-min":     "2",
+		"resizer.io/tolerance":                   "0.1",
+		"resizer.io/window-days":                 "30",
+		"resizer.io/shrink-cooldown-days":        "14",
+		"resizer.io/max-shrink-step":             "15%",
+		"resizer.io/shrink-pr-ttl-days":          "3",
+		"resizer.io/grow-pr-ttl-days":            "5",
+		"resizer.io/grow-pr-obsolete-hours":      "6",
+		"resizer.io/cooldown-minutes":            "120",
+		"resizer.io/enabled":                     "false",
+		"resizer.io/shrink-enabled":              "false",
+		"resizer.io/requests.cpu-min":            "2",
+		"resizer.io/estimator":                   "p95",
+		"resizer.io/forecast-days":               "30",
+		"resizer.io/seasonality":                 "monthly",
+		"resizer.io/exclude-outliers":            "true",
+		"resizer.io/cpu-max":                     "32",
+		"resizer.io/memory-max-increase":         "8Gi",
+		"resizer.io/max-grow-step":               "50%",
+		"resizer.io/memory-step":                 "1Gi",
+		"resizer.io/max-limit-request-ratio":     "2",
+		"resizer.io/cpu-max-limit-request-ratio": "4",
+		"resizer.io/scale-out-floor":             "false",
+		"resizer.io/cronjob-lookahead-hours":     "6",
 	}, DefaultPolicy())
 
 	if p.Tolerance != 0.1 {
@@ -160,6 +174,48 @@ func TestParsePolicy_ScalarsAndMin(t *testing.T) {
 	if p.ShrinkCooldown != 14*24*time.Hour {
 		t.Errorf("shrinkCooldown = %v, want 336h", p.ShrinkCooldown)
 	}
+	if p.ScaleOutFloor {
+		t.Errorf("scaleOutFloor = true, want false")
+	}
+	if p.CronLookahead != 6*time.Hour {
+		t.Errorf("cronLookahead = %v, want 6h", p.CronLookahead)
+	}
+	if !p.ExcludeOutliers {
+		t.Errorf("excludeOutliers = false, want true")
+	}
+	if p.Seasonality != SeasonalityMonthly {
+		t.Errorf("seasonality = %q, want monthly", p.Seasonality)
+	}
+	if p.ForecastDays != 30 {
+		t.Errorf("forecastDays = %d, want 30", p.ForecastDays)
+	}
+	if p.Percentile != 95 {
+		t.Errorf("percentile = %v, want 95", p.Percentile)
+	}
+	if p.MaxGrowStep != 0.5 {
+		t.Errorf("maxGrowStep = %v, want 0.5", p.MaxGrowStep)
+	}
+	if max, ok := p.MaxFor(corev1.ResourceLimitsCPU); !ok || max.String() != "32" {
+		t.Errorf("MaxFor(limits.cpu) = %v/%v, want 32/true", max.String(), ok)
+	}
+	if inc, ok := p.MaxIncreaseFor(corev1.ResourceRequestsMemory); !ok || inc.String() != "8Gi" {
+		t.Errorf("MaxIncreaseFor(requests.memory) = %v/%v, want 8Gi/true", inc.String(), ok)
+	}
+	if step, ok := p.StepFor(corev1.ResourceLimitsMemory); !ok || step.String() != "1Gi" {
+		t.Errorf("StepFor(limits.memory) = %v/%v, want 1Gi/true", step.String(), ok)
+	}
+	if r := p.MaxLimitRequestRatioFor(corev1.ResourceLimitsCPU); r != 4 {
+		t.Errorf("MaxLimitRequestRatioFor(limits.cpu) = %v, want 4", r)
+	}
+	if r := p.MaxLimitRequestRatioFor(corev1.ResourceLimitsMemory); r != 2 {
+		t.Errorf("MaxLimitRequestRatioFor(limits.memory) = %v, want the default 2", r)
+	}
+	if p.MaxShrinkStep != 0.15 || p.MaxGrowStep != 0.5 {
+		t.Errorf("the step caps were read as rounding steps")
+	}
+	if _, ok := p.MaxFor(corev1.ResourceRequestsMemory); ok {
+		t.Errorf("MaxFor(requests.memory) reported a value, want none")
+	}
 	if p.MaxShrinkStep != 0.15 {
 		t.Errorf("maxShrinkStep = %v, want 0.15", p.MaxShrinkStep)
 	}
@@ -169,6 +225,12 @@ func TestParsePolicy_ScalarsAndMin(t *testing.T) {
 	if p.GrowCooldown != 120*time.Minute {
 		t.Errorf("growCooldown = %v, want 120m", p.GrowCooldown)
 	}
+	if p.GrowPRTTL != 5*24*time.Hour {
+		t.Errorf("growPRTTL = %v, want 120h", p.GrowPRTTL)
+	}
+	if p.GrowPRObsoleteAfter != 6*time.Hour {
+		t.Errorf("growPRObsoleteAfter = %v, want 6h", p.GrowPRObsoleteAfter)
+	}
 	if p.Enabled || p.ShrinkEnabled {
 		t.Errorf("enabled = %v, shrinkEnabled = %v, want false/false",
 			p.Enabled, p.ShrinkEnabled)
@@ -372,12 +434,40 @@ func TestParsePolicy_RejectsInvalidValuesWithWarning(t *testing.T) {
 			func(p Policy) any { return p.WindowDays }, base.WindowDays},
 		{"window-days not a number", "resizer.io/window-days", "abc",
 			func(p Policy) any { return p.WindowDays }, base.WindowDays},
+		{"window-days above the cap", "resizer.io/window-days", "91",
+			func(p Policy) any { return p.WindowDays }, base.WindowDays},
+		{"estimator unknown", "resizer.io/estimator", "mean",
+			func(p Policy) any { return p.Percentile }, base.Percentile},
+		{"estimator p100", "resizer.io/estimator", "p100",
+			func(p Policy) any { return p.Percentile }, base.Percentile},
+		{"forecast-days negative", "resizer.io/forecast-days", "-1",
+			func(p Policy) any { return p.ForecastDays }, base.ForecastDays},
+		{"seasonality unknown", "resizer.io/seasonality", "yearly",
+			func(p Policy) any { return p.Seasonality }, base.Seasonality},
 		{"shrink-cooldown-days negative", "resizer.io/shrink-cooldown-days", "-1",
 			func(p Policy) any { return p.ShrinkCooldown }, base.ShrinkCooldown},
 		{"shrink-pr-ttl-days zero", "resizer.io/shrink-pr-ttl-days", "0",
 			func(p Policy) any { return p.ShrinkPRTTL }, base.ShrinkPRTTL},
+		{"grow-pr-ttl-days zero", "resizer.io/grow-pr-ttl-days", "0",
+			func(p Policy) any { return p.GrowPRTTL }, base.GrowPRTTL},
+		{"grow-pr-obsolete-hours zero", "resizer.io/grow-pr-obsolete-hours", "0",
+			func(p Policy) any { return p.GrowPRObsoleteAfter }, base.GrowPRObsoleteAfter},
 		{"cooldown-minutes negative", "resizer.io/cooldown-minutes", "-1",
 			func(p Policy) any { return p.GrowCooldown }, base.GrowCooldown},
+		{"max-grow-step zero", "resizer.io/max-grow-step", "0",
+			func(p Policy) any { return p.MaxGrowStep }, base.MaxGrowStep},
+		{"cpu-max zero", "resizer.io/cpu-max", "0",
+			func(p Policy) any { _, ok := p.MaxFor(corev1.ResourceRequestsCPU); return ok }, false},
+		{"cpu-max-increase negative", "resizer.io/cpu-max-increase", "-1",
+			func(p Policy) any { _, ok := p.MaxIncreaseFor(corev1.ResourceRequestsCPU); return ok }, false},
+		{"max-limit-request-ratio below 1", "resizer.io/max-limit-request-ratio", "0.5",
+			func(p Policy) any { return p.MaxLimitRequestRatioFor(corev1.ResourceLimitsCPU) }, 0.0},
+		{"scale-out-floor not a bool", "resizer.io/scale-out-floor", "sometimes",
+			func(p Policy) any { return p.ScaleOutFloor }, base.ScaleOutFloor},
+		{"cronjob-lookahead-hours negative", "resizer.io/cronjob-lookahead-hours", "-1",
+			func(p Policy) any { return p.CronLookahead }, base.CronLookahead},
+		{"cpu-step zero", "resizer.io/cpu-step", "0",
+			func(p Policy) any { _, ok := p.StepFor(corev1.ResourceRequestsCPU); return ok }, false},
 		{"requests.cpu-min not a quantity", "resizer.io/requests.cpu-min", "not-a-quantity",
 			func(p Policy) any { _, ok := p.MinFor(corev1.ResourceRequestsCPU); return ok }, false},
 	}
@@ -77,3 +77,19 @@ func Quantize(res corev1.ResourceName, milli int64, format resource.Format) reso
 
 	return *resource.NewMilliQuantity(milli, format)
 }
+
+// quantizeDown is Quantize rounding downwards, for a cap the result must not
+// exceed.
+func quantizeDown(res corev1.ResourceName, milli int64, format resource.Format) resource.Quantity {
+	if IsCountable(res) {
+		return *resource.NewQuantity(milli/1000, resource.DecimalSI)
+	}
+
+	measure := measureOf(res)
+	if strings.Contains(measure, "memory") || strings.Contains(measure, "storage") {
+		mi := milli / 1000 / bytesPerMi
+		return *resource.NewQuantity(mi*bytesPerMi, resource.BinarySI)
+	}
+
+	return *resource.NewMilliQuantity(milli, format)
+}
@@ -0,0 +1,76 @@
+package sizing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ParseSteps parses comma-separated resource=quantity pairs, such as
+// "cpu=500m,memory=1Gi", into rounding steps. Resources resolve the same way
+// as for the annotations: exact quota key first, then family.
+func ParseSteps(raw string) (map[corev1.ResourceName]resource.Quantity, error) {
+	steps := map[corev1.ResourceName]resource.Quantity{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		res, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(res) == "" {
+			return nil, fmt.Errorf("step %q is not of the form resource=quantity", pair)
+		}
+		step, err := resource.ParseQuantity(strings.TrimSpace(value))
+		if err != nil || step.Sign() <= 0 || overflowsMilliValue(step) {
+			return nil, fmt.Errorf("step %q must be a positive resource quantity", pair)
+		}
+		steps[corev1.ResourceName(strings.TrimSpace(res))] = step
+	}
+	return steps, nil
+}
+
+// StepFor returns the rounding step for a quota key, resolved the same way as
+// MinFor. A fractional step is ignored for a countable key, which only takes
+// whole numbers.
+func (p Policy) StepFor(res corev1.ResourceName) (resource.Quantity, bool) {
+	step, ok := quantityFor(p.Step, res)
+	if !ok || step.Sign() <= 0 || overflowsMilliValue(step) {
+		return resource.Quantity{}, false
+	}
+	if IsCountable(res) && step.MilliValue()%1000 != 0 {
+		return resource.Quantity{}, false
+	}
+	return step, true
+}
+
+// roundUp returns the smallest multiple of the step for res that is at least
+// qty, or qty itself without a step.
+func (p Policy) roundUp(res corev1.ResourceName, qty resource.Quantity) resource.Quantity {
+	return p.roundTo(res, qty, math.Ceil)
+}
+
+// roundDown returns the largest multiple of the step for res that is at most
+// qty, or qty itself without a step.
+func (p Policy) roundDown(res corev1.ResourceName, qty resource.Quantity) resource.Quantity {
+	return p.roundTo(res, qty, math.Floor)
+}
+
+func (p Policy) roundTo(res corev1.ResourceName, qty resource.Quantity, round func(float64) float64) resource.Quantity {
+	step, ok := p.StepFor(res)
+	if !ok || overflowsMilliValue(qty) {
+		return qty
+	}
+	stepMilli := step.MilliValue()
+	steps := round(float64(qty.MilliValue()) / float64(stepMilli))
+	if steps*float64(stepMilli) > maxMilliValue {
+		return qty
+	}
+	milli := int64(steps) * stepMilli
+	if milli%1000 == 0 {
+		return *resource.NewQuantity(milli/1000, step.Format)
+	}
+	return *resource.NewMilliQuantity(milli, step.Format)
+}
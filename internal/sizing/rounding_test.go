@@ -0,0 +1,103 @@
+package sizing
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseSteps(t *testing.T) {
+	steps, err := ParseSteps(" cpu=500m, memory=1Gi ,")
+	if err != nil {
+		t.Fatalf("ParseSteps: %v", err)
+	}
+	if got := steps[corev1.ResourceCPU]; got.String() != "500m" {
+		t.Errorf("cpu step = %s, want 500m", got.String())
+	}
+	if got := steps[corev1.ResourceMemory]; got.String() != "1Gi" {
+		t.Errorf("memory step = %s, want 1Gi", got.String())
+	}
+
+	for _, raw := range []string{"cpu", "=1", "cpu=0", "cpu=-1", "cpu=lots"} {
+		if _, err := ParseSteps(raw); err == nil {
+			t.Errorf("ParseSteps(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestDecide_GrowRoundsUpToTheStep(t *testing.T) {
+	// peak = used + deficit = 10.486, target = 13.1075 -> 13500m.
+	in := baseInput("10", "10", "10")
+	in.Deficits = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 486}
+	in.Policy.Step[corev1.ResourceCPU] = resource.MustParse("500m")
+
+	got := Decide(in)
+
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow", got.Direction)
+	}
+	if want := "13500m"; targetCPU(t, got) != want {
+		t.Fatalf("target = %s, want %s", targetCPU(t, got), want)
+	}
+}
+
+func TestDecide_GrowRoundsMemoryToWholeGi(t *testing.T) {
+	in := baseInput("10", "10", "10")
+	in.Hard = corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("8Gi")}
+	in.Used = corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("9Gi")}
+	in.Policy.Step[corev1.ResourceMemory] = resource.MustParse("1Gi")
+
+	got := Decide(in)
+
+	// 9Gi * 1.25 = 11.25Gi.
+	qty := got.Targets[corev1.ResourceRequestsMemory]
+	if want := "12Gi"; qty.String() != want {
+		t.Fatalf("target = %s, want %s", qty.String(), want)
+	}
+}
+
+func TestDecide_ShrinkRoundsDownAboveTheFloor(t *testing.T) {
+	cases := []struct {
+		name   string
+		used   string
+		step   string
+		target string
+	}{
+		// target 5, floor 3.75: down to 4.
+		{name: "down to the step", used: "3", step: "2", target: "4"},
+		// target 5, floor 4.375: 4 would undercut it, so 6.
+		{name: "never below the floor", used: "3500m", step: "2", target: "6"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := baseInput("16", tc.used, "4")
+			in.Policy.MaxShrinkStep = 0.9
+			in.Policy.Step[corev1.ResourceRequestsCPU] = resource.MustParse(tc.step)
+
+			got := Decide(in)
+
+			if got.Direction != DirectionShrink {
+				t.Fatalf("direction = %v, want shrink", got.Direction)
+			}
+			if targetCPU(t, got) != tc.target {
+				t.Fatalf("target = %s, want %s", targetCPU(t, got), tc.target)
+			}
+		})
+	}
+}
+
+func TestDecide_ShrinkRoundingBackToTheLimitIsQuiet(t *testing.T) {
+	// The step cap allows 12; the next step above it is the current limit.
+	in := baseInput("16", "3", "4")
+	in.Policy.Step[corev1.ResourceCPU] = resource.MustParse("8")
+
+	got := Decide(in)
+
+	if got.Direction != DirectionNone {
+		t.Fatalf("direction = %v, want none", got.Direction)
+	}
+	if len(got.ShrinkPreview) != 0 {
+		t.Fatalf("shrinkPreview = %v, want none", got.ShrinkPreview)
+	}
+}
@@ -0,0 +1,40 @@
+package sizing
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// WorkloadDemand returns what replicas pods of spec, each with claims, count
+// against the keys of a quota, in milli-units: their requests and limits, the
+// pods and, for claim templates, the claims and their storage.
+func WorkloadDemand(spec corev1.PodSpec, claims []corev1.PersistentVolumeClaim, replicas int64) map[corev1.ResourceName]int64 {
+	demand := PodRequests(spec)
+	for res, milli := range PVCRequests(claims) {
+		demand[res] += milli
+	}
+	demand[corev1.ResourcePods] = 1000
+	if len(claims) > 0 {
+		demand[corev1.ResourcePersistentVolumeClaims] = int64(len(claims)) * 1000
+	}
+	for res, milli := range demand {
+		demand[res] = milli * replicas
+	}
+	return demand
+}
+
+// demandFor looks up a workload demand for a quota key. The bare cpu and
+// memory keys stand for their requests, and count/pods for pods.
+func demandFor(demand map[corev1.ResourceName]int64, res corev1.ResourceName) (int64, bool) {
+	if milli, ok := demand[res]; ok {
+		return milli, true
+	}
+	switch res {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		milli, ok := demand["requests."+res]
+		return milli, ok
+	case "count/pods":
+		milli, ok := demand[corev1.ResourcePods]
+		return milli, ok
+	}
+	return 0, false
+}
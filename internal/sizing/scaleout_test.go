@@ -0,0 +1,119 @@
+package sizing
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestWorkloadDemand_MultipliesByReplicas(t *testing.T) {
+	spec := corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "app",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}}}
+	claims := []corev1.PersistentVolumeClaim{{Spec: corev1.PersistentVolumeClaimSpec{
+		Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse("10Gi"),
+		}},
+	}}}
+
+	got := WorkloadDemand(spec, claims, 4)
+
+	want := map[corev1.ResourceName]int64{
+		corev1.ResourceRequestsCPU:            1000,
+		corev1.ResourceLimitsMemory:           4 * 1024 * 1024 * 1024 * 1000,
+		corev1.ResourcePods:                   4000,
+		corev1.ResourcePersistentVolumeClaims: 4000,
+		corev1.ResourceRequestsStorage:        40 * 1024 * 1024 * 1024 * 1000,
+	}
+	for res, milli := range want {
+		if got[res] != milli {
+			t.Errorf("%s = %d, want %d", res, got[res], milli)
+		}
+	}
+}
+
+func TestDecide_ScaleOutFloorHoldsAShrinkBack(t *testing.T) {
+	// requests: used 4 -> 5, but 20 replicas of 500m need 10.
+	in := pairInput("16", "16", "4", "4")
+	in.Policy.MaxShrinkStep = 0.9
+	in.ScaleOut = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 10000}
+
+	got := Decide(in)
+
+	if got.Direction != DirectionShrink {
+		t.Fatalf("direction = %v, want shrink", got.Direction)
+	}
+	targets := targetsOf(got)
+	if targets["requests.cpu"] != "10" || targets["limits.cpu"] != "10" {
+		t.Fatalf("targets = %v, want requests.cpu at 10 and limits.cpu kept with it", targets)
+	}
+	if !strings.Contains(got.Reason, "scale-out floor") {
+		t.Errorf("reason = %q, want the scale-out floor named", got.Reason)
+	}
+}
+
+func TestDecide_ScaleOutFloorOptOut(t *testing.T) {
+	in := pairInput("16", "16", "4", "4")
+	in.Policy.MaxShrinkStep = 0.9
+	in.Policy.ScaleOutFloor = false
+	in.ScaleOut = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 10000}
+
+	got := Decide(in)
+
+	if targets := targetsOf(got); targets["requests.cpu"] != "5" {
+		t.Fatalf("targets = %v, want requests.cpu at 5 with the floor ignored", targets)
+	}
+}
+
+func TestDecide_ScaleOutFloorAboveHardDoesNotGrow(t *testing.T) {
+	in := pairInput("16", "16", "4", "4")
+	in.Policy.MaxShrinkStep = 0.9
+	in.ScaleOut = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 40000}
+
+	got := Decide(in)
+
+	if got.Direction == DirectionGrow {
+		t.Fatalf("direction = grow, want the scale-out demand never to grow a quota")
+	}
+	if _, ok := got.Targets[corev1.ResourceRequestsCPU]; ok {
+		t.Errorf("targets = %v, want requests.cpu left at hard", targetsOf(got))
+	}
+}
+
+func TestDecide_ProjectedDemandGrowsBeforeARejection(t *testing.T) {
+	// requests: 20 projected past the hard limit of 16 -> 25 with headroom.
+	in := pairInput("16", "32", "4", "4")
+	in.Projected = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 20000}
+
+	got := Decide(in)
+
+	if got.Direction != DirectionGrow {
+		t.Fatalf("direction = %v, want grow", got.Direction)
+	}
+	if targets := targetsOf(got); targets["requests.cpu"] != "25" {
+		t.Fatalf("targets = %v, want requests.cpu at 25", targets)
+	}
+	if !strings.Contains(got.Reason, "projected demand") {
+		t.Errorf("reason = %q, want the projected demand named", got.Reason)
+	}
+}
+
+func TestDecide_ProjectedDemandWithinTheLimitIsIgnored(t *testing.T) {
+	in := pairInput("16", "32", "4", "4")
+	in.Projected = map[corev1.ResourceName]int64{corev1.ResourceRequestsCPU: 15000}
+
+	got := Decide(in)
+
+	if got.Direction == DirectionGrow {
+		t.Fatalf("direction = grow, want a projection inside the limit to change nothing")
+	}
+	if strings.Contains(got.Reason, "projected demand") {
+		t.Errorf("reason = %q, want no projected demand", got.Reason)
+	}
+}
@@ -0,0 +1,195 @@
+package sizing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a five-field cron expression as a CronJob accepts it: minute,
+// hour, day of month, month and day of week, evaluated in one time zone.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// A day of month or day of week written as "*" matches with the other
+	// field alone; when both are restricted, either may match, as in cron.
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// cronField bounds one field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day of week accepts 7 for Sunday, as cron does; it is folded onto 0.
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronMacros are the predefined schedules a CronJob accepts.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a CronJob's spec.schedule in the time zone of its
+// spec.timeZone. An empty time zone means UTC; a CRON_TZ= or TZ= prefix on
+// the schedule takes precedence, as it does for older CronJobs.
+func ParseSchedule(spec, timeZone string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if prefix, rest, ok := strings.Cut(spec, " "); ok &&
+		(strings.HasPrefix(prefix, "CRON_TZ=") || strings.HasPrefix(prefix, "TZ=")) {
+		_, timeZone, _ = strings.Cut(prefix, "=")
+		spec = strings.TrimSpace(rest)
+	}
+	loc := time.UTC
+	if timeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timeZone); err != nil {
+			return Schedule{}, fmt.Errorf("unknown time zone %q: %w", timeZone, err)
+		}
+	}
+	if expanded, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("schedule %q: want 5 fields, got %d", spec, len(fields))
+	}
+	s := Schedule{loc: loc}
+	var err error
+	if s.minute, _, err = parseCronField(fields[0], minuteField); err != nil {
+		return Schedule{}, err
+	}
+	if s.hour, _, err = parseCronField(fields[1], hourField); err != nil {
+		return Schedule{}, err
+	}
+	if s.dom, s.domStar, err = parseCronField(fields[2], domField); err != nil {
+		return Schedule{}, err
+	}
+	if s.month, _, err = parseCronField(fields[3], monthField); err != nil {
+		return Schedule{}, err
+	}
+	if s.dow, s.dowStar, err = parseCronField(fields[4], dowField); err != nil {
+		return Schedule{}, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseCronField returns the values a field matches as a bit set, and
+// whether it was written as an unstepped "*" or "?".
+func parseCronField(raw string, f cronField) (uint64, bool, error) {
+	var bits uint64
+	star := false
+	for _, part := range strings.Split(raw, ",") {
+		rangePart, stepPart, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			v, err := strconv.Atoi(stepPart)
+			if err != nil || v <= 0 {
+				return 0, false, fmt.Errorf("%s %q: invalid step", f.name, part)
+			}
+			step = v
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			lo, hi = f.min, f.max
+			star = star || !stepped
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, false, err
+			}
+			if hi, err = f.value(to); err != nil {
+				return 0, false, err
+			}
+		default:
+			var err error
+			if lo, err = f.value(rangePart); err != nil {
+				return 0, false, err
+			}
+			// "5/15" runs from 5 to the end of the range.
+			hi = lo
+			if stepped {
+				hi = f.max
+			}
+		}
+		if lo > hi {
+			return 0, false, fmt.Errorf("%s %q: range runs backwards", f.name, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+// value parses a single number or name within the field's bounds.
+func (f cronField) value(raw string) (int, error) {
+	if v, ok := f.names[strings.ToLower(raw)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %q: want %d-%d", f.name, raw, f.min, f.max)
+	}
+	return v, nil
+}
+
+// matches reports whether the schedule fires in the minute starting at t.
+func (s Schedule) matches(t time.Time) bool {
+	t = t.In(s.loc)
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 ||
+		s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Runs returns the first run of the schedule in every hour after from
+// and up to to, in the schedule's time zone. A job that fires every few
+// minutes counts once per hour: how long its runs last is not known, so
+// their overlap is not guessed at.
+func (s Schedule) Runs(from, to time.Time) []time.Time {
+	var runs []time.Time
+	lastSlot := time.Time{}
+	for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(to); t = t.Add(time.Minute) {
+		if !s.matches(t) {
+			continue
+		}
+		if slot := t.Truncate(time.Hour); !slot.Equal(lastSlot) {
+			runs = append(runs, t.In(s.loc))
+			lastSlot = slot
+		}
+	}
+	return runs
+}
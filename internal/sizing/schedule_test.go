@@ -0,0 +1,71 @@
+package sizing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Runs(t *testing.T) {
+	// testNow is Saturday, 2026-08-08 12:00 UTC.
+	cases := []struct {
+		name     string
+		schedule string
+		timeZone string
+		within   time.Duration
+		want     []string
+	}{
+		{"nightly in the CronJob's time zone", "0 2 * * *", "Europe/Berlin", 24 * time.Hour,
+			[]string{"2026-08-09T00:00:00Z"}},
+		{"CRON_TZ prefix", "CRON_TZ=America/New_York 0 2 * * *", "", 24 * time.Hour,
+			[]string{"2026-08-09T06:00:00Z"}},
+		{"UTC without a time zone", "30 2 * * *", "", 24 * time.Hour,
+			[]string{"2026-08-09T02:30:00Z"}},
+		{"every 15 minutes counts once per hour", "*/15 * * * *", "", 2 * time.Hour,
+			[]string{"2026-08-08T12:15:00Z", "2026-08-08T13:00:00Z", "2026-08-08T14:00:00Z"}},
+		{"day of month or day of week", "0 0 13 * fri", "", 7 * 24 * time.Hour,
+			[]string{"2026-08-13T00:00:00Z", "2026-08-14T00:00:00Z"}},
+		{"day of week 7 is Sunday", "0 0 * * 7", "", 48 * time.Hour,
+			[]string{"2026-08-09T00:00:00Z"}},
+		{"macro", "@daily", "", 24 * time.Hour,
+			[]string{"2026-08-09T00:00:00Z"}},
+		{"ranges, lists and steps", "0 9-17/4,20 * * mon-fri", "", 72 * time.Hour,
+			[]string{"2026-08-10T09:00:00Z", "2026-08-10T13:00:00Z", "2026-08-10T17:00:00Z",
+				"2026-08-10T20:00:00Z", "2026-08-11T09:00:00Z"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseSchedule(tc.schedule, tc.timeZone)
+			if err != nil {
+				t.Fatalf("ParseSchedule: %v", err)
+			}
+			var got []string
+			for _, run := range s.Runs(testNow, testNow.Add(tc.within)) {
+				got = append(got, run.UTC().Format(time.RFC3339))
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("runs = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("runs = %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Rejects(t *testing.T) {
+	for _, tc := range []struct{ schedule, timeZone string }{
+		{"0 2 * *", ""},
+		{"60 2 * * *", ""},
+		{"0 2 * * *", "Mars/Olympus_Mons"},
+		{"0 5-2 * * *", ""},
+		{"*/0 * * * *", ""},
+		{"@every 1h", ""},
+	} {
+		if _, err := ParseSchedule(tc.schedule, tc.timeZone); err == nil {
+			t.Errorf("ParseSchedule(%q, %q) accepted, want an error", tc.schedule, tc.timeZone)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package sizing
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Seasonality is the cycle a namespace's demand is expected to repeat in.
+type Seasonality string
+
+const (
+	// SeasonalityOff sizes from the observation window alone.
+	SeasonalityOff Seasonality = ""
+	// SeasonalityWeekly covers weekend batches.
+	SeasonalityWeekly Seasonality = "weekly"
+	// SeasonalityMonthly covers month-end closes, and weekly cycles with them.
+	SeasonalityMonthly Seasonality = "monthly"
+)
+
+const (
+	// seasonalWeeks and seasonalMonths are how many completed periods the
+	// coarse history keeps, beyond the one in progress.
+	seasonalWeeks  = 4
+	seasonalMonths = 3
+
+	monthLayout = "2006-01"
+)
+
+// PeriodBucket holds the per-resource maximum of status.used observed in one
+// week or month, and on how many days of it samples were taken.
+type PeriodBucket struct {
+	Start string            `json:"s"`
+	Days  int               `json:"n"`
+	Peaks map[string]string `json:"p"`
+}
+
+// weekStart returns the Monday starting the week of t, as a date.
+func weekStart(t time.Time) string {
+	day := t.UTC().Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7).Format(dateLayout)
+}
+
+// observePeriod folds a sample into the period starting at start, appending
+// it when it is new and keeping the last keep completed periods before it.
+// newDay reports whether the sample is the first of its day.
+func observePeriod(periods []PeriodBucket, start string, keep int, newDay bool, usedList corev1.ResourceList) []PeriodBucket {
+	idx := -1
+	for i, period := range periods {
+		if period.Start == start {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		periods = append(periods, PeriodBucket{Start: start, Peaks: map[string]string{}})
+		if len(periods) > keep+1 {
+			periods = periods[len(periods)-keep-1:]
+		}
+		idx = len(periods) - 1
+		newDay = true
+	}
+	period := &periods[idx]
+	if period.Peaks == nil {
+		period.Peaks = map[string]string{}
+	}
+	if newDay {
+		period.Days++
+	}
+	for res, qty := range usedList {
+		previous, err := resource.ParseQuantity(period.Peaks[string(res)])
+		if _, ok := period.Peaks[string(res)]; !ok || err != nil || qty.Cmp(previous) > 0 {
+			period.Peaks[string(res)] = qty.String()
+		}
+	}
+	return periods
+}
+
+// observeSeasons folds a sample into the weekly and monthly history.
+func (w *Window) observeSeasons(stamp time.Time, newDay bool, usedList corev1.ResourceList) {
+	w.Weeks = observePeriod(w.Weeks, weekStart(stamp), seasonalWeeks, newDay, usedList)
+	w.Months = observePeriod(w.Months, stamp.Format(monthLayout), seasonalMonths, newDay, usedList)
+}
+
+// completedPeriods returns the periods of the season that ended before now and
+// were sampled on every one of their days.
+func (w Window) completedPeriods(season Seasonality, now time.Time) []PeriodBucket {
+	var completed []PeriodBucket
+	switch season {
+	case SeasonalityWeekly:
+		current := weekStart(now)
+		for _, period := range w.Weeks {
+			if period.Start < current && period.Days >= 7 {
+				completed = append(completed, period)
+			}
+		}
+	case SeasonalityMonthly:
+		current := now.UTC().Format(monthLayout)
+		for _, period := range w.Months {
+			start, err := time.Parse(monthLayout, period.Start)
+			if err != nil {
+				continue
+			}
+			days := start.AddDate(0, 1, 0).Sub(start).Hours() / 24
+			if period.Start < current && float64(period.Days) >= days {
+				completed = append(completed, period)
+			}
+		}
+	}
+	return completed
+}
+
+// SeasonObserved reports whether at least one full cycle of the season has been
+// observed. Without one, the seasonal floor cannot know what it missed.
+func (w Window) SeasonObserved(season Seasonality, now time.Time) bool {
+	return season == SeasonalityOff || len(w.completedPeriods(season, now)) > 0
+}
+
+// SeasonalPeak returns the peak of res that recurred in every completed cycle
+// of the season the history keeps: the lowest of their maxima, in
+// milli-units. A spike seen in one cycle only is not seasonal and does not
+// count; one seen in every cycle is, however short the window.
+func (w Window) SeasonalPeak(res corev1.ResourceName, season Seasonality, now time.Time) (int64, bool) {
+	var (
+		lowest int64
+		found  bool
+	)
+	for _, period := range w.completedPeriods(season, now) {
+		raw, ok := period.Peaks[string(res)]
+		if !ok {
+			continue
+		}
+		qty, err := resource.ParseQuantity(raw)
+		if err != nil || overflowsMilliValue(qty) {
+			continue
+		}
+		if milli := qty.MilliValue(); !found || milli < lowest {
+			lowest = milli
+			found = true
+		}
+	}
+	return lowest, found
+}
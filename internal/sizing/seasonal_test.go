@@ -0,0 +1,120 @@
+package sizing
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// monthEndWindow samples every 5 minutes from start until now: 1 CPU, except
+// on the last day of every month, when it is 8 CPU.
+func monthEndWindow(start, now time.Time, windowDays int) Window {
+	w := Window{Version: WindowVersion}
+	for t := start; t.Before(now); t = t.Add(5 * time.Minute) {
+		cpu := "1"
+		if t.AddDate(0, 0, 1).Month() != t.Month() {
+			cpu = "8"
+		}
+		w.Observe(t, testUID, used(cpu), windowDays)
+	}
+	return w
+}
+
+func TestWindow_KeepsCoarseHistory(t *testing.T) {
+	now := time.Date(2026, 8, 20, 12, 0, 0, 0, time.UTC)
+	w := monthEndWindow(time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC), now, 14)
+
+	if len(w.Months) != seasonalMonths+1 {
+		t.Fatalf("months = %d, want %d", len(w.Months), seasonalMonths+1)
+	}
+	if w.Months[0].Start != "2026-05" || w.Months[0].Days != 31 {
+		t.Errorf("oldest month = %+v, want all 31 days of May", w.Months[0])
+	}
+	if len(w.Weeks) != seasonalWeeks+1 {
+		t.Fatalf("weeks = %d, want %d", len(w.Weeks), seasonalWeeks+1)
+	}
+	if w.Weeks[len(w.Weeks)-1].Start != "2026-08-17" {
+		t.Errorf("current week starts %s, want Monday 2026-08-17", w.Weeks[len(w.Weeks)-1].Start)
+	}
+}
+
+func TestWindow_SeasonalPeak(t *testing.T) {
+	now := time.Date(2026, 8, 20, 12, 0, 0, 0, time.UTC)
+	w := monthEndWindow(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), now, 14)
+
+	if peak, _ := w.Peak(corev1.ResourceRequestsCPU, now, 14); peak != 1000 {
+		t.Fatalf("window peak = %d, want 1000: the window misses the month end", peak)
+	}
+	peak, ok := w.SeasonalPeak(corev1.ResourceRequestsCPU, SeasonalityMonthly, now)
+	if !ok || peak != 8000 {
+		t.Errorf("monthly peak = %d/%v, want 8000: it recurred every month", peak, ok)
+	}
+	peak, ok = w.SeasonalPeak(corev1.ResourceRequestsCPU, SeasonalityWeekly, now)
+	if !ok || peak != 1000 {
+		t.Errorf("weekly peak = %d/%v, want 1000: most weeks have no month end", peak, ok)
+	}
+	if _, ok := w.SeasonalPeak(corev1.ResourceRequestsCPU, SeasonalityOff, now); ok {
+		t.Error("a namespace without seasonality reported a seasonal peak")
+	}
+}
+
+func TestWindow_SeasonObserved(t *testing.T) {
+	now := time.Date(2026, 8, 20, 12, 0, 0, 0, time.UTC)
+	// Sampling started after the 1st, so July is the first complete month.
+	w := monthEndWindow(time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC), now, 14)
+	if !w.SeasonObserved(SeasonalityMonthly, now) {
+		t.Error("July was observed in full")
+	}
+	if w.SeasonObserved(SeasonalityMonthly, time.Date(2026, 7, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Error("a month in progress counts as a cycle")
+	}
+
+	partial := monthEndWindow(time.Date(2026, 7, 3, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 31, 12, 0, 0, 0, time.UTC), 14)
+	if partial.SeasonObserved(SeasonalityMonthly, now) {
+		t.Error("July, sampled from the 3rd, counts as a full cycle")
+	}
+	if !partial.SeasonObserved(SeasonalityOff, now) {
+		t.Error("no seasonality needs no cycle")
+	}
+}
+
+func TestDecide_SeasonalFloorAndGate(t *testing.T) {
+	now := time.Date(2026, 8, 20, 12, 0, 0, 0, time.UTC)
+	in := baseInput("20", "1", "1")
+	in.Now = now
+	in.Window = monthEndWindow(time.Date(2026, 6, 25, 0, 0, 0, 0, time.UTC), now, in.Policy.WindowDays)
+	in.Policy.MaxShrinkStep = 0.95
+
+	if got := Decide(in); targetCPU(t, got) != "1250m" {
+		t.Fatalf("target without seasonality = %s, want 1250m", targetCPU(t, got))
+	}
+
+	in.Policy.Seasonality = SeasonalityMonthly
+	got := Decide(in)
+	if got.Direction != DirectionShrink {
+		t.Fatalf("direction = %v (blocked by %v), want shrink", got.Direction, got.BlockedBy)
+	}
+	if want := "10"; targetCPU(t, got) != want {
+		t.Fatalf("target = %s, want %s (month-end peak of 8 plus headroom)", targetCPU(t, got), want)
+	}
+
+	in.Window = monthEndWindow(time.Date(2026, 7, 25, 0, 0, 0, 0, time.UTC), now, in.Policy.WindowDays)
+	got = Decide(in)
+	if !slices.Contains(got.BlockedBy, GateSeasonal) {
+		t.Fatalf("blocked by %v, want %s before a full month was observed", got.BlockedBy, GateSeasonal)
+	}
+}
+
+func TestDecide_SeasonalFloorNeverGrows(t *testing.T) {
+	now := time.Date(2026, 8, 20, 12, 0, 0, 0, time.UTC)
+	in := baseInput("4", "1", "1")
+	in.Now = now
+	in.Window = monthEndWindow(time.Date(2026, 6, 25, 0, 0, 0, 0, time.UTC), now, in.Policy.WindowDays)
+	in.Policy.Seasonality = SeasonalityMonthly
+
+	if got := Decide(in); got.Direction != DirectionNone {
+		t.Fatalf("direction = %v, want none: the seasonal floor holds at the limit", got.Direction)
+	}
+}
@@ -0,0 +1,104 @@
+package sizing
+
+import (
+	"math"
+	"sort"
+)
+
+// sketchGrowth is the ratio between the upper bounds of two neighbouring
+// sketch buckets. A quantile read from a sketch overstates the true sample
+// by at most this factor minus one, never understates it.
+const sketchGrowth = 1.02
+
+// sketchZero is the bucket holding samples of zero, which have no logarithm.
+const sketchZero = -1
+
+// maxSketchBuckets bounds the buckets of one sketch. Usage moving by a factor
+// of 3.5 within a day fills that many; past it, the lowest buckets are folded
+// into the one above them.
+const maxSketchBuckets = 64
+
+// Sketch counts the samples of one resource on one day in logarithmic
+// buckets: bucket i holds the milli-values in (sketchGrowth^(i-1),
+// sketchGrowth^i]. A day of usage moving within a few tens of percent fills a
+// handful of buckets, whatever the sampling rate, and maxSketchBuckets bounds
+// the rest, which keeps the window small enough for a Lease annotation.
+type Sketch map[int]int
+
+// sketchIndex returns the bucket of a milli-value.
+func sketchIndex(milli int64) int {
+	if milli <= 0 {
+		return sketchZero
+	}
+	return int(math.Ceil(math.Log(float64(milli)) / math.Log(sketchGrowth)))
+}
+
+// sketchUpperBound returns the largest milli-value bucket i may hold.
+func sketchUpperBound(i int) int64 {
+	if i == sketchZero {
+		return 0
+	}
+	bound := math.Pow(sketchGrowth, float64(i))
+	if bound >= maxMilliValue*1000 {
+		return math.MaxInt64
+	}
+	return int64(math.Ceil(bound))
+}
+
+// Add counts n samples of milli.
+func (s Sketch) Add(milli int64, n int) {
+	s[sketchIndex(milli)] += n
+	if len(s) > maxSketchBuckets {
+		s.collapse()
+	}
+}
+
+// collapse folds the lowest buckets into the lowest one kept until s has
+// maxSketchBuckets. Their samples then read higher than they were, which only
+// the low quantiles see and which never understates one.
+func (s Sketch) collapse() {
+	indexes := make([]int, 0, len(s))
+	for i := range s {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	excess := len(indexes) - maxSketchBuckets
+	into := indexes[excess]
+	for _, i := range indexes[:excess] {
+		s[into] += s[i]
+		delete(s, i)
+	}
+}
+
+// Count returns the number of samples in s.
+func (s Sketch) Count() int {
+	total := 0
+	for _, n := range s {
+		total += n
+	}
+	return total
+}
+
+// Quantile returns the upper bound of the bucket holding the q-quantile of
+// the samples, 0 < q < 1. It reports ok=false for an empty sketch.
+func (s Sketch) Quantile(q float64) (int64, bool) {
+	total := s.Count()
+	if total == 0 {
+		return 0, false
+	}
+	indexes := make([]int, 0, len(s))
+	for i := range s {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	rank := int(math.Ceil(q * float64(total)))
+	seen := 0
+	for _, i := range indexes {
+		seen += s[i]
+		if seen >= rank {
+			return sketchUpperBound(i), true
+		}
+	}
+	return sketchUpperBound(indexes[len(indexes)-1]), true
+}
@@ -0,0 +1,66 @@
+package sizing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSketch_IndexAndUpperBoundRoundTrip(t *testing.T) {
+	for _, milli := range []int64{1, 2, 999, 1000, 1001, 4000, 16_000, 68_719_476_736_000, maxMilliValue} {
+		i := sketchIndex(milli)
+		bound := sketchUpperBound(i)
+		if bound < milli {
+			t.Errorf("sketchUpperBound(sketchIndex(%d)) = %d, understates the sample", milli, bound)
+		}
+		if float64(bound) > float64(milli)*sketchGrowth+1 {
+			t.Errorf("sketchUpperBound(sketchIndex(%d)) = %d, overstates it by more than the growth", milli, bound)
+		}
+		if next := sketchIndex(milli + 1); next < i {
+			t.Errorf("sketchIndex(%d) = %d, below the bucket of %d", milli+1, next, milli)
+		}
+	}
+
+	if i := sketchIndex(0); i != sketchZero || sketchUpperBound(i) != 0 {
+		t.Errorf("zero goes to bucket %d bounded by %d, want the zero bucket bounded by 0", i, sketchUpperBound(i))
+	}
+	if i := sketchIndex(-5); i != sketchZero {
+		t.Errorf("sketchIndex(-5) = %d, want the zero bucket", i)
+	}
+	if bound := sketchUpperBound(sketchIndex(math.MaxInt64)); bound != math.MaxInt64 {
+		t.Errorf("bound of the largest value = %d, want it saturated", bound)
+	}
+}
+
+func TestSketch_QuantileOfFewSamples(t *testing.T) {
+	if _, ok := (Sketch{}).Quantile(0.95); ok {
+		t.Error("an empty sketch reported a quantile")
+	}
+
+	s := Sketch{}
+	s.Add(4000, 1)
+	for _, q := range []float64{0.01, 0.5, 0.99} {
+		got, ok := s.Quantile(q)
+		if !ok || got < 4000 || got > 4080 {
+			t.Errorf("Quantile(%v) of one sample = %d/%v, want 4000 within 2 %%", q, got, ok)
+		}
+	}
+}
+
+func TestSketch_AddBoundsTheBuckets(t *testing.T) {
+	s := Sketch{}
+	// Usage from 1m to 10 cores fills several hundred buckets uncapped.
+	for milli := int64(1); milli <= 10_000; milli += 7 {
+		s.Add(milli, 1)
+	}
+	s.Add(10_000, 1)
+
+	if len(s) != maxSketchBuckets {
+		t.Fatalf("buckets = %d, want %d", len(s), maxSketchBuckets)
+	}
+	if got, _ := s.Quantile(0.999); got < 10_000 || got > 10_200 {
+		t.Errorf("p99.9 = %d, want 10000 within 2 %%: folding must leave the top alone", got)
+	}
+	if got, _ := s.Quantile(0.01); got < 100 {
+		t.Errorf("p1 = %d, want it raised by the folded buckets rather than understated", got)
+	}
+}
@@ -2,6 +2,7 @@ package sizing
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -12,8 +13,13 @@ import (
 // in decide.go.
 
 // WindowVersion is the schema version of the persisted observation window.
-// A window carrying any other value is discarded and rebuilt from scratch.
-const WindowVersion = 1
+// Version 2 added the per-day sample sketches. A version 1 window is migrated
+// on decode; a window carrying any other value is discarded and rebuilt from
+// scratch.
+const WindowVersion = 2
+
+// windowVersionPeaksOnly is the schema that kept only the daily peaks.
+const windowVersionPeaksOnly = 1
 
 const (
 	dateLayout = "2006-01-02"
@@ -28,15 +34,45 @@ const (
 	coverageLastBy  = "23:30"
 )
 
+// The window is persisted in one Lease annotation, and the annotations of an
+// object share 256 KiB. maxWindowDays bounds its days and maxWindowBytes its
+// encoding; the caps on sketches and periods bound each day.
+const (
+	maxWindowDays  = 90
+	maxWindowBytes = 128 << 10
+)
+
 // DayBucket holds the per-resource maximum of status.used observed on one day,
 // plus the metadata needed to judge whether that day was observed continuously.
+// Sketches count the day's samples per resource for the percentile estimators;
+// a day written before they existed has none.
 type DayBucket struct {
-	Date   string            `json:"d"`
-	N      int               `json:"n"`
-	First  string            `json:"first"`
-	Last   string            `json:"last"`
-	MaxGap string            `json:"maxGap"`
-	Peaks  map[string]string `json:"p"`
+	Date     string            `json:"d"`
+	N        int               `json:"n"`
+	First    string            `json:"first"`
+	Last     string            `json:"last"`
+	MaxGap   string            `json:"maxGap"`
+	Peaks    map[string]string `json:"p"`
+	Sketches map[string]Sketch `json:"s,omitempty"`
+	// Excluded is the highest usage of workloads ignored in sizing seen on
+	// the day; Peaks, Sketches and the seasonal history leave it out.
+	Excluded map[string]string `json:"x,omitempty"`
+	// Drivers names the workload holding the largest share of each peak
+	// when it was sampled.
+	Drivers map[string]string `json:"a,omitempty"`
+	// Hourly holds the peak of each resource per UTC hour of the day, in
+	// milli-units, with -1 for an hour not sampled. The scheduled-run
+	// projection reads what else runs at the hour of a CronJob.
+	Hourly map[string][]int64 `json:"h,omitempty"`
+}
+
+// Sample is one observation of a quota.
+type Sample struct {
+	Used corev1.ResourceList
+	// Excluded is the part of Used held by workloads ignored in sizing.
+	Excluded corev1.ResourceList
+	// Drivers names the workload holding the most of each resource.
+	Drivers map[corev1.ResourceName]string
 }
 
 // Window is the rolling observation window persisted on the state Lease.
@@ -46,11 +82,17 @@ type Window struct {
 	LastSampleAt string      `json:"ls"`
 	LastWriteAt  string      `json:"lw"`
 	Days         []DayBucket `json:"days"`
+	// Weeks and Months are the coarse history the seasonal floor reads. They
+	// reach back further than Days, a few periods each.
+	Weeks  []PeriodBucket `json:"wk,omitempty"`
+	Months []PeriodBucket `json:"mo,omitempty"`
 }
 
-// DecodeWindow parses a persisted window. Anything unparseable or written by a
-// different schema version yields an empty window, which keeps the shrink path
-// blocked until a full window has been rebuilt.
+// DecodeWindow parses a persisted window. Anything unparseable or written by an
+// unknown schema version yields an empty window, which keeps the shrink path
+// blocked until a full window has been rebuilt. A version 1 window keeps its
+// days: they carry no sketches, which Percentile reads as every sample of the
+// day at its peak, so the migration never lowers a target.
 func DecodeWindow(raw string) Window {
 	if raw == "" {
 		return Window{Version: WindowVersion}
@@ -59,6 +101,9 @@ func DecodeWindow(raw string) Window {
 	if err := json.Unmarshal([]byte(raw), &w); err != nil {
 		return Window{Version: WindowVersion}
 	}
+	if w.Version == windowVersionPeaksOnly {
+		w.Version = WindowVersion
+	}
 	if w.Version != WindowVersion {
 		return Window{Version: WindowVersion}
 	}
@@ -73,9 +118,22 @@ func DecodeWindow(raw string) Window {
 		// rebuild, rather than risk reading the wrong one.
 		return Window{Version: WindowVersion}
 	}
+	// Observe only trims the seasonal history when a period starts; one
+	// stored longer than it keeps is trimmed here so it cannot grow the
+	// annotation.
+	w.Weeks = lastPeriods(w.Weeks, seasonalWeeks+1)
+	w.Months = lastPeriods(w.Months, seasonalMonths+1)
 	return w
 }
 
+// lastPeriods returns the last n periods.
+func lastPeriods(periods []PeriodBucket, n int) []PeriodBucket {
+	if len(periods) > n {
+		return periods[len(periods)-n:]
+	}
+	return periods
+}
+
 // hasDuplicateDates reports whether any date appears more than once, which
 // should never happen on a window this package wrote itself.
 func hasDuplicateDates(days []DayBucket) bool {
@@ -89,24 +147,64 @@ func hasDuplicateDates(days []DayBucket) bool {
 	return false
 }
 
-// EncodeWindow serialises a window for storage in a Lease annotation.
+// EncodeWindow serialises a window for storage in a Lease annotation. A window
+// encoding to more than maxWindowBytes, which takes a quota with many
+// resources, sheds the sketches, hourly peaks and drivers of its oldest days
+// first. A day without a sketch is read at its peak, so what it sheds can only
+// raise a target. A window still too large after that is an error.
 func EncodeWindow(w Window) (string, error) {
 	w.Version = WindowVersion
 	raw, err := json.Marshal(w)
 	if err != nil {
 		return "", err
 	}
+	if excess := len(raw) - maxWindowBytes; excess > 0 {
+		// The caller's days are left as they were.
+		w.Days = append([]DayBucket(nil), w.Days...)
+		for i := 0; excess > 0 && i < len(w.Days); i++ {
+			before, err := json.Marshal(w.Days[i])
+			if err != nil {
+				return "", err
+			}
+			w.Days[i].Sketches = nil
+			w.Days[i].Hourly = nil
+			w.Days[i].Drivers = nil
+			after, err := json.Marshal(w.Days[i])
+			if err != nil {
+				return "", err
+			}
+			excess -= len(before) - len(after)
+		}
+		if raw, err = json.Marshal(w); err != nil {
+			return "", err
+		}
+	}
+	if len(raw) > maxWindowBytes {
+		return "", fmt.Errorf("observation window of %d bytes exceeds %d", len(raw), maxWindowBytes)
+	}
 	return string(raw), nil
 }
 
 // Observe folds one sample of status.used into the window. It returns true when
 // the window changed in a way worth persisting: a new day, a pruned bucket, or
-// a peak that rose.
+// a peak that rose. A sample that only lands in a sketch is not worth a write
+// of its own; the observer's heartbeat persists it.
 func (w *Window) Observe(
 	now time.Time,
 	uid string,
 	usedList corev1.ResourceList,
 	windowDays int,
+) bool {
+	return w.ObserveSample(now, uid, Sample{Used: usedList}, windowDays)
+}
+
+// ObserveSample is Observe for a sample that may exclude usage and attribute
+// it. The window records usage net of the excluded part.
+func (w *Window) ObserveSample(
+	now time.Time,
+	uid string,
+	sample Sample,
+	windowDays int,
 ) bool {
 	if w.UID != "" && w.UID != uid {
 		// The quota was deleted and recreated under the same name. The old
@@ -121,7 +219,8 @@ func (w *Window) Observe(
 	stamp := now.UTC()
 	today := stamp.Format(dateLayout)
 	idx := w.indexOf(today)
-	if idx < 0 {
+	newDay := idx < 0
+	if newDay {
 		w.Days = append(w.Days, DayBucket{
 			Date:  today,
 			First: stamp.Format(timeLayout),
@@ -131,6 +230,8 @@ func (w *Window) Observe(
 		changed = true
 	}
 	bucket := &w.Days[idx]
+	usedList := bucket.exclude(sample)
+	w.observeSeasons(stamp, newDay, usedList)
 	if bucket.Peaks == nil {
 		bucket.Peaks = map[string]string{}
 	}
@@ -150,17 +251,30 @@ func (w *Window) Observe(
 	bucket.Last = stamp.Format(timeLayout)
 	bucket.N++
 
+	if bucket.Sketches == nil {
+		bucket.Sketches = map[string]Sketch{}
+	}
 	for res, qty := range usedList {
 		key := string(res)
+		if !overflowsMilliValue(qty) {
+			if bucket.Sketches[key] == nil {
+				bucket.Sketches[key] = Sketch{}
+			}
+			bucket.Sketches[key].Add(qty.MilliValue(), 1)
+			bucket.observeHour(key, stamp.Hour(), qty.MilliValue())
+		}
+
 		previous, ok := bucket.Peaks[key]
 		if !ok {
 			bucket.Peaks[key] = qty.String()
+			bucket.attribute(res, sample.Drivers[res])
 			changed = true
 			continue
 		}
 		parsed, err := resource.ParseQuantity(previous)
 		if err != nil || qty.Cmp(parsed) > 0 {
 			bucket.Peaks[key] = qty.String()
+			bucket.attribute(res, sample.Drivers[res])
 			changed = true
 		}
 	}
@@ -168,18 +282,105 @@ func (w *Window) Observe(
 	return changed
 }
 
+// observeHour raises the peak of key in the given hour. Like a sketch, it is
+// persisted by the observer's heartbeat rather than a write of its own.
+func (b *DayBucket) observeHour(key string, hour int, milli int64) {
+	if b.Hourly == nil {
+		b.Hourly = map[string][]int64{}
+	}
+	hours := b.Hourly[key]
+	if len(hours) != 24 {
+		hours = make([]int64, 24)
+		for i := range hours {
+			hours[i] = -1
+		}
+		b.Hourly[key] = hours
+	}
+	hours[hour] = max(hours[hour], milli)
+}
+
+// HourlyPeak returns the highest usage of res seen in the given UTC hour on
+// any day of the window, in milli-units, and false when that hour was never
+// sampled.
+func (w Window) HourlyPeak(res corev1.ResourceName, hour int) (int64, bool) {
+	peak, ok := int64(0), false
+	for _, bucket := range w.Days {
+		hours := bucket.Hourly[string(res)]
+		if len(hours) != 24 || hours[hour] < 0 {
+			continue
+		}
+		peak, ok = max(peak, hours[hour]), true
+	}
+	return peak, ok
+}
+
+// exclude returns the usage of sample net of its excluded part, and records
+// the highest excluded usage of the day.
+func (b *DayBucket) exclude(sample Sample) corev1.ResourceList {
+	if len(sample.Excluded) == 0 {
+		return sample.Used
+	}
+	net := make(corev1.ResourceList, len(sample.Used))
+	for res, qty := range sample.Used {
+		excluded, ok := sample.Excluded[res]
+		if !ok || excluded.Sign() <= 0 || overflowsMilliValue(excluded) {
+			net[res] = qty
+			continue
+		}
+		if b.Excluded == nil {
+			b.Excluded = map[string]string{}
+		}
+		previous, err := resource.ParseQuantity(b.Excluded[string(res)])
+		if err != nil || excluded.Cmp(previous) > 0 {
+			b.Excluded[string(res)] = excluded.String()
+		}
+		remaining := qty.DeepCopy()
+		remaining.Sub(excluded)
+		if remaining.Sign() < 0 {
+			remaining = *resource.NewQuantity(0, qty.Format)
+		}
+		net[res] = remaining
+	}
+	return net
+}
+
+// attribute records which workload drove the day's peak of res.
+func (b *DayBucket) attribute(res corev1.ResourceName, driver string) {
+	if driver == "" {
+		delete(b.Drivers, string(res))
+		return
+	}
+	if b.Drivers == nil {
+		b.Drivers = map[string]string{}
+	}
+	b.Drivers[string(res)] = driver
+}
+
 // Peak returns the highest value observed for a resource across the completed
 // days of the window, in milli-units.
 func (w Window) Peak(res corev1.ResourceName, now time.Time, windowDays int) (int64, bool) {
+	peak, _, ok := w.peakExcept(res, now, windowDays, nil)
+	return peak, ok
+}
+
+// peakExcept is Peak over the days not in skip. It also returns the day of the
+// peak.
+func (w Window) peakExcept(
+	res corev1.ResourceName,
+	now time.Time,
+	windowDays int,
+	skip map[string]bool,
+) (int64, DayBucket, bool) {
 	var (
-		best  int64
-		found bool
+		best    int64
+		bestDay DayBucket
+		found   bool
 	)
 	today := now.UTC().Format(dateLayout)
 	oldest := now.UTC().AddDate(0, 0, -windowDays).Format(dateLayout)
 
 	for _, bucket := range w.Days {
-		if bucket.Date >= today || bucket.Date < oldest {
+		if bucket.Date >= today || bucket.Date < oldest || skip[bucket.Date] {
 			continue
 		}
 		raw, ok := bucket.Peaks[string(res)]
@@ -201,10 +402,58 @@ func (w Window) Peak(res corev1.ResourceName, now time.Time, windowDays int) (in
 		}
 		if milli := qty.MilliValue(); !found || milli > best {
 			best = milli
+			bestDay = bucket
 			found = true
 		}
 	}
-	return best, found
+	return best, bestDay, found
+}
+
+// Percentile returns the p-th percentile, 0 < p < 100, of the samples observed
+// for a resource across the completed days of the window, in milli-units. It
+// never exceeds Peak. Samples a day counted in N but not in its sketch — all of
+// them on a day migrated from version 1, the ones before the upgrade on the
+// day it happened — are counted at the day's peak, which can only raise the
+// result.
+func (w Window) Percentile(res corev1.ResourceName, now time.Time, windowDays int, p float64) (int64, bool) {
+	peak, ok := w.Peak(res, now, windowDays)
+	if !ok {
+		return 0, false
+	}
+	merged := Sketch{}
+	today := now.UTC().Format(dateLayout)
+	oldest := now.UTC().AddDate(0, 0, -windowDays).Format(dateLayout)
+
+	for _, bucket := range w.Days {
+		if bucket.Date >= today || bucket.Date < oldest {
+			continue
+		}
+		raw, ok := bucket.Peaks[string(res)]
+		if !ok {
+			continue
+		}
+		qty, err := resource.ParseQuantity(raw)
+		if err != nil || overflowsMilliValue(qty) {
+			// Skipped by Peak as well; see there.
+			continue
+		}
+		counted := 0
+		for i, n := range bucket.Sketches[string(res)] {
+			if n > 0 {
+				merged[i] += n
+				counted += n
+			}
+		}
+		if missing := max(bucket.N-counted, 0); missing > 0 || counted == 0 {
+			merged.Add(qty.MilliValue(), max(missing, 1))
+		}
+	}
+
+	estimate, ok := merged.Quantile(p / 100)
+	if !ok {
+		return 0, false
+	}
+	return min(estimate, peak), true
 }
 
 // IsComplete reports whether every one of the windowDays completed days before
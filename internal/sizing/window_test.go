@@ -1,6 +1,7 @@
 package sizing
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -339,3 +340,151 @@ func TestWindow_ObserveReportsChange(t *testing.T) {
 		t.Fatal("higher sample reported no change, want change")
 	}
 }
+
+// spikyWindow samples 1 CPU every 5 minutes across the given number of
+// completed days ending yesterday, except for one hour of the most recent day
+// at 8 CPU.
+func spikyWindow(now time.Time, days int) Window {
+	w := Window{Version: WindowVersion}
+	end := now.UTC().Truncate(24 * time.Hour)
+	spike := end.Add(-12 * time.Hour)
+	for t := end.AddDate(0, 0, -days); t.Before(end); t = t.Add(5 * time.Minute) {
+		cpu := "1"
+		if !t.Before(spike) && t.Before(spike.Add(time.Hour)) {
+			cpu = "8"
+		}
+		w.Observe(t, testUID, used(cpu), days)
+	}
+	return w
+}
+
+func TestWindow_PercentileIgnoresASingleSpike(t *testing.T) {
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.UTC)
+	w := spikyWindow(now, 14)
+
+	if peak, _ := w.Peak(corev1.ResourceRequestsCPU, now, 14); peak != 8000 {
+		t.Fatalf("peak = %d, want 8000", peak)
+	}
+	p95, ok := w.Percentile(corev1.ResourceRequestsCPU, now, 14, 95)
+	if !ok {
+		t.Fatal("Percentile reported no data")
+	}
+	// One hour in 14 days is 0.3 % of the samples; the sketch rounds up by
+	// at most 2 %.
+	if p95 < 1000 || p95 > 1020 {
+		t.Fatalf("p95 = %d, want 1000 within 2 %%", p95)
+	}
+	p999, _ := w.Percentile(corev1.ResourceRequestsCPU, now, 14, 99.9)
+	if p999 != 8000 {
+		t.Fatalf("p99.9 = %d, want the peak 8000", p999)
+	}
+}
+
+func TestWindow_PercentileCountsUnsketchedSamplesAtThePeak(t *testing.T) {
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.UTC)
+	w := spikyWindow(now, 14)
+	// The day of the spike was written before sketches existed.
+	for i := range w.Days {
+		if w.Days[i].Peaks[string(corev1.ResourceRequestsCPU)] == "8" {
+			w.Days[i].Sketches = nil
+		}
+	}
+
+	p95, _ := w.Percentile(corev1.ResourceRequestsCPU, now, 14, 95)
+	if p95 != 8000 {
+		t.Fatalf("p95 = %d, want 8000: a day without a sketch counts all of its samples at its peak", p95)
+	}
+	p50, _ := w.Percentile(corev1.ResourceRequestsCPU, now, 14, 50)
+	if p50 < 1000 || p50 > 1020 {
+		t.Fatalf("p50 = %d, want 1000 within 2 %%", p50)
+	}
+}
+
+func TestDecodeWindow_MigratesPeaksOnlyVersion(t *testing.T) {
+	raw := `{"v":1,"uid":"u","days":[{"d":"2026-08-01","n":288,"first":"00:00","last":"23:55",` +
+		`"maxGap":"5m0s","p":{"requests.cpu":"3"}}]}`
+
+	w := DecodeWindow(raw)
+
+	if w.Version != WindowVersion {
+		t.Fatalf("version = %d, want %d", w.Version, WindowVersion)
+	}
+	if len(w.Days) != 1 || w.Days[0].Peaks["requests.cpu"] != "3" {
+		t.Fatalf("days = %+v, want the version 1 day kept", w.Days)
+	}
+	now := time.Date(2026, 8, 2, 12, 0, 0, 0, time.UTC)
+	if p50, ok := w.Percentile(corev1.ResourceRequestsCPU, now, 14, 50); !ok || p50 != 3000 {
+		t.Fatalf("p50 = %d/%v, want the peak 3000", p50, ok)
+	}
+}
+
+// worstCaseWindow fills every field of a window as far as the caps let it:
+// maxWindowDays days of ten resources, each with a full sketch, every hour
+// sampled, and the longest names Kubernetes allows.
+func worstCaseWindow(now time.Time) Window {
+	resources := []string{
+		"requests.cpu", "requests.memory", "limits.cpu", "limits.memory",
+		"requests.storage", "requests.ephemeral-storage", "limits.ephemeral-storage",
+		"requests.nvidia.com/gpu", "persistentvolumeclaims", "count/deployments.apps",
+	}
+	driver := "Deployment/" + strings.Repeat("d", 253)
+	w := Window{Version: WindowVersion, UID: testUID, LastSampleAt: now.Format(time.RFC3339)}
+	for day := maxWindowDays; day >= 0; day-- {
+		bucket := DayBucket{
+			Date: now.AddDate(0, 0, -day).Format(dateLayout), N: 86_400,
+			First: "00:00", Last: "23:59", MaxGap: "59m59s",
+			Peaks: map[string]string{}, Sketches: map[string]Sketch{},
+			Excluded: map[string]string{}, Drivers: map[string]string{}, Hourly: map[string][]int64{},
+		}
+		for _, res := range resources {
+			bucket.Peaks[res] = "9223372036854775807m"
+			bucket.Excluded[res] = "9223372036854775807m"
+			bucket.Drivers[res] = driver
+			sketch := Sketch{}
+			for i := range maxSketchBuckets {
+				sketch[sketchIndex(maxMilliValue)-i] = 86_400
+			}
+			bucket.Sketches[res] = sketch
+			hours := make([]int64, 24)
+			for i := range hours {
+				hours[i] = maxMilliValue
+			}
+			bucket.Hourly[res] = hours
+		}
+		w.Days = append(w.Days, bucket)
+	}
+	for i := range seasonalWeeks + 1 {
+		w.Weeks = append(w.Weeks, PeriodBucket{Start: weekStart(now.AddDate(0, 0, -7*i)), Days: 7, Peaks: w.Days[0].Peaks})
+	}
+	for i := range seasonalMonths + 1 {
+		w.Months = append(w.Months, PeriodBucket{Start: now.AddDate(0, -i, 0).Format(monthLayout), Days: 31, Peaks: w.Days[0].Peaks})
+	}
+	return w
+}
+
+func TestEncodeWindow_WorstCaseFitsTheAnnotation(t *testing.T) {
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.UTC)
+	w := worstCaseWindow(now)
+
+	encoded, err := EncodeWindow(w)
+	if err != nil {
+		t.Fatalf("EncodeWindow: %v", err)
+	}
+	if len(encoded) > maxWindowBytes {
+		t.Fatalf("encoded window = %d bytes, want at most %d", len(encoded), maxWindowBytes)
+	}
+
+	decoded := DecodeWindow(encoded)
+	if len(decoded.Days) != len(w.Days) {
+		t.Fatalf("days = %d, want all %d kept", len(decoded.Days), len(w.Days))
+	}
+	if decoded.Days[0].Sketches != nil {
+		t.Error("the oldest day kept its sketches")
+	}
+	if today := decoded.Days[len(decoded.Days)-1]; today.Sketches == nil || today.Hourly == nil {
+		t.Error("today shed its sketches or hourly peaks, which only the oldest days give up")
+	}
+	if w.Days[0].Sketches == nil {
+		t.Error("EncodeWindow changed the caller's window")
+	}
+}